@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yrzs/openimwssdk/datadir"
+)
+
+type dataDirResp struct {
+	Total int64            `json:"total"` // bytes of the whole data dir
+	Users []*datadir.Usage `json:"users"`
+}
+
+// registerDataDirRoutes registers the routes acting on the local sdk databases.
+func (s *Server) registerDataDirRoutes() {
+	s.mux.HandleFunc("GET /admin/datadir", s.getDataDir)
+	s.mux.HandleFunc("DELETE /admin/datadir/{userID}", s.purgeDataDir)
+}
+
+// getDataDir returns the disk usage of every user, biggest first.
+func (s *Server) getDataDir(w http.ResponseWriter, _ *http.Request) {
+	users, total, err := datadir.Usages()
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if users == nil {
+		users = []*datadir.Usage{}
+	}
+	writeData(w, &dataDirResp{Total: total, Users: users})
+}
+
+// purgeDataDir removes the local state of one offline user, the next login syncs it again from the server.
+func (s *Server) purgeDataDir(w http.ResponseWriter, r *http.Request) {
+	err := datadir.Purge(r.PathValue("userID"))
+	switch {
+	case errors.Is(err, datadir.ErrInvalidUserID):
+		writeErr(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, datadir.ErrNotFound):
+		writeErr(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, datadir.ErrInUse):
+		writeErr(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeErr(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeData(w, nil)
+	}
+}
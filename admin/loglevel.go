@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+type logLevelReq struct {
+	Level string `json:"level"` // debug, info, warn or error
+}
+
+type logLevelResp struct {
+	Level string            `json:"level"`
+	Users map[string]string `json:"users"` // userID -> overridden level
+}
+
+// registerLogLevelRoutes registers the routes changing the log level at runtime.
+func (s *Server) registerLogLevelRoutes() {
+	s.mux.HandleFunc("GET /admin/log/level", s.getLogLevel)
+	s.mux.HandleFunc("PUT /admin/log/level", s.setLogLevel)
+	s.mux.HandleFunc("PUT /admin/log/level/{userID}", s.setUserLogLevel)
+	s.mux.HandleFunc("DELETE /admin/log/level/{userID}", s.clearUserLogLevel)
+}
+
+// getLogLevel returns the global level and the per-user overrides.
+func (s *Server) getLogLevel(w http.ResponseWriter, _ *http.Request) {
+	writeData(w, &logLevelResp{Level: strings.ToLower(logger.GetLevel().String()), Users: logger.UserLevels()})
+}
+
+// setLogLevel changes the global level.
+func (s *Server) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	req := &logLevelReq{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	logger.SetLevel(level)
+	logger.Info(r.Context(), "global log level changed", "level", req.Level)
+	writeData(w, nil)
+}
+
+// setUserLogLevel overrides the level of one user, e.g. debug while diagnosing it.
+func (s *Server) setUserLogLevel(w http.ResponseWriter, r *http.Request) {
+	req := &logLevelReq{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	logger.SetUserLevel(r.PathValue("userID"), level)
+	logger.Info(r.Context(), "user log level changed", "target", r.PathValue("userID"), "level", req.Level)
+	writeData(w, nil)
+}
+
+// clearUserLogLevel drops the override of one user.
+func (s *Server) clearUserLogLevel(w http.ResponseWriter, r *http.Request) {
+	logger.ClearUserLevel(r.PathValue("userID"))
+	writeData(w, nil)
+}
@@ -0,0 +1,17 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/yrzs/openimwssdk/core_func"
+)
+
+// registerMethodRoutes registers the route listing the reqFuncNames the gateway serves.
+func (s *Server) registerMethodRoutes() {
+	s.mux.HandleFunc("GET /admin/methods", s.getMethods)
+}
+
+// getMethods returns the same list as the GetMethods request.
+func (s *Server) getMethods(w http.ResponseWriter, _ *http.Request) {
+	writeData(w, core_func.Methods())
+}
@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/panics"
+)
+
+// registerPanicRoutes registers the routes reading the recovered panics and enabling a disabled method again.
+func (s *Server) registerPanicRoutes() {
+	s.mux.HandleFunc("GET /admin/panics", s.getPanics)
+	s.mux.HandleFunc("DELETE /admin/panics/{method}", s.enableMethod)
+}
+
+// getPanics returns the panics recovered since the start and the methods disabled by the crash-loop guard.
+func (s *Server) getPanics(w http.ResponseWriter, _ *http.Request) {
+	writeData(w, panics.ReadStats())
+}
+
+// enableMethod ends the cooldown of a disabled method.
+func (s *Server) enableMethod(w http.ResponseWriter, r *http.Request) {
+	method := r.PathValue("method")
+	if !panics.Enable(method) {
+		writeErr(w, http.StatusNotFound, "method not disabled")
+		return
+	}
+	logger.Info(r.Context(), "method enabled", "method", method)
+	writeData(w, nil)
+}
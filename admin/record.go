@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yrzs/openimwssdk/record"
+)
+
+type recordResp struct {
+	Enabled bool     `json:"enabled"` // false when the gateway runs without -record_dir
+	Users   []string `json:"users"`
+}
+
+// registerRecordRoutes registers the routes choosing the users whose sessions are recorded.
+func (s *Server) registerRecordRoutes() {
+	s.mux.HandleFunc("GET /admin/record", s.getRecord)
+	s.mux.HandleFunc("PUT /admin/record/{userID}", s.startRecord)
+	s.mux.HandleFunc("DELETE /admin/record/{userID}", s.stopRecord)
+}
+
+// getRecord returns the recorded users.
+func (s *Server) getRecord(w http.ResponseWriter, _ *http.Request) {
+	writeData(w, &recordResp{Enabled: record.Enabled(), Users: record.Users()})
+}
+
+// startRecord records the sessions of one user, the current one included.
+func (s *Server) startRecord(w http.ResponseWriter, r *http.Request) {
+	err := record.Start(r.PathValue("userID"))
+	switch {
+	case errors.Is(err, record.ErrInvalidUserID):
+		writeErr(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, record.ErrDisabled):
+		writeErr(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeErr(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeData(w, nil)
+	}
+}
+
+// stopRecord ends the recording of one user.
+func (s *Server) stopRecord(w http.ResponseWriter, r *http.Request) {
+	record.Stop(r.PathValue("userID"))
+	writeData(w, nil)
+}
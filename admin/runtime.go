@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/yrzs/openimwssdk/module"
+	"github.com/yrzs/openimwssdk/panics"
+)
+
+// RuntimeStats is the memory of the gateway process and its share per connected session.
+type RuntimeStats struct {
+	Sessions          int                  `json:"sessions"`
+	Goroutines        int                  `json:"goroutines"`
+	HeapInuse         uint64               `json:"heapInuse"` // bytes
+	Sys               uint64               `json:"sys"`       // bytes obtained from the os
+	HeapPerSession    uint64               `json:"heapPerSession"`
+	SysPerSession     uint64               `json:"sysPerSession"`
+	NumGC             uint32               `json:"numGC"`
+	PauseTotalNs      uint64               `json:"pauseTotalNs"`
+	ProgressStartTime int64                `json:"progressStartTime"` // unix seconds
+	Panics            int64                `json:"panics"`            // recovered since the start, see /admin/panics
+	Mailbox           *module.MailboxStats `json:"mailbox"`
+}
+
+// registerRuntimeRoutes registers the routes reading the process state.
+func (s *Server) registerRuntimeRoutes() {
+	s.mux.HandleFunc("GET /admin/runtime", s.getRuntime)
+}
+
+// getRuntime returns the memory of the process, ?gc=1 collects the garbage first for a steadier figure.
+func (s *Server) getRuntime(w http.ResponseWriter, r *http.Request) {
+	writeData(w, ReadRuntimeStats(r.URL.Query().Get("gc") == "1"))
+}
+
+// ReadRuntimeStats reads the memory of the process, after a garbage collection when gc is set.
+func ReadRuntimeStats(gc bool) *RuntimeStats {
+	if gc {
+		runtime.GC()
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	stats := &RuntimeStats{Sessions: module.GJsActors.Count(), Goroutines: runtime.NumGoroutine(),
+		HeapInuse: m.HeapInuse, Sys: m.Sys, NumGC: m.NumGC, PauseTotalNs: m.PauseTotalNs,
+		ProgressStartTime: module.ProgressStartTime, Panics: panics.ReadStats().Total,
+		Mailbox: module.ReadMailboxStats()}
+	if stats.Sessions > 0 {
+		stats.HeapPerSession = m.HeapInuse / uint64(stats.Sessions)
+		stats.SysPerSession = m.Sys / uint64(stats.Sessions)
+	}
+	return stats
+}
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+// Resp is the body of every admin response.
+type Resp struct {
+	ErrCode int    `json:"errCode"`
+	ErrMsg  string `json:"errMsg"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Server is the admin http api, served on its own listener and guarded by a bearer token.
+type Server struct {
+	Addr        string
+	Token       string
+	HTTPTimeout time.Duration
+	mux         *http.ServeMux
+	httpServer  *http.Server
+}
+
+// NewServer creates an admin server with the session, log level, datadir, runtime, record, method and
+// panic routes registered.
+func NewServer(addr string, token string, httpTimeout time.Duration) *Server {
+	s := &Server{Addr: addr, Token: token, HTTPTimeout: httpTimeout, mux: http.NewServeMux()}
+	s.registerSessionRoutes()
+	s.registerLogLevelRoutes()
+	s.registerDataDirRoutes()
+	s.registerRuntimeRoutes()
+	s.registerRecordRoutes()
+	s.registerMethodRoutes()
+	s.registerPanicRoutes()
+	return s
+}
+
+// Handle registers an extra route, the token check is applied to it as well.
+func (s *Server) Handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// ServeHTTP checks the bearer token before dispatching to the routes.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+		logger.Info(r.Context(), "admin token mismatch", "remoteAddr", r.RemoteAddr, "path", r.URL.Path)
+		writeResp(w, http.StatusUnauthorized, &Resp{ErrCode: http.StatusUnauthorized, ErrMsg: "unauthorized"})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Start listens on Addr and serves in the background.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	if s.HTTPTimeout <= 0 {
+		s.HTTPTimeout = 10 * time.Second
+	}
+	s.httpServer = &http.Server{
+		Handler:      s,
+		ReadTimeout:  s.HTTPTimeout,
+		WriteTimeout: s.HTTPTimeout,
+	}
+	go func() {
+		if err := s.httpServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error(context.Background(), "admin server stopped", "err", err)
+		}
+	}()
+	logger.Info(context.Background(), "admin server listening", "addr", s.Addr)
+	return nil
+}
+
+// Close stops the listener.
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		_ = s.httpServer.Close()
+	}
+}
+
+// writeResp writes a json response with the given http status.
+func writeResp(w http.ResponseWriter, status int, resp *Resp) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeData writes a successful response.
+func writeData(w http.ResponseWriter, data any) {
+	writeResp(w, http.StatusOK, &Resp{Data: data})
+}
+
+// writeErr writes a failed response, the http status doubles as errCode.
+func writeErr(w http.ResponseWriter, status int, errMsg string) {
+	writeResp(w, status, &Resp{ErrCode: status, ErrMsg: errMsg})
+}
@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/record"
+)
+
+func doAdminReq(s *Server, method string, path string, token string, body string) (*httptest.ResponseRecorder, *Resp) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	resp := &Resp{}
+	_ = json.Unmarshal(w.Body.Bytes(), resp)
+	return w, resp
+}
+
+func TestServerAuth(t *testing.T) {
+	s := NewServer(":0", "secret", 0)
+	w, _ := doAdminReq(s, http.MethodGet, "/admin/sessions", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = doAdminReq(s, http.MethodGet, "/admin/sessions", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, resp := doAdminReq(s, http.MethodGet, "/admin/sessions", "secret", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []any{}, resp.Data)
+
+	s = NewServer(":0", "", 0)
+	w, _ = doAdminReq(s, http.MethodGet, "/admin/sessions", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestServerSessionNotFound(t *testing.T) {
+	s := NewServer(":0", "secret", 0)
+	w, _ := doAdminReq(s, http.MethodGet, "/admin/sessions/nobody", "secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = doAdminReq(s, http.MethodPost, "/admin/sessions/nobody/kick", "secret", `{"reason":"test"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, resp := doAdminReq(s, http.MethodPost, "/admin/notice", "secret", `{"data":"hello"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]any{"delivered": float64(0)}, resp.Data)
+}
+
+func TestServerLogLevel(t *testing.T) {
+	s := NewServer(":0", "secret", 0)
+	w, _ := doAdminReq(s, http.MethodPut, "/admin/log/level", "secret", `{"level":"verbose"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = doAdminReq(s, http.MethodPut, "/admin/log/level/u1", "secret", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, resp := doAdminReq(s, http.MethodGet, "/admin/log/level", "secret", "")
+	assert.Equal(t, map[string]any{"level": "info", "users": map[string]any{"u1": "debug"}}, resp.Data)
+	w, _ = doAdminReq(s, http.MethodDelete, "/admin/log/level/u1", "secret", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServerRuntime(t *testing.T) {
+	s := NewServer(":0", "secret", 0)
+	w, resp := doAdminReq(s, http.MethodGet, "/admin/runtime?gc=1", "secret", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := resp.Data.(map[string]any)
+	assert.Equal(t, float64(0), data["sessions"])
+	assert.Equal(t, float64(0), data["heapPerSession"])
+	assert.NotZero(t, data["heapInuse"])
+	assert.NotZero(t, data["numGC"])
+}
+
+func TestServerRecord(t *testing.T) {
+	s := NewServer(":0", "secret", 0)
+	w, _ := doAdminReq(s, http.MethodPut, "/admin/record/u1", "secret", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.Nil(t, record.Init(record.Config{Dir: t.TempDir()}))
+	t.Cleanup(func() { _ = record.Init(record.Config{}) })
+	w, _ = doAdminReq(s, http.MethodPut, "/admin/record/u1", "secret", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = doAdminReq(s, http.MethodPut, "/admin/record/a%5Cb", "secret", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, resp := doAdminReq(s, http.MethodGet, "/admin/record", "secret", "")
+	assert.Equal(t, map[string]any{"enabled": true, "users": []any{"u1"}}, resp.Data)
+	doAdminReq(s, http.MethodDelete, "/admin/record/u1", "secret", "")
+	_, resp = doAdminReq(s, http.MethodGet, "/admin/record", "secret", "")
+	assert.Equal(t, map[string]any{"enabled": true, "users": []any{}}, resp.Data)
+}
+
+func TestServerMethods(t *testing.T) {
+	s := NewServer(":0", "secret", 0)
+	w, resp := doAdminReq(s, http.MethodGet, "/admin/methods", "secret", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := resp.Data.(map[string]any)
+	assert.NotEmpty(t, data["version"])
+	assert.NotEmpty(t, data["methods"])
+}
+
+func TestServerPanics(t *testing.T) {
+	s := NewServer(":0", "secret", 0)
+	w, resp := doAdminReq(s, http.MethodGet, "/admin/panics", "secret", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, resp.Data.(map[string]any), "total")
+	w, _ = doAdminReq(s, http.MethodDelete, "/admin/panics/GetUsersInfo", "secret", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
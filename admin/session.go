@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/yrzs/openimwssdk/module"
+)
+
+type kickReq struct {
+	Reason string `json:"reason"`
+}
+
+type noticeReq struct {
+	UserID string `json:"userID"` // empty sends to every connected user
+	Data   string `json:"data"`
+}
+
+type noticeResp struct {
+	Delivered int `json:"delivered"`
+}
+
+// registerSessionRoutes registers the routes acting on GJsActors.
+func (s *Server) registerSessionRoutes() {
+	s.mux.HandleFunc("GET /admin/sessions", s.listSessions)
+	s.mux.HandleFunc("GET /admin/sessions/{userID}", s.getSession)
+	s.mux.HandleFunc("POST /admin/sessions/{userID}/kick", s.kickSession)
+	s.mux.HandleFunc("POST /admin/notice", s.notice)
+}
+
+// listSessions returns every session connected to this node, ?cluster=1 those of every node in the registry.
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("cluster") != "1" {
+		writeData(w, module.GJsActors.Sessions())
+		return
+	}
+	sessions, err := module.GJsActors.ClusterSessions()
+	if err != nil {
+		writeSessionErr(w, err)
+		return
+	}
+	writeData(w, sessions)
+}
+
+// getSession returns the session of one user, on whichever node serves it.
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) {
+	info, err := module.GJsActors.Locate(r.PathValue("userID"))
+	if err != nil {
+		writeSessionErr(w, err)
+		return
+	}
+	writeData(w, info)
+}
+
+// kickSession force-disconnects one user with a reason.
+func (s *Server) kickSession(w http.ResponseWriter, r *http.Request) {
+	req := &kickReq{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if err := module.GJsActors.Kick(r.PathValue("userID"), req.Reason); err != nil {
+		writeSessionErr(w, err)
+		return
+	}
+	writeData(w, nil)
+}
+
+// notice pushes a system notice to one user or to everyone.
+func (s *Server) notice(w http.ResponseWriter, r *http.Request) {
+	req := &noticeReq{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.UserID == "" {
+		writeData(w, &noticeResp{Delivered: module.GJsActors.NoticeAll(req.Data)})
+		return
+	}
+	if err := module.GJsActors.Notice(req.UserID, req.Data); err != nil {
+		writeSessionErr(w, err)
+		return
+	}
+	writeData(w, &noticeResp{Delivered: 1})
+}
+
+// writeSessionErr maps module session errors to http statuses.
+func writeSessionErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, module.ErrSessionNotFound) {
+		writeErr(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeErr(w, http.StatusServiceUnavailable, err.Error())
+}
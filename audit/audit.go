@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const (
+	ArgsNone = "none" // arguments are not recorded
+	ArgsFull = "full" // arguments are recorded, cut to MaxArgsLen when it is set
+
+	DefaultMaxSize    = 100 * 1024 * 1024
+	DefaultMaxBackups = 10
+	recordChanLen     = 4096
+)
+
+// Record is one line of the audit stream.
+type Record struct {
+	Time        int64  `json:"time"` // unix milliseconds the request was received
+	UserID      string `json:"userID"`
+	SessionId   string `json:"sessionId"`
+	PlatformID  string `json:"platformID"`
+	ReqFuncName string `json:"reqFuncName"`
+	OperationID string `json:"operationID"`
+	ErrCode     int32  `json:"errCode"`
+	ErrMsg      string `json:"errMsg,omitempty"`
+	LatencyMs   int64  `json:"latencyMs"`
+	Args        string `json:"args,omitempty"`
+}
+
+// Config configures the audit stream.
+type Config struct {
+	Dir        string
+	MaxSize    int64  // bytes of the current file before it is rotated
+	MaxBackups int    // rotated files kept, older ones are removed
+	ArgsMode   string // ArgsNone or ArgsFull
+	MaxArgsLen int    // 0 keeps the whole argument string
+}
+
+// Logger writes records asynchronously so that auditing never blocks an actor.
+type Logger struct {
+	conf    Config
+	recChan chan *Record
+	file    *rotateFile
+	mu      sync.RWMutex
+	closed  bool // set by Close, the records logged after it are dropped
+	done    chan struct{}
+}
+
+var defaultLogger *Logger
+
+// Init opens the audit stream used by Log, an empty dir leaves auditing disabled.
+func Init(conf Config) error {
+	if conf.Dir == "" {
+		return nil
+	}
+	l, err := NewLogger(conf)
+	if err != nil {
+		return err
+	}
+	defaultLogger = l
+	return nil
+}
+
+// Close flushes and closes the audit stream opened by Init.
+func Close() {
+	if defaultLogger != nil {
+		defaultLogger.Close()
+	}
+}
+
+// Enabled reports whether Init opened an audit stream.
+func Enabled() bool {
+	return defaultLogger != nil
+}
+
+// Log appends a record to the audit stream opened by Init.
+func Log(rec *Record) {
+	if defaultLogger != nil {
+		defaultLogger.Log(rec)
+	}
+}
+
+// CaptureArgs returns the argument string as configured for the audit stream opened by Init.
+func CaptureArgs(args string) string {
+	if defaultLogger == nil {
+		return ""
+	}
+	return defaultLogger.CaptureArgs(args)
+}
+
+// NewLogger opens the current file in conf.Dir and starts the writer goroutine.
+func NewLogger(conf Config) (*Logger, error) {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = DefaultMaxSize
+	}
+	if conf.MaxBackups <= 0 {
+		conf.MaxBackups = DefaultMaxBackups
+	}
+	if conf.ArgsMode == "" {
+		conf.ArgsMode = ArgsNone
+	}
+	file, err := openRotateFile(conf.Dir, conf.MaxSize, conf.MaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	l := &Logger{conf: conf, recChan: make(chan *Record, recordChanLen), file: file, done: make(chan struct{})}
+	go l.run()
+	return l, nil
+}
+
+// CaptureArgs applies ArgsMode and MaxArgsLen to the argument string of a request.
+func (l *Logger) CaptureArgs(args string) string {
+	if l.conf.ArgsMode != ArgsFull {
+		return ""
+	}
+	if l.conf.MaxArgsLen > 0 && len(args) > l.conf.MaxArgsLen {
+		return args[:l.conf.MaxArgsLen]
+	}
+	return args
+}
+
+// Log queues a record, it is dropped with an error log when the writer falls behind, and quietly once the logger is
+// closed: the sessions closing last may still log.
+func (l *Logger) Log(rec *Record) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.recChan <- rec:
+	default:
+		ctx := logger.NewSessionContext(context.Background(), rec.SessionId, rec.UserID)
+		logger.Error(logger.WithOperationID(ctx, rec.OperationID), "audit channel is full, record dropped")
+	}
+}
+
+// Close writes the queued records and closes the file.
+func (l *Logger) Close() {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.recChan)
+	}
+	l.mu.Unlock()
+	<-l.done
+}
+
+// run is the single writer of the file.
+func (l *Logger) run() {
+	defer close(l.done)
+	defer l.file.Close()
+	for rec := range l.recChan {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			logger.Error(context.Background(), "audit marshal error", "err", err)
+			continue
+		}
+		if err := l.file.WriteLine(line); err != nil {
+			logger.Error(context.Background(), "audit write error", "err", err)
+		}
+	}
+}
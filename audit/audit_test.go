@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerRotateAndQuery(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLogger(Config{Dir: dir, MaxSize: 300, MaxBackups: 2, ArgsMode: ArgsFull, MaxArgsLen: 4})
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		l.Log(&Record{Time: int64(i), UserID: fmt.Sprintf("u%d", i%2), ReqFuncName: "GetFriendList",
+			OperationID: fmt.Sprintf("op%d", i), ErrCode: int32(i % 3), Args: l.CaptureArgs("[\"abcdef\"]")})
+	}
+	l.Close()
+	l.Close()
+	l.Log(&Record{OperationID: "late"}) // dropped, not a send on the closed channel
+
+	files, err := listFiles(dir, true)
+	assert.Nil(t, err)
+	assert.Len(t, files, 3)
+
+	var ops []string
+	err = Query(dir, &Filter{UserID: "u1"}, func(rec *Record) bool {
+		ops = append(ops, rec.OperationID)
+		assert.Equal(t, "[\"ab", rec.Args)
+		return true
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, ops)
+	for i := 1; i < len(ops); i++ {
+		assert.Less(t, ops[i-1], ops[i])
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	rec := &Record{Time: 100, UserID: "u1", ReqFuncName: "SendMessage", ErrCode: 0}
+	assert.True(t, (&Filter{}).Match(rec))
+	assert.True(t, (&Filter{UserID: "u1", From: 100, To: 101}).Match(rec))
+	assert.False(t, (&Filter{To: 100}).Match(rec))
+	assert.False(t, (&Filter{ErrOnly: true}).Match(rec))
+	assert.False(t, (&Filter{ReqFuncName: "Login"}).Match(rec))
+}
+
+func TestBackupNameUnique(t *testing.T) {
+	r, err := openRotateFile(t.TempDir(), 100, 10)
+	assert.Nil(t, err)
+	defer r.Close()
+	now := time.Now()
+	var names []string
+	for i := 0; i < 3; i++ {
+		name, err := r.backupName(now)
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(filepath.Join(r.dir, name), nil, 0o644))
+		names = append(names, filepath.Join(r.dir, name))
+	}
+	files, err := listFiles(r.dir, false)
+	assert.Nil(t, err)
+	assert.Equal(t, names, files)
+}
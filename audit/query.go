@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+)
+
+// Filter selects records, zero fields match everything.
+type Filter struct {
+	UserID      string
+	SessionId   string
+	ReqFuncName string
+	OperationID string
+	From        int64 // unix milliseconds, inclusive
+	To          int64 // unix milliseconds, exclusive
+	ErrOnly     bool
+}
+
+// Match reports whether the record passes the filter.
+func (f *Filter) Match(rec *Record) bool {
+	if f.UserID != "" && rec.UserID != f.UserID {
+		return false
+	}
+	if f.SessionId != "" && rec.SessionId != f.SessionId {
+		return false
+	}
+	if f.ReqFuncName != "" && rec.ReqFuncName != f.ReqFuncName {
+		return false
+	}
+	if f.OperationID != "" && rec.OperationID != f.OperationID {
+		return false
+	}
+	if f.From > 0 && rec.Time < f.From {
+		return false
+	}
+	if f.To > 0 && rec.Time >= f.To {
+		return false
+	}
+	if f.ErrOnly && rec.ErrCode == 0 {
+		return false
+	}
+	return true
+}
+
+// Query walks every file of dir oldest first and calls fn for each matching record until fn returns false.
+func Query(dir string, filter *Filter, fn func(rec *Record) bool) error {
+	files, err := listFiles(dir, true)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		cont, err := queryFile(name, filter, fn)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+	return nil
+}
+
+func queryFile(name string, filter *Filter, fn func(rec *Record) bool) (bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		rec := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			continue // a torn last line of a crashed writer
+		}
+		if filter.Match(rec) && !fn(rec) {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	currentFileName = "audit.jsonl"
+	backupPrefix    = "audit-"
+	fileSuffix      = ".jsonl"
+	backupTimeFmt   = "20060102T150405.000"
+)
+
+// rotateFile is an append-only file renamed to a timestamped backup once it grows past maxSize.
+type rotateFile struct {
+	dir        string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+// openRotateFile opens or creates the current file of dir.
+func openRotateFile(dir string, maxSize int64, maxBackups int) (*rotateFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	r := &rotateFile{dir: dir, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotateFile) open() error {
+	f, err := os.OpenFile(filepath.Join(r.dir, currentFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = info.Size()
+	return nil
+}
+
+// WriteLine appends one line, rotating first when the line would overflow the current file.
+func (r *rotateFile) WriteLine(line []byte) error {
+	if r.size > 0 && r.size+int64(len(line))+1 > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := r.f.Write(append(line, '\n'))
+	r.size += int64(n)
+	return err
+}
+
+// rotate renames the current file to a backup, prunes old backups and reopens the current file.
+func (r *rotateFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	name, err := r.backupName(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(r.dir, currentFileName), filepath.Join(r.dir, name)); err != nil {
+		return fmt.Errorf("rotate audit file: %w", err)
+	}
+	backups, err := listFiles(r.dir, false)
+	if err != nil {
+		return err
+	}
+	for len(backups) > r.maxBackups {
+		_ = os.Remove(backups[0])
+		backups = backups[1:]
+	}
+	return r.open()
+}
+
+// backupName returns a backup name not taken yet, rotations within the same millisecond get the suffixes _001,
+// _002..., which sort after the name without one.
+func (r *rotateFile) backupName(now time.Time) (string, error) {
+	base := backupPrefix + now.Format(backupTimeFmt)
+	name := base + fileSuffix
+	for seq := 1; ; seq++ {
+		_, err := os.Lstat(filepath.Join(r.dir, name))
+		if os.IsNotExist(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("%s_%03d%s", base, seq, fileSuffix)
+	}
+}
+
+func (r *rotateFile) Close() error {
+	return r.f.Close()
+}
+
+// listFiles returns the backups of dir oldest first, followed by the current file when withCurrent is set.
+func listFiles(dir string, withCurrent bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), backupPrefix) && strings.HasSuffix(e.Name(), fileSuffix) {
+			ret = append(ret, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(ret)
+	if withCurrent {
+		if _, err := os.Stat(filepath.Join(dir, currentFileName)); err == nil {
+			ret = append(ret, filepath.Join(dir, currentFileName))
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,132 @@
+// Package bot runs bot accounts inside the gateway, next to the user sessions. Every account is an sdk session
+// without a client: the message listener events of the account are handed to its Bot, which answers through the
+// FuncRouter operations of the session, e.g. CreateTextMessage then SendMessage.
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+var (
+	ErrUnknownBot = errors.New("unknown bot")
+	ErrClosed     = errors.New("bot session closed")
+)
+
+// Bot handles the events of a bot account. OnEvent is called with the message listener events (OnRecvNewMessage,
+// OnRecvNewMessages, OnNewRecvMessageRevoked and the others) one at a time, s is the session of the account.
+type Bot interface {
+	OnEvent(ctx context.Context, s *Session, ev *core_func.EventData)
+}
+
+// BotFunc is a function handling the events of a bot account.
+type BotFunc func(ctx context.Context, s *Session, ev *core_func.EventData)
+
+// OnEvent calls f.
+func (f BotFunc) OnEvent(ctx context.Context, s *Session, ev *core_func.EventData) {
+	f(ctx, s, ev)
+}
+
+// Factory returns the Bot of an account running the registered bot.
+type Factory func(account *Account) (Bot, error)
+
+var (
+	mu        sync.Mutex
+	factories = make(map[string]Factory)
+	sessions  = make(map[string]*Session) // by userID
+)
+
+// Register makes a bot available to the accounts under name, it panics when the name is taken.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("bot " + name + " registered twice")
+	}
+	factories[name] = factory
+}
+
+// Names returns the registered bots, sorted.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Account is a user of the OpenIM server the gateway runs a bot as.
+type Account struct {
+	Bot        string          `json:"bot"` // name the bot is registered under
+	UserID     string          `json:"userID"`
+	Token      string          `json:"token"`
+	PlatformID int             `json:"platformID,omitempty"` // DefaultPlatformID when 0
+	Config     json.RawMessage `json:"config,omitempty"`     // for the bot, see its Factory
+}
+
+// LoadAccounts reads the accounts of a JSON file holding an array of Account.
+func LoadAccounts(path string) ([]*Account, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []*Account
+	if err := json.Unmarshal(b, &accounts); err != nil {
+		return nil, fmt.Errorf("bot accounts %s: %w", path, err)
+	}
+	return accounts, nil
+}
+
+// StartAll starts a session for each account, those failing to start are logged and skipped.
+func StartAll(ctx context.Context, accounts []*Account) {
+	for _, account := range accounts {
+		if _, err := Start(ctx, account); err != nil {
+			logger.Error(ctx, "bot start error", "bot", account.Bot, "userID", account.UserID, "err", err)
+			continue
+		}
+		logger.Info(ctx, "bot started", "bot", account.Bot, "userID", account.UserID)
+	}
+}
+
+// CloseAll closes the sessions of all the running bots.
+func CloseAll() {
+	mu.Lock()
+	running := make([]*Session, 0, len(sessions))
+	for _, s := range sessions {
+		running = append(running, s)
+	}
+	mu.Unlock()
+	for _, s := range running {
+		s.Close()
+	}
+}
+
+// Online reports whether a bot runs as the user.
+func Online(userID string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := sessions[userID]
+	return ok
+}
+
+// newBot returns the Bot of an account, from the factory of its bot.
+func newBot(account *Account) (Bot, error) {
+	mu.Lock()
+	factory, ok := factories[account.Bot]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownBot, account.Bot)
+	}
+	return factory(account)
+}
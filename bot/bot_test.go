@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/client"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/fakeim"
+	"github.com/yrzs/openimwssdk/fakeim/fakegate"
+)
+
+// eventListener collects the events of a client.
+type eventListener struct {
+	events chan *client.Event
+}
+
+func (l *eventListener) OnEvent(ev *client.Event) { l.events <- ev }
+func (l *eventListener) OnConnected()             {}
+func (l *eventListener) OnDisconnected(err error) {}
+
+func TestEchoBot(t *testing.T) {
+	fake := fakeim.NewServer()
+	t.Cleanup(fake.Close)
+	g, err := fakegate.Start(fake, t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	t.Cleanup(func() {
+		g.Close()
+		datadir.Init(datadir.Config{})
+	})
+	ctx := context.Background()
+
+	_, err = Start(ctx, &Account{Bot: "nosuchbot", UserID: "bot0"})
+	assert.True(t, errors.Is(err, ErrUnknownBot), err)
+
+	s, err := Start(ctx, &Account{Bot: EchoName, UserID: "bot1", Token: fake.AddUser("bot1", "Echo"),
+		Config: []byte(`{"prefix":"echo: "}`)})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s.Close()
+	assert.True(t, Online("bot1"))
+	var e *client.Error
+	assert.True(t, errors.As(s.Call(ctx, "NoSuchFunc", nil), &e))
+
+	l := &eventListener{events: make(chan *client.Event, 1000)}
+	var c *client.Client
+	assert.Eventually(t, func() bool {
+		c, err = client.Dial(ctx, client.Config{Addr: g.Addr, UserID: "u1", Token: fake.AddUser("u1", "Alice"),
+			Listener: l})
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	defer c.Close()
+	assert.Nil(t, c.Login(ctx))
+	assert.True(t, fake.WaitIdle(200*time.Millisecond, 5*time.Second))
+
+	msg, err := c.CreateTextMessage(ctx, "hello")
+	assert.Nil(t, err)
+	_, err = c.SendMessage(ctx, msg, "bot1", "", nil, false)
+	assert.Nil(t, err)
+	timeout := time.After(10 * time.Second)
+	for echoed := false; !echoed; {
+		select {
+		case ev := <-l.events:
+			echoed = ev.Event == "OnRecvNewMessages" && strings.Contains(ev.Data, "echo: hello")
+		case <-timeout:
+			t.Fatal("echo not received")
+		}
+	}
+
+	s.Close()
+	assert.False(t, Online("bot1"))
+	assert.Equal(t, ErrClosed, s.Call(ctx, "GetSelfUserInfo", nil))
+}
+
+func TestMessages(t *testing.T) {
+	for _, event := range []string{"OnRecvNewMessage", "OnRecvOfflineNewMessage", "OnRecvOnlineOnlyMessage"} {
+		msgs, err := Messages(&core_func.EventData{Event: event, Data: `{"clientMsgID":"c1"}`})
+		if assert.NoError(t, err, event) && assert.Len(t, msgs, 1, event) {
+			assert.Equal(t, "c1", msgs[0].ClientMsgID)
+		}
+	}
+	msgs, err := Messages(&core_func.EventData{Event: "OnRecvOfflineNewMessages", Data: `[{"clientMsgID":"c1"},{}]`})
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 2)
+}
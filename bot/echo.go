@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yrzs/openimsdkcore/pkg/constant"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+// EchoName is the name the echo bot is registered under.
+const EchoName = "echo"
+
+// EchoConfig is the config of an account running the echo bot.
+type EchoConfig struct {
+	Prefix string `json:"prefix,omitempty"` // put before the echoed text
+}
+
+// Echo is the sample bot: it answers every text message sent to its account with the same text.
+type Echo struct {
+	Prefix string
+}
+
+func init() {
+	Register(EchoName, func(account *Account) (Bot, error) {
+		var cfg EchoConfig
+		if len(account.Config) > 0 {
+			if err := json.Unmarshal(account.Config, &cfg); err != nil {
+				return nil, fmt.Errorf("echo config: %w", err)
+			}
+		}
+		return &Echo{Prefix: cfg.Prefix}, nil
+	})
+}
+
+// OnEvent replies to the text messages of the others.
+func (e *Echo) OnEvent(ctx context.Context, s *Session, ev *core_func.EventData) {
+	msgs, err := Messages(ev)
+	if err != nil {
+		logger.Error(ctx, "echo bot decode error", "event", ev.Event, "err", err)
+		return
+	}
+	for _, msg := range msgs {
+		if msg.SendID == s.UserID() || msg.ContentType != constant.Text || msg.TextElem == nil {
+			continue
+		}
+		if _, err := s.Reply(ctx, msg, e.Prefix+msg.TextElem.Content); err != nil {
+			logger.Error(ctx, "echo bot reply error", "clientMsgID", msg.ClientMsgID, "err", err)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openimsdk/protocol/sdkws"
+	"github.com/yrzs/openimsdkcore/sdk_struct"
+	"github.com/yrzs/openimwssdk/client"
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/module"
+	"github.com/yrzs/openimwssdk/panics"
+)
+
+const (
+	DefaultPlatformID = 7 // linux
+	DefaultTimeout    = 30 * time.Second
+	EventQueueSize    = 1000 // events of a session waiting for its bot, the later ones are dropped
+)
+
+// Session is the sdk session of a bot account. It is a JsCore without a client: the requests of Call go to the
+// same FuncRouter methods as those of a websocket, with the same interceptors, and the session subscribes to the
+// message events only.
+type Session struct {
+	account   *Account
+	bot       Bot
+	core      *module.JsCore
+	sessionId string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	seq       atomic.Int64
+	mu        sync.Mutex
+	pending   map[string]chan *core_func.EventData // by operationID
+	closed    bool
+	events    chan *core_func.EventData
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Start initializes the sdk of an account, logs it in and hands its message events to the bot of the account
+// until Close.
+func Start(ctx context.Context, account *Account) (*Session, error) {
+	if account.UserID == "" {
+		return nil, errors.New("bot account without userID")
+	}
+	b, err := newBot(account)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{account: account, bot: b, sessionId: common.GenSessionID(),
+		pending: make(map[string]chan *core_func.EventData), events: make(chan *core_func.EventData, EventQueueSize),
+		done: make(chan struct{})}
+	s.ctx, s.cancel = context.WithCancel(logger.NewSessionContext(context.Background(), s.sessionId, account.UserID))
+	mu.Lock()
+	if _, ok := sessions[account.UserID]; ok {
+		mu.Unlock()
+		s.cancel()
+		return nil, fmt.Errorf("a bot already runs as %s", account.UserID)
+	}
+	sessions[account.UserID] = s
+	mu.Unlock()
+	// registered first, so a client connecting meanwhile sees the bot, then rolled back when a client came before
+	if module.GJsActors.Online(account.UserID) {
+		s.Close()
+		return nil, fmt.Errorf("%s has a client session", account.UserID)
+	}
+
+	platformID := account.PlatformID
+	if platformID == 0 {
+		platformID = DefaultPlatformID
+	}
+	q := url.Values{}
+	q.Set(module.WsUserID, account.UserID)
+	q.Set(module.PlatformID, strconv.Itoa(platformID))
+	q.Set(module.OperationID, s.operationID())
+	q.Set(module.Events, string(core_func.CategoryMessage))
+	initResp := s.register(q.Get(module.OperationID))
+	s.core = module.NewJsCore(s.ctx, &module.ParamStru{UrlPath: "/?" + q.Encode(), SessionId: s.sessionId}, s.sessionId)
+	go s.run()
+	if _, err := s.wait(ctx, "InitSDK", q.Get(module.OperationID), initResp); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if err := s.Call(ctx, "Login", nil, account.UserID, account.Token); err != nil {
+		s.Close()
+		return nil, err
+	}
+	go s.handle()
+	return s, nil
+}
+
+// UserID returns the user the bot runs as.
+func (s *Session) UserID() string {
+	return s.account.UserID
+}
+
+// Account returns the account of the session.
+func (s *Session) Account() *Account {
+	return s.account
+}
+
+// Call calls a FuncRouter operation as a client request would and decodes its response data into out, which may
+// be nil. The args are those of the request data, a struct, slice or map one is sent as its JSON, see
+// client.EncodeArgs. A failed call returns a *client.Error.
+func (s *Session) Call(ctx context.Context, reqFuncName string, out any, args ...any) error {
+	data, err := client.EncodeArgs(args...)
+	if err != nil {
+		return err
+	}
+	operationID := s.operationID()
+	ch := s.register(operationID)
+	if ch == nil {
+		return ErrClosed
+	}
+	if err := s.core.SendMsg(ctx, &module.Req{ReqFuncName: reqFuncName, OperationID: operationID, Data: data}); err != nil {
+		s.unregister(operationID)
+		e := errcode.From(err)
+		return &client.Error{ReqFuncName: reqFuncName, Code: e.Code, Msg: e.Msg, Detail: e.Detail}
+	}
+	resp, err := s.wait(ctx, reqFuncName, operationID, ch)
+	if err != nil {
+		return err
+	}
+	if out == nil || resp.Data == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(resp.Data), out); err != nil {
+		return fmt.Errorf("%s response decode error: %w", reqFuncName, err)
+	}
+	return nil
+}
+
+// CreateTextMessage returns a new text message.
+func (s *Session) CreateTextMessage(ctx context.Context, text string) (*sdk_struct.MsgStruct, error) {
+	msg := &sdk_struct.MsgStruct{}
+	if err := s.Call(ctx, "CreateTextMessage", msg, text); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// SendMessage sends a message to a user, recvID, or a group, groupID, and returns it as sent.
+func (s *Session) SendMessage(ctx context.Context, msg *sdk_struct.MsgStruct, recvID, groupID string) (
+	*sdk_struct.MsgStruct, error) {
+	sent := &sdk_struct.MsgStruct{}
+	if err := s.Call(ctx, "SendMessage", sent, msg, recvID, groupID, &sdkws.OfflinePushInfo{}, false); err != nil {
+		return nil, err
+	}
+	return sent, nil
+}
+
+// Reply sends a text to the conversation of a received message: its group, or its sender.
+func (s *Session) Reply(ctx context.Context, to *sdk_struct.MsgStruct, text string) (*sdk_struct.MsgStruct, error) {
+	msg, err := s.CreateTextMessage(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if to.GroupID != "" {
+		return s.SendMessage(ctx, msg, "", to.GroupID)
+	}
+	return s.SendMessage(ctx, msg, to.SendID, "")
+}
+
+// Close logs the account out and stops handing its events to the bot. The calls in progress fail with ErrClosed.
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		mu.Lock()
+		if sessions[s.account.UserID] == s {
+			delete(sessions, s.account.UserID)
+		}
+		mu.Unlock()
+		s.cancel()
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		for operationID, ch := range s.pending {
+			delete(s.pending, operationID)
+			close(ch)
+		}
+		s.mu.Unlock()
+		if s.core != nil {
+			s.core.Destroy()
+		}
+	})
+}
+
+// Messages decodes the messages of a message event, none for the events not carrying messages.
+func Messages(ev *core_func.EventData) ([]*sdk_struct.MsgStruct, error) {
+	switch ev.Event {
+	case "OnRecvNewMessage", "OnRecvOfflineNewMessage", "OnRecvOnlineOnlyMessage":
+		msg := &sdk_struct.MsgStruct{}
+		if err := json.Unmarshal([]byte(ev.Data), msg); err != nil {
+			return nil, err
+		}
+		return []*sdk_struct.MsgStruct{msg}, nil
+	case "OnRecvNewMessages", "OnRecvOfflineNewMessages":
+		var msgs []*sdk_struct.MsgStruct
+		if err := json.Unmarshal([]byte(ev.Data), &msgs); err != nil {
+			return nil, err
+		}
+		return msgs, nil
+	}
+	return nil, nil
+}
+
+// run routes the responses to their calls and queues the message events for the bot.
+func (s *Session) run() {
+	for {
+		select {
+		case ev := <-s.core.RecvMsg():
+			s.dispatch(ev)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Session) dispatch(ev *core_func.EventData) {
+	if ev.OperationID != "" {
+		s.mu.Lock()
+		ch, ok := s.pending[ev.OperationID]
+		delete(s.pending, ev.OperationID)
+		s.mu.Unlock()
+		if ok {
+			ch <- ev
+		}
+		return
+	}
+	if category, ok := core_func.EventCategoryOf(ev.Event); !ok || category != core_func.CategoryMessage {
+		switch ev.Event {
+		case "OnKickedOffline", "OnUserTokenExpired", "OnUserTokenInvalid":
+			logger.Warn(s.ctx, "bot logged out by the server", "bot", s.account.Bot, "event", ev.Event)
+		default:
+			logger.Debug(s.ctx, "bot session event", "event", ev.Event)
+		}
+		return
+	}
+	select {
+	case s.events <- ev:
+	default:
+		logger.Error(s.ctx, "bot event dropped, the queue is full", "bot", s.account.Bot, "event", ev.Event)
+	}
+}
+
+// handle hands the queued events to the bot, a panic of the bot loses the event only.
+func (s *Session) handle() {
+	for {
+		select {
+		case ev := <-s.events:
+			func() {
+				defer panics.Recover(s.ctx, "bot "+s.account.Bot, nil)
+				s.bot.OnEvent(s.ctx, s, ev)
+			}()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// register returns the channel the response of operationID is sent to, nil once the session is closed.
+func (s *Session) register(operationID string) chan *core_func.EventData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	ch := make(chan *core_func.EventData, 1)
+	s.pending[operationID] = ch
+	return ch
+}
+
+func (s *Session) unregister(operationID string) {
+	s.mu.Lock()
+	delete(s.pending, operationID)
+	s.mu.Unlock()
+}
+
+// wait returns the response of a call, a failed one as a *client.Error.
+func (s *Session) wait(ctx context.Context, reqFuncName, operationID string, ch chan *core_func.EventData) (
+	*core_func.EventData, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	select {
+	case resp := <-ch:
+		if resp == nil {
+			return nil, ErrClosed
+		}
+		if resp.ErrCode != 0 || resp.ErrMsg != "" {
+			return nil, &client.Error{ReqFuncName: reqFuncName, Code: resp.ErrCode, Msg: resp.ErrMsg,
+				Detail: resp.ErrDetail}
+		}
+		return resp, nil
+	case <-ctx.Done():
+		s.unregister(operationID)
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Session) operationID() string {
+	return s.account.UserID + "-bot-" + strconv.FormatInt(time.Now().UnixMilli(), 36) + "-" +
+		strconv.FormatInt(s.seq.Add(1), 10)
+}
@@ -0,0 +1,478 @@
+// Package client is a Go client of the gateway protocol. It sends the Req frames the js sdk sends,
+// matches the EventData responses by operationID, hands the pushed events to a Listener and
+// reconnects, logging in again, when the connection drops.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const (
+	DefaultTimeout           = 30 * time.Second
+	DefaultReconnectInterval = 3 * time.Second
+	DefaultPlatformID        = 5 // web
+
+	initEvent = "InitSDK"
+)
+
+var (
+	ErrClosed       = errors.New("client closed")
+	ErrDisconnected = errors.New("connection to the gateway lost")
+)
+
+// Config of a client, Addr is the ws address of the gateway, e.g. ws://127.0.0.1:10003.
+type Config struct {
+	Addr       string
+	UserID     string
+	Token      string
+	PlatformID int
+	// Timeout of one call when the context has no deadline, DefaultTimeout when 0.
+	Timeout time.Duration
+	// ReconnectInterval between two dials after the connection dropped, DefaultReconnectInterval
+	// when 0, a negative value disables the reconnect.
+	ReconnectInterval time.Duration
+	// Listener gets the events the gateway pushes, it may be nil.
+	Listener Listener
+	// LastSeq is the seq of the last event handled by a previous client of the user, the gateway sends the events
+	// pushed after it again. See Client.LastSeq.
+	LastSeq int64
+	// Subscription selects the listener events of the session from the connect on, nil for all of them.
+	Subscription *Subscription
+}
+
+// Req is the request frame, see module.Req.
+type Req struct {
+	ReqFuncName string `json:"reqFuncName"`
+	OperationID string `json:"operationID"`
+	Data        string `json:"data"`
+}
+
+// Event is the response and event frame, see core_func.EventData.
+type Event struct {
+	Event       string          `json:"event"`
+	ErrCode     int32           `json:"errCode"`
+	ErrMsg      string          `json:"errMsg"`
+	ErrDetail   *errcode.Detail `json:"errDetail,omitempty"`
+	Data        string          `json:"data"`
+	OperationID string          `json:"operationID"`
+	Seq         int64           `json:"seq,omitempty"` // set on the pushed events, see Client.AckEvents
+}
+
+// Error is a failed response of the gateway, Code is one of package errcode or of the sdk and the server.
+type Error struct {
+	ReqFuncName string
+	Code        int32
+	Msg         string
+	Detail      *errcode.Detail // nil from gateways older than the errcode space
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed, errCode %d: %s", e.ReqFuncName, e.Code, e.Msg)
+}
+
+// Client is one session on the gateway, its methods are safe for concurrent use.
+type Client struct {
+	cfg     Config
+	dialer  websocket.Dialer
+	ctx     context.Context
+	seq     atomic.Int64
+	lastSeq atomic.Int64 // seq of the last event the Listener handled
+	notify  chan func()
+	done    chan struct{}
+
+	mu           sync.Mutex
+	conn         *websocket.Conn
+	ready        chan struct{} // closed once the sdk of the current connection is initialized
+	pending      map[string]chan *Event
+	loggedIn     bool
+	closed       bool
+	subscription *Subscription // sent in the connect url
+
+	writeMu sync.Mutex
+}
+
+// Dial connects to the gateway and waits until the sdk of the session is initialized.
+func Dial(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.PlatformID == 0 {
+		cfg.PlatformID = DefaultPlatformID
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.ReconnectInterval == 0 {
+		cfg.ReconnectInterval = DefaultReconnectInterval
+	}
+	c := &Client{cfg: cfg, ctx: logger.NewSessionContext(context.Background(), "", cfg.UserID),
+		notify: make(chan func(), 1000), done: make(chan struct{}), ready: make(chan struct{}),
+		pending: make(map[string]chan *Event), subscription: cfg.Subscription}
+	c.dialer.HandshakeTimeout = cfg.Timeout
+	c.lastSeq.Store(cfg.LastSeq)
+	go c.dispatch()
+	if err := c.connect(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// UserID returns the user of the session.
+func (c *Client) UserID() string {
+	return c.cfg.UserID
+}
+
+// Close closes the connection, the calls in flight fail with ErrClosed.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	conn := c.conn
+	c.conn = nil
+	c.failPending(ErrClosed)
+	c.mu.Unlock()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// Call sends a request and decodes the data of its response into out, which may be nil. The args are
+// encoded the way the js sdk does: strings, numbers and bools as they are, other values as json strings.
+func (c *Client) Call(ctx context.Context, reqFuncName string, out any, args ...any) error {
+	if err := c.waitReady(ctx); err != nil {
+		return err
+	}
+	return c.call(ctx, reqFuncName, out, args...)
+}
+
+// Do sends a request frame as it is and returns its response, a failed one included. An empty
+// operationID is replaced by a new one, others must not be in flight already.
+func (c *Client) Do(ctx context.Context, req *Req) (*Event, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	if req.OperationID == "" {
+		req.OperationID = c.operationID()
+	}
+	ch, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.wait(ctx, req.OperationID, ch)
+}
+
+// waitReady blocks until the sdk of the current connection is initialized.
+func (c *Client) waitReady(ctx context.Context) error {
+	c.mu.Lock()
+	ready := c.ready
+	c.mu.Unlock()
+	select {
+	case <-ready:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) call(ctx context.Context, reqFuncName string, out any, args ...any) error {
+	data, err := EncodeArgs(args...)
+	if err != nil {
+		return err
+	}
+	req := &Req{ReqFuncName: reqFuncName, OperationID: c.operationID(), Data: data}
+	ch, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	resp, err := c.wait(ctx, req.OperationID, ch)
+	if err != nil {
+		return err
+	}
+	return decode(resp, reqFuncName, out)
+}
+
+// send writes the request once its response channel is registered.
+func (c *Client) send(req *Req) (chan *Event, error) {
+	msg, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan *Event, 1)
+	c.mu.Lock()
+	conn := c.conn
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if conn == nil {
+		c.mu.Unlock()
+		return nil, ErrDisconnected
+	}
+	c.pending[req.OperationID] = ch
+	c.mu.Unlock()
+	c.writeMu.Lock()
+	err = conn.WriteMessage(websocket.TextMessage, msg)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.unregister(req.OperationID)
+		return nil, err
+	}
+	return ch, nil
+}
+
+// wait blocks until the response arrives, a nil response means the connection was lost.
+func (c *Client) wait(ctx context.Context, operationID string, ch chan *Event) (*Event, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+	}
+	select {
+	case resp := <-ch:
+		if resp == nil {
+			c.mu.Lock()
+			closed := c.closed
+			c.mu.Unlock()
+			if closed {
+				return nil, ErrClosed
+			}
+			return nil, ErrDisconnected
+		}
+		return resp, nil
+	case <-ctx.Done():
+		c.unregister(operationID)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) unregister(operationID string) {
+	c.mu.Lock()
+	delete(c.pending, operationID)
+	c.mu.Unlock()
+}
+
+// failPending wakes up the calls waiting for a response, c.mu must be held.
+func (c *Client) failPending(err error) {
+	for operationID, ch := range c.pending {
+		delete(c.pending, operationID)
+		close(ch)
+	}
+	logger.Debug(c.ctx, "pending calls failed", "err", err)
+}
+
+func (c *Client) operationID() string {
+	return c.cfg.UserID + "-" + strconv.FormatInt(time.Now().UnixMilli(), 36) + "-" + strconv.FormatInt(c.seq.Add(1), 10)
+}
+
+// decode returns the error of a failed response or decodes its data into out.
+func decode(resp *Event, reqFuncName string, out any) error {
+	if resp.ErrCode != 0 || resp.ErrMsg != "" {
+		return &Error{ReqFuncName: reqFuncName, Code: resp.ErrCode, Msg: resp.ErrMsg, Detail: resp.ErrDetail}
+	}
+	if out == nil || resp.Data == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(resp.Data), out); err != nil {
+		return fmt.Errorf("%s response decode error: %w", reqFuncName, err)
+	}
+	return nil
+}
+
+// EncodeArgs returns the data field of a request carrying the args.
+func EncodeArgs(args ...any) (string, error) {
+	values := make([]any, 0, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			return "", fmt.Errorf("args[%d] is nil", i)
+		}
+		v := reflect.ValueOf(arg)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr:
+			b, err := json.Marshal(arg)
+			if err != nil {
+				return "", fmt.Errorf("args[%d] encode error: %w", i, err)
+			}
+			values = append(values, string(b))
+		default:
+			values = append(values, arg)
+		}
+	}
+	b, err := json.Marshal(values)
+	return string(b), err
+}
+
+// connect dials the gateway, waits for the sdk to be initialized and logs in again when the session was.
+func (c *Client) connect(ctx context.Context) error {
+	operationID := c.operationID()
+	q := url.Values{}
+	q.Set("sendID", c.cfg.UserID)
+	q.Set("token", c.cfg.Token)
+	q.Set("platformID", strconv.Itoa(c.cfg.PlatformID))
+	q.Set("operationID", operationID)
+	if seq := c.lastSeq.Load(); seq > 0 {
+		q.Set("lastSeq", strconv.FormatInt(seq, 10))
+	}
+	c.mu.Lock()
+	if c.subscription != nil {
+		c.subscription.setQuery(q)
+	}
+	c.mu.Unlock()
+	conn, _, err := c.dialer.DialContext(ctx, c.cfg.Addr+"/?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	ch := make(chan *Event, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		_ = conn.Close()
+		return ErrClosed
+	}
+	c.conn = conn
+	c.pending[operationID] = ch
+	loggedIn := c.loggedIn
+	c.mu.Unlock()
+	go c.read(conn)
+	resp, err := c.wait(ctx, operationID, ch)
+	if err == nil {
+		err = decode(resp, initEvent, nil)
+	}
+	if err == nil && loggedIn {
+		err = c.call(ctx, "Login", nil, c.cfg.UserID, c.cfg.Token)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	c.mu.Lock()
+	close(c.ready)
+	c.mu.Unlock()
+	c.emit(func(l Listener) { l.OnConnected() })
+	return nil
+}
+
+// read dispatches the frames of a connection until it fails.
+func (c *Client) read(conn *websocket.Conn) {
+	var err error
+	for {
+		var msg []byte
+		var messageType int
+		if messageType, msg, err = conn.ReadMessage(); err != nil {
+			break
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		ev := &Event{}
+		if json.Unmarshal(msg, ev) != nil || ev.Event == "" {
+			logger.Debug(c.ctx, "unknown frame", "msg", string(msg))
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[ev.OperationID]
+		if ok && ev.OperationID != "" {
+			delete(c.pending, ev.OperationID)
+		}
+		c.mu.Unlock()
+		if ok && ev.OperationID != "" {
+			ch <- ev
+			continue
+		}
+		c.emit(func(l Listener) {
+			l.OnEvent(ev)
+			if ev.Seq > 0 {
+				c.lastSeq.Store(ev.Seq)
+			}
+		})
+	}
+	c.disconnected(conn, err)
+}
+
+// disconnected fails the pending calls of a dropped connection and starts the reconnect. A connection
+// lost before it was ready is left to the connect that dialed it.
+func (c *Client) disconnected(conn *websocket.Conn, err error) {
+	_ = conn.Close()
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return
+	}
+	c.conn = nil
+	c.failPending(ErrDisconnected)
+	wasReady := false
+	select {
+	case <-c.ready:
+		c.ready = make(chan struct{})
+		wasReady = true
+	default:
+	}
+	c.mu.Unlock()
+	if !wasReady {
+		return
+	}
+	logger.Info(c.ctx, "gateway connection lost", "err", err)
+	c.emit(func(l Listener) { l.OnDisconnected(err) })
+	if c.cfg.ReconnectInterval > 0 {
+		go c.reconnect()
+	}
+}
+
+func (c *Client) reconnect() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(c.cfg.ReconnectInterval):
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+		err := c.connect(ctx)
+		cancel()
+		if err == nil || errors.Is(err, ErrClosed) {
+			return
+		}
+		logger.Info(c.ctx, "gateway reconnect error", "addr", c.cfg.Addr, "err", err)
+	}
+}
+
+// emit queues a listener callback, they run one by one outside the read loop so they may call the client.
+func (c *Client) emit(fn func(Listener)) {
+	if c.cfg.Listener == nil {
+		return
+	}
+	select {
+	case c.notify <- func() { fn(c.cfg.Listener) }:
+	case <-c.done:
+	}
+}
+
+func (c *Client) dispatch() {
+	for {
+		select {
+		case fn := <-c.notify:
+			fn()
+		case <-c.done:
+			return
+		}
+	}
+}
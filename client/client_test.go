@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/fakeim"
+	"github.com/yrzs/openimwssdk/fakeim/fakegate"
+)
+
+// eventListener collects the pushed events and connection changes.
+type eventListener struct {
+	events chan *Event
+}
+
+func (l *eventListener) OnEvent(ev *Event)        { l.events <- ev }
+func (l *eventListener) OnConnected()             { l.events <- &Event{Event: "connected"} }
+func (l *eventListener) OnDisconnected(err error) { l.events <- &Event{Event: "disconnected"} }
+
+// wait skips the other events until the named one.
+func (l *eventListener) wait(t *testing.T, event string) *Event {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case ev := <-l.events:
+			if ev.Event == event {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("event %s not received", event)
+			return nil
+		}
+	}
+}
+
+// newGateway starts a gateway backed by a fake OpenIM server and returns its ws address.
+func newGateway(t *testing.T) (string, *fakeim.Server) {
+	fake := fakeim.NewServer()
+	t.Cleanup(fake.Close)
+	g, err := fakegate.Start(fake, t.TempDir())
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		g.Close()
+		datadir.Init(datadir.Config{})
+	})
+	return g.Addr, fake
+}
+
+// login dials the gateway as a new user and waits until the sdk has synced with the server.
+func login(t *testing.T, addr string, fake *fakeim.Server, userID string) (*Client, *eventListener) {
+	token := fake.AddUser(userID, "nick_"+userID)
+	l := &eventListener{events: make(chan *Event, 1000)}
+	ctx := context.Background()
+	var c *Client
+	var err error
+	assert.Eventually(t, func() bool {
+		c, err = Dial(ctx, Config{Addr: addr, UserID: userID, Token: token, Listener: l,
+			ReconnectInterval: 100 * time.Millisecond})
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	t.Cleanup(func() { _ = c.Close() })
+	assert.Nil(t, c.Login(ctx))
+	l.wait(t, "OnSyncServerFinish")
+	assert.True(t, fake.WaitIdle(200*time.Millisecond, 5*time.Second))
+	return c, l
+}
+
+func TestEncodeArgs(t *testing.T) {
+	data, err := EncodeArgs("u1", 20, true, []string{"a", "b"}, &Req{ReqFuncName: "Login"})
+	assert.Nil(t, err)
+	assert.Equal(t, `["u1",20,true,"[\"a\",\"b\"]","{\"reqFuncName\":\"Login\",\"operationID\":\"\",\"data\":\"\"}"]`, data)
+	_, err = EncodeArgs("u1", nil)
+	assert.NotNil(t, err)
+}
+
+func TestClient(t *testing.T) {
+	addr, fake := newGateway(t)
+	fake.AddUser("u2", "Bob")
+	fake.AddFriend("u1", "u2")
+	ctx := context.Background()
+	c1, _ := login(t, addr, fake, "u1")
+
+	self, err := c1.GetSelfUserInfo(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "nick_u1", self.Nickname)
+	friends, err := c1.GetFriendList(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, friends, 1) {
+		assert.Equal(t, "Bob", friends[0].FriendInfo.Nickname)
+	}
+	methods, err := c1.GetMethods(ctx)
+	assert.Nil(t, err)
+	assert.True(t, methods.Has("SendMessage"))
+
+	err = c1.Call(ctx, "NoSuchFunc", nil)
+	if e, ok := err.(*Error); assert.True(t, ok, err) {
+		assert.Equal(t, "NoSuchFunc", e.ReqFuncName)
+		assert.NotZero(t, e.Code)
+	}
+}
+
+func TestClientSendMessage(t *testing.T) {
+	addr, fake := newGateway(t)
+	ctx := context.Background()
+	c1, _ := login(t, addr, fake, "u1")
+	c2, l2 := login(t, addr, fake, "u2")
+
+	msg, err := c1.CreateTextMessage(ctx, "hello")
+	assert.Nil(t, err)
+	sent, err := c1.SendMessage(ctx, msg, "u2", "", nil, false)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, sent.ServerMsgID)
+	ev := l2.wait(t, "OnRecvNewMessages")
+	assert.Contains(t, ev.Data, sent.ClientMsgID)
+	assert.NotZero(t, ev.Seq)
+	ack, err := c2.AckEvents(ctx, ev.Seq)
+	assert.Nil(t, err)
+	if assert.NotNil(t, ack) {
+		assert.Equal(t, ev.Seq, ack.Acked)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	addr, fake := newGateway(t)
+	ctx := context.Background()
+	c, l := login(t, addr, fake, "u1")
+
+	c.mu.Lock()
+	_ = c.conn.Close()
+	c.mu.Unlock()
+	l.wait(t, "disconnected")
+	l.wait(t, "connected")
+	status, err := c.GetLoginStatus(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, status) // logged
+	self, err := c.GetSelfUserInfo(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "u1", self.UserID)
+
+	assert.Nil(t, c.Close())
+	assert.Equal(t, ErrClosed, c.Call(ctx, "GetSelfUserInfo", nil))
+}
+
+func TestDialRejected(t *testing.T) {
+	addr, _ := newGateway(t)
+	var err error
+	assert.Eventually(t, func() bool { // until the gateway listens
+		_, err = Dial(context.Background(), Config{Addr: addr, UserID: "u1", Timeout: 5 * time.Second})
+		return errors.As(err, new(*Error))
+	}, 5*time.Second, 50*time.Millisecond)
+	var e *Error
+	if assert.ErrorAs(t, err, &e) {
+		assert.Equal(t, errcode.TokenMissing, e.Code)
+		assert.Equal(t, errcode.CategoryAuth, e.Detail.Category)
+	}
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+
+	"github.com/openimsdk/protocol/sdkws"
+	"github.com/yrzs/openimsdkcore/pkg/db/model_struct"
+	"github.com/yrzs/openimsdkcore/pkg/sdk_params_callback"
+	"github.com/yrzs/openimsdkcore/sdk_struct"
+)
+
+// GetAllConversationList returns the conversations of the logged in user.
+func (c *Client) GetAllConversationList(ctx context.Context) ([]*model_struct.LocalConversation, error) {
+	var conversations []*model_struct.LocalConversation
+	err := c.Call(ctx, "GetAllConversationList", &conversations)
+	return conversations, err
+}
+
+// GetConversationListSplit returns a page of the conversations.
+func (c *Client) GetConversationListSplit(ctx context.Context, offset, count int) ([]*model_struct.LocalConversation, error) {
+	var conversations []*model_struct.LocalConversation
+	err := c.Call(ctx, "GetConversationListSplit", &conversations, offset, count)
+	return conversations, err
+}
+
+// GetOneConversation returns the conversation with a user or group, creating it when needed.
+func (c *Client) GetOneConversation(ctx context.Context, sessionType int32, sourceID string) (*model_struct.LocalConversation, error) {
+	conversation := &model_struct.LocalConversation{}
+	if err := c.Call(ctx, "GetOneConversation", conversation, sessionType, sourceID); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// GetMultipleConversation returns the conversations.
+func (c *Client) GetMultipleConversation(ctx context.Context, conversationIDs []string) ([]*model_struct.LocalConversation, error) {
+	var conversations []*model_struct.LocalConversation
+	err := c.Call(ctx, "GetMultipleConversation", &conversations, conversationIDs)
+	return conversations, err
+}
+
+// GetConversationIDBySessionType returns the id of the conversation with a user or group.
+func (c *Client) GetConversationIDBySessionType(ctx context.Context, sourceID string, sessionType int) (string, error) {
+	var conversationID string
+	err := c.Call(ctx, "GetConversationIDBySessionType", &conversationID, sourceID, sessionType)
+	return conversationID, err
+}
+
+// GetTotalUnreadMsgCount returns the unread messages of all the conversations.
+func (c *Client) GetTotalUnreadMsgCount(ctx context.Context) (int32, error) {
+	var count int32
+	err := c.Call(ctx, "GetTotalUnreadMsgCount", &count)
+	return count, err
+}
+
+// MarkConversationMessageAsRead marks the messages of a conversation as read.
+func (c *Client) MarkConversationMessageAsRead(ctx context.Context, conversationID string) error {
+	return c.Call(ctx, "MarkConversationMessageAsRead", nil, conversationID)
+}
+
+// PinConversation pins or unpins a conversation.
+func (c *Client) PinConversation(ctx context.Context, conversationID string, isPinned bool) error {
+	return c.Call(ctx, "PinConversation", nil, conversationID, isPinned)
+}
+
+// HideConversation removes a conversation from the list until its next message.
+func (c *Client) HideConversation(ctx context.Context, conversationID string) error {
+	return c.Call(ctx, "HideConversation", nil, conversationID)
+}
+
+// SetConversationDraft sets the draft of a conversation.
+func (c *Client) SetConversationDraft(ctx context.Context, conversationID, draftText string) error {
+	return c.Call(ctx, "SetConversationDraft", nil, conversationID, draftText)
+}
+
+// SetConversationRecvMessageOpt sets how the messages of a conversation are received.
+func (c *Client) SetConversationRecvMessageOpt(ctx context.Context, conversationID string, opt int) error {
+	return c.Call(ctx, "SetConversationRecvMessageOpt", nil, conversationID, opt)
+}
+
+// DeleteConversationAndDeleteAllMsg deletes a conversation and its messages.
+func (c *Client) DeleteConversationAndDeleteAllMsg(ctx context.Context, conversationID string) error {
+	return c.Call(ctx, "DeleteConversationAndDeleteAllMsg", nil, conversationID)
+}
+
+// CreateTextMessage returns a text message to send.
+func (c *Client) CreateTextMessage(ctx context.Context, text string) (*sdk_struct.MsgStruct, error) {
+	return c.createMessage(ctx, "CreateTextMessage", text)
+}
+
+// CreateTextAtMessage returns a text message mentioning group members, quote may be nil.
+func (c *Client) CreateTextAtMessage(ctx context.Context, text string, userIDs []string, usersInfo []*sdk_struct.AtInfo,
+	quote *sdk_struct.MsgStruct) (*sdk_struct.MsgStruct, error) {
+	if quote == nil {
+		quote = &sdk_struct.MsgStruct{}
+	}
+	return c.createMessage(ctx, "CreateTextAtMessage", text, userIDs, usersInfo, quote)
+}
+
+// CreateCustomMessage returns a message carrying custom data.
+func (c *Client) CreateCustomMessage(ctx context.Context, data, extension, description string) (*sdk_struct.MsgStruct, error) {
+	return c.createMessage(ctx, "CreateCustomMessage", data, extension, description)
+}
+
+// CreateImageMessageByURL returns an image message of pictures already uploaded.
+func (c *Client) CreateImageMessageByURL(ctx context.Context, sourcePath string, sourcePicture, bigPicture,
+	snapshotPicture sdk_struct.PictureBaseInfo) (*sdk_struct.MsgStruct, error) {
+	return c.createMessage(ctx, "CreateImageMessageByURL", sourcePath, sourcePicture, bigPicture, snapshotPicture)
+}
+
+func (c *Client) createMessage(ctx context.Context, reqFuncName string, args ...any) (*sdk_struct.MsgStruct, error) {
+	msg := &sdk_struct.MsgStruct{}
+	if err := c.Call(ctx, reqFuncName, msg, args...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// SendMessage sends a message to a user, recvID, or a group, groupID, and returns it as sent.
+// offlinePushInfo may be nil.
+func (c *Client) SendMessage(ctx context.Context, msg *sdk_struct.MsgStruct, recvID, groupID string,
+	offlinePushInfo *sdkws.OfflinePushInfo, isOnlineOnly bool) (*sdk_struct.MsgStruct, error) {
+	return c.sendMessage(ctx, "SendMessage", msg, recvID, groupID, offlinePushInfo, isOnlineOnly)
+}
+
+// SendMessageNotOss sends a message whose files are already uploaded.
+func (c *Client) SendMessageNotOss(ctx context.Context, msg *sdk_struct.MsgStruct, recvID, groupID string,
+	offlinePushInfo *sdkws.OfflinePushInfo, isOnlineOnly bool) (*sdk_struct.MsgStruct, error) {
+	return c.sendMessage(ctx, "SendMessageNotOss", msg, recvID, groupID, offlinePushInfo, isOnlineOnly)
+}
+
+func (c *Client) sendMessage(ctx context.Context, reqFuncName string, msg *sdk_struct.MsgStruct, recvID, groupID string,
+	offlinePushInfo *sdkws.OfflinePushInfo, isOnlineOnly bool) (*sdk_struct.MsgStruct, error) {
+	if offlinePushInfo == nil {
+		offlinePushInfo = &sdkws.OfflinePushInfo{}
+	}
+	return c.createMessage(ctx, reqFuncName, msg, recvID, groupID, offlinePushInfo, isOnlineOnly)
+}
+
+// GetAdvancedHistoryMessageList returns a page of the messages of a conversation, newest first.
+func (c *Client) GetAdvancedHistoryMessageList(ctx context.Context, params sdk_params_callback.GetAdvancedHistoryMessageListParams) (
+	*sdk_params_callback.GetAdvancedHistoryMessageListCallback, error) {
+	result := &sdk_params_callback.GetAdvancedHistoryMessageListCallback{}
+	if err := c.Call(ctx, "GetAdvancedHistoryMessageList", result, params); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindMessageList returns the messages of the conversations.
+func (c *Client) FindMessageList(ctx context.Context, args []*sdk_params_callback.ConversationArgs) (
+	*sdk_params_callback.FindMessageListCallback, error) {
+	result := &sdk_params_callback.FindMessageListCallback{}
+	if err := c.Call(ctx, "FindMessageList", result, args); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SearchLocalMessages searches the messages stored by the sdk.
+func (c *Client) SearchLocalMessages(ctx context.Context, params *sdk_params_callback.SearchLocalMessagesParams) (
+	*sdk_params_callback.SearchLocalMessagesCallback, error) {
+	result := &sdk_params_callback.SearchLocalMessagesCallback{}
+	if err := c.Call(ctx, "SearchLocalMessages", result, params); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RevokeMessage revokes a sent message.
+func (c *Client) RevokeMessage(ctx context.Context, conversationID, clientMsgID string) error {
+	return c.Call(ctx, "RevokeMessage", nil, conversationID, clientMsgID)
+}
+
+// DeleteMessage deletes a message locally and on the server.
+func (c *Client) DeleteMessage(ctx context.Context, conversationID, clientMsgID string) error {
+	return c.Call(ctx, "DeleteMessage", nil, conversationID, clientMsgID)
+}
+
+// TypingStatusUpdate tells a user the logged in user is typing.
+func (c *Client) TypingStatusUpdate(ctx context.Context, recvID, msgTip string) error {
+	return c.Call(ctx, "TypingStatusUpdate", nil, recvID, msgTip)
+}
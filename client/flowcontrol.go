@@ -0,0 +1,20 @@
+package client
+
+import "context"
+
+// FlowControl is the mailbox state of the session, also pushed as the OnFlowControl event.
+type FlowControl struct {
+	Size      int   `json:"size"`
+	Credit    int   `json:"credit"`   // requests that can be sent before the mailbox is full
+	SlowDown  bool  `json:"slowDown"` // wait for an OnFlowControl event with slowDown false before bursting again
+	Overflows int64 `json:"overflows"`
+}
+
+// GetFlowControl returns the mailbox state of the session, it does not need a login.
+func (c *Client) GetFlowControl(ctx context.Context) (*FlowControl, error) {
+	var fc FlowControl
+	if err := c.Call(ctx, "GetFlowControl", &fc); err != nil {
+		return nil, err
+	}
+	return &fc, nil
+}
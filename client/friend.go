@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+
+	"github.com/openimsdk/protocol/relation"
+	"github.com/yrzs/openimsdkcore/pkg/db/model_struct"
+	"github.com/yrzs/openimsdkcore/pkg/sdk_params_callback"
+	"github.com/yrzs/openimsdkcore/pkg/server_api_params"
+)
+
+// GetFriendList returns the friends of the logged in user.
+func (c *Client) GetFriendList(ctx context.Context) ([]*server_api_params.FullUserInfo, error) {
+	var friends []*server_api_params.FullUserInfo
+	err := c.Call(ctx, "GetFriendList", &friends)
+	return friends, err
+}
+
+// GetSpecifiedFriendsInfo returns the information of the friends.
+func (c *Client) GetSpecifiedFriendsInfo(ctx context.Context, friendUserIDs []string) ([]*server_api_params.FullUserInfo, error) {
+	var friends []*server_api_params.FullUserInfo
+	err := c.Call(ctx, "GetSpecifiedFriendsInfo", &friends, friendUserIDs)
+	return friends, err
+}
+
+// CheckFriend returns the friendship of the users with the logged in user.
+func (c *Client) CheckFriend(ctx context.Context, friendUserIDs []string) ([]*server_api_params.UserIDResult, error) {
+	var results []*server_api_params.UserIDResult
+	err := c.Call(ctx, "CheckFriend", &results, friendUserIDs)
+	return results, err
+}
+
+// AddFriend sends a friend application.
+func (c *Client) AddFriend(ctx context.Context, req *relation.ApplyToAddFriendReq) error {
+	return c.Call(ctx, "AddFriend", nil, req)
+}
+
+// DeleteFriend removes a friend.
+func (c *Client) DeleteFriend(ctx context.Context, friendUserID string) error {
+	return c.Call(ctx, "DeleteFriend", nil, friendUserID)
+}
+
+// SetFriendRemark sets the remark of a friend.
+func (c *Client) SetFriendRemark(ctx context.Context, params *sdk_params_callback.SetFriendRemarkParams) error {
+	return c.Call(ctx, "SetFriendRemark", nil, params)
+}
+
+// GetFriendApplicationListAsRecipient returns the friend applications the logged in user received.
+func (c *Client) GetFriendApplicationListAsRecipient(ctx context.Context) ([]*model_struct.LocalFriendRequest, error) {
+	var requests []*model_struct.LocalFriendRequest
+	err := c.Call(ctx, "GetFriendApplicationListAsRecipient", &requests)
+	return requests, err
+}
+
+// GetFriendApplicationListAsApplicant returns the friend applications the logged in user sent.
+func (c *Client) GetFriendApplicationListAsApplicant(ctx context.Context) ([]*model_struct.LocalFriendRequest, error) {
+	var requests []*model_struct.LocalFriendRequest
+	err := c.Call(ctx, "GetFriendApplicationListAsApplicant", &requests)
+	return requests, err
+}
+
+// AcceptFriendApplication accepts a friend application.
+func (c *Client) AcceptFriendApplication(ctx context.Context, params *sdk_params_callback.ProcessFriendApplicationParams) error {
+	return c.Call(ctx, "AcceptFriendApplication", nil, params)
+}
+
+// RefuseFriendApplication refuses a friend application.
+func (c *Client) RefuseFriendApplication(ctx context.Context, params *sdk_params_callback.ProcessFriendApplicationParams) error {
+	return c.Call(ctx, "RefuseFriendApplication", nil, params)
+}
+
+// GetBlackList returns the users the logged in user blocked.
+func (c *Client) GetBlackList(ctx context.Context) ([]*model_struct.LocalBlack, error) {
+	var blacks []*model_struct.LocalBlack
+	err := c.Call(ctx, "GetBlackList", &blacks)
+	return blacks, err
+}
+
+// AddBlack blocks a user.
+func (c *Client) AddBlack(ctx context.Context, blackUserID, ex string) error {
+	return c.Call(ctx, "AddBlack", nil, blackUserID, ex)
+}
+
+// RemoveBlack unblocks a user.
+func (c *Client) RemoveBlack(ctx context.Context, blackUserID string) error {
+	return c.Call(ctx, "RemoveBlack", nil, blackUserID)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+
+	"github.com/openimsdk/protocol/group"
+	"github.com/openimsdk/protocol/sdkws"
+	"github.com/yrzs/openimsdkcore/pkg/db/model_struct"
+)
+
+// CreateGroup creates a group and returns it.
+func (c *Client) CreateGroup(ctx context.Context, req *group.CreateGroupReq) (*sdkws.GroupInfo, error) {
+	info := &sdkws.GroupInfo{}
+	if err := c.Call(ctx, "CreateGroup", info, req); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// JoinGroup applies to join a group.
+func (c *Client) JoinGroup(ctx context.Context, groupID, reqMsg string, joinSource int32, ex string) error {
+	return c.Call(ctx, "JoinGroup", nil, groupID, reqMsg, joinSource, ex)
+}
+
+// QuitGroup leaves a group.
+func (c *Client) QuitGroup(ctx context.Context, groupID string) error {
+	return c.Call(ctx, "QuitGroup", nil, groupID)
+}
+
+// DismissGroup dismisses a group owned by the logged in user.
+func (c *Client) DismissGroup(ctx context.Context, groupID string) error {
+	return c.Call(ctx, "DismissGroup", nil, groupID)
+}
+
+// GetJoinedGroupList returns the groups the logged in user is a member of.
+func (c *Client) GetJoinedGroupList(ctx context.Context) ([]*model_struct.LocalGroup, error) {
+	var groups []*model_struct.LocalGroup
+	err := c.Call(ctx, "GetJoinedGroupList", &groups)
+	return groups, err
+}
+
+// GetSpecifiedGroupsInfo returns the information of the groups.
+func (c *Client) GetSpecifiedGroupsInfo(ctx context.Context, groupIDs []string) ([]*model_struct.LocalGroup, error) {
+	var groups []*model_struct.LocalGroup
+	err := c.Call(ctx, "GetSpecifiedGroupsInfo", &groups, groupIDs)
+	return groups, err
+}
+
+// GetGroupMemberList returns a page of the members of a group, filter 0 returns all of them.
+func (c *Client) GetGroupMemberList(ctx context.Context, groupID string, filter, offset, count int32) ([]*model_struct.LocalGroupMember, error) {
+	var members []*model_struct.LocalGroupMember
+	err := c.Call(ctx, "GetGroupMemberList", &members, groupID, filter, offset, count)
+	return members, err
+}
+
+// GetSpecifiedGroupMembersInfo returns the information of members of a group.
+func (c *Client) GetSpecifiedGroupMembersInfo(ctx context.Context, groupID string, userIDs []string) ([]*model_struct.LocalGroupMember, error) {
+	var members []*model_struct.LocalGroupMember
+	err := c.Call(ctx, "GetSpecifiedGroupMembersInfo", &members, groupID, userIDs)
+	return members, err
+}
+
+// InviteUserToGroup adds users to a group.
+func (c *Client) InviteUserToGroup(ctx context.Context, groupID, reason string, userIDs []string) error {
+	return c.Call(ctx, "InviteUserToGroup", nil, groupID, reason, userIDs)
+}
+
+// KickGroupMember removes members from a group.
+func (c *Client) KickGroupMember(ctx context.Context, groupID, reason string, userIDs []string) error {
+	return c.Call(ctx, "KickGroupMember", nil, groupID, reason, userIDs)
+}
+
+// TransferGroupOwner gives a group to another member.
+func (c *Client) TransferGroupOwner(ctx context.Context, groupID, newOwnerUserID string) error {
+	return c.Call(ctx, "TransferGroupOwner", nil, groupID, newOwnerUserID)
+}
+
+// IsJoinGroup reports whether the logged in user is a member of the group.
+func (c *Client) IsJoinGroup(ctx context.Context, groupID string) (bool, error) {
+	var joined bool
+	err := c.Call(ctx, "IsJoinGroup", &joined, groupID)
+	return joined, err
+}
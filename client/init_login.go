@@ -0,0 +1,35 @@
+package client
+
+import "context"
+
+// Login logs the session in with the user and token of the config, the client logs in again by
+// itself after a reconnect. The sdk then syncs with the server, OnSyncServerFinish tells when it is done.
+func (c *Client) Login(ctx context.Context) error {
+	if err := c.Call(ctx, "Login", nil, c.cfg.UserID, c.cfg.Token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.loggedIn = true
+	c.mu.Unlock()
+	return nil
+}
+
+// Logout logs the session out, the connection stays open.
+func (c *Client) Logout(ctx context.Context) error {
+	c.mu.Lock()
+	c.loggedIn = false
+	c.mu.Unlock()
+	return c.Call(ctx, "Logout", nil)
+}
+
+// GetLoginStatus returns the login status of the sdk, see openimsdkcore pkg/constant.
+func (c *Client) GetLoginStatus(ctx context.Context) (int, error) {
+	var status int
+	err := c.Call(ctx, "GetLoginStatus", &status)
+	return status, err
+}
+
+// SetAppBackgroundStatus tells the server whether the app is in background.
+func (c *Client) SetAppBackgroundStatus(ctx context.Context, isBackground bool) error {
+	return c.Call(ctx, "SetAppBackgroundStatus", nil, isBackground)
+}
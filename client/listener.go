@@ -0,0 +1,20 @@
+package client
+
+// Listener gets the events the gateway pushes without an operationID, e.g. OnRecvNewMessages or
+// OnSyncServerFinish, see core_func/ws_listener.go for their names. The callbacks run one at a time
+// in the order the frames arrived.
+type Listener interface {
+	OnEvent(ev *Event)
+	// OnConnected is called once the sdk of a new connection is initialized and, after a reconnect,
+	// logged in again.
+	OnConnected()
+	// OnDisconnected is called when a ready connection is lost.
+	OnDisconnected(err error)
+}
+
+// EventFunc is a Listener only interested in the pushed events.
+type EventFunc func(ev *Event)
+
+func (f EventFunc) OnEvent(ev *Event)    { f(ev) }
+func (f EventFunc) OnConnected()         {}
+func (f EventFunc) OnDisconnected(error) {}
@@ -0,0 +1,43 @@
+package client
+
+import "context"
+
+// Param is a param of a gateway method, Wire is how it is sent: string, number, boolean, json or any.
+type Param struct {
+	Type string `json:"type"`
+	Wire string `json:"wire"`
+}
+
+// Method is a reqFuncName served by the gateway.
+type Method struct {
+	Name       string   `json:"name"`
+	Params     []Param  `json:"params"`
+	Results    []string `json:"results"`
+	Deprecated bool     `json:"deprecated"`
+}
+
+// Methods is what a gateway build serves.
+type Methods struct {
+	Version    string    `json:"version"`
+	SdkVersion string    `json:"sdkVersion"`
+	Methods    []*Method `json:"methods"`
+}
+
+// Has reports whether the gateway serves the reqFuncName.
+func (m *Methods) Has(reqFuncName string) bool {
+	for _, method := range m.Methods {
+		if method.Name == reqFuncName {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMethods returns the methods of the gateway, it does not need a login.
+func (c *Client) GetMethods(ctx context.Context) (*Methods, error) {
+	var methods Methods
+	if err := c.Call(ctx, "GetMethods", &methods); err != nil {
+		return nil, err
+	}
+	return &methods, nil
+}
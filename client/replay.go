@@ -0,0 +1,26 @@
+package client
+
+import "context"
+
+// EventAck is the replay buffer of the user on the gateway after an ack.
+type EventAck struct {
+	Acked   int64 `json:"acked"`
+	LastSeq int64 `json:"lastSeq"` // seq of the last event pushed
+	Pending int   `json:"pending"` // events the gateway still sends again on reconnect
+}
+
+// LastSeq returns the seq of the last pushed event the Listener handled. The client sends it when it reconnects so
+// the gateway replays what the connection lost; keep it as Config.LastSeq to resume from a new client.
+func (c *Client) LastSeq() int64 {
+	return c.lastSeq.Load()
+}
+
+// AckEvents tells the gateway the events up to seq are handled and need not be replayed, it does not need a login.
+// Reconnecting acknowledges LastSeq as well.
+func (c *Client) AckEvents(ctx context.Context, seq int64) (*EventAck, error) {
+	var ack EventAck
+	if err := c.Call(ctx, "AckEvents", &ack, seq); err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
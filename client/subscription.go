@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Subscription selects the listener events the session receives, see core_func.Subscription for the categories.
+// Empty fields select everything.
+type Subscription struct {
+	Categories      []string `json:"categories,omitempty"`
+	ConversationIDs []string `json:"conversationIDs,omitempty"` // message and conversation events of these only
+}
+
+// SubscribeEvents replaces the subscription of the session, also used when the client reconnects. It does not need
+// a login.
+func (c *Client) SubscribeEvents(ctx context.Context, sub Subscription) error {
+	if err := c.Call(ctx, "SubscribeEvents", nil, &sub); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.subscription = &sub
+	c.mu.Unlock()
+	return nil
+}
+
+// setQuery adds the subscription to the query of the connect url.
+func (sub *Subscription) setQuery(q url.Values) {
+	if len(sub.Categories) > 0 {
+		q.Set("events", strings.Join(sub.Categories, ","))
+	}
+	if len(sub.ConversationIDs) > 0 {
+		q.Set("conversationIDs", strings.Join(sub.ConversationIDs, ","))
+	}
+}
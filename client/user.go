@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+
+	"github.com/openimsdk/protocol/sdkws"
+	"github.com/yrzs/openimsdkcore/pkg/db/model_struct"
+	"github.com/yrzs/openimsdkcore/pkg/server_api_params"
+)
+
+// GetUsersInfo returns the information of the users.
+func (c *Client) GetUsersInfo(ctx context.Context, userIDs []string) ([]*server_api_params.FullUserInfo, error) {
+	var users []*server_api_params.FullUserInfo
+	err := c.Call(ctx, "GetUsersInfo", &users, userIDs)
+	return users, err
+}
+
+// GetSelfUserInfo returns the information of the logged in user.
+func (c *Client) GetSelfUserInfo(ctx context.Context) (*model_struct.LocalUser, error) {
+	user := &model_struct.LocalUser{}
+	if err := c.Call(ctx, "GetSelfUserInfo", user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetSelfInfo updates the information of the logged in user.
+func (c *Client) SetSelfInfo(ctx context.Context, userInfo *sdkws.UserInfoWithEx) error {
+	return c.Call(ctx, "SetSelfInfo", nil, userInfo)
+}
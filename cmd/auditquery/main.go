@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yrzs/openimwssdk/audit"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// parseTime accepts RFC3339 or "2006-01-02 15:04:05" in local time and returns unix milliseconds.
+func parseTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UnixMilli(), nil
+	}
+	t, err := time.ParseInLocation(timeLayout, s, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("time %q is neither RFC3339 nor %q", s, timeLayout)
+	}
+	return t.UnixMilli(), nil
+}
+
+// The main function prints the audit records of a directory matching the flags, one JSON object per line.
+func main() {
+	dir := flag.String("dir", "./audit", "audit directory of the gateway")
+	userID := flag.String("user", "", "only records of this userID")
+	sessionId := flag.String("session", "", "only records of this sessionId")
+	funcName := flag.String("func", "", "only records of this reqFuncName")
+	operationID := flag.String("op", "", "only records of this operationID")
+	from := flag.String("from", "", "records received at or after this time, RFC3339 or \""+timeLayout+"\"")
+	to := flag.String("to", "", "records received before this time, RFC3339 or \""+timeLayout+"\"")
+	errOnly := flag.Bool("errors", false, "only records with a non zero errCode")
+	limit := flag.Int("limit", 0, "stop after this many records, 0 means no limit")
+	flag.Parse()
+
+	filter := &audit.Filter{UserID: *userID, SessionId: *sessionId, ReqFuncName: *funcName, OperationID: *operationID,
+		ErrOnly: *errOnly}
+	var err error
+	if filter.From, err = parseTime(*from); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	var n int
+	err = audit.Query(*dir, filter, func(rec *audit.Record) bool {
+		_ = enc.Encode(rec)
+		n++
+		return *limit <= 0 || n < *limit
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
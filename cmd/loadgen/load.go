@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimwssdk/admin"
+	"github.com/yrzs/openimwssdk/client"
+)
+
+const (
+	connectName = "connect"
+	syncName    = "sync" // from the Login response to OnSyncServerFinish
+	syncEvent   = "OnSyncServerFinish"
+)
+
+// user is a simulated user, Token is what the gateway checks.
+type user struct {
+	UserID string
+	Token  string
+}
+
+// mixEntry is a reqFuncName of the request mix and its weight.
+type mixEntry struct {
+	name   string
+	weight int
+}
+
+// parseMix reads "GetSelfUserInfo=4,GetFriendList=1", a name without weight counts once.
+func parseMix(s string) ([]mixEntry, error) {
+	var mix []mixEntry
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weight, found := strings.Cut(part, "=")
+		entry := mixEntry{name: strings.TrimSpace(name), weight: 1}
+		if found {
+			w, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("weight of %s is not a positive integer", entry.name)
+			}
+			entry.weight = w
+		}
+		mix = append(mix, entry)
+	}
+	return mix, nil
+}
+
+// pick returns a reqFuncName of the mix according to the weights.
+func pick(mix []mixEntry, r *rand.Rand) string {
+	total := 0
+	for _, e := range mix {
+		total += e.weight
+	}
+	n := r.Intn(total)
+	for _, e := range mix {
+		if n < e.weight {
+			return e.name
+		}
+		n -= e.weight
+	}
+	return mix[len(mix)-1].name
+}
+
+// loadConfig describes a run.
+type loadConfig struct {
+	Addr        string
+	Users       []user
+	ConnectRate float64 // dials per second
+	ReqRate     float64 // requests of the mix per user per second
+	MsgRate     float64 // text messages per user per second, to a random other user
+	Mix         []mixEntry
+	Duration    time.Duration // of the load once every user is connected
+	Timeout     time.Duration
+	// Runtime reads the memory of the gateway, it may be nil.
+	Runtime func() (*admin.RuntimeStats, error)
+}
+
+// listener counts the pushed events of a user and signals its sync.
+type listener struct {
+	stats  *stats
+	synced chan struct{}
+	once   sync.Once
+}
+
+func (l *listener) OnEvent(ev *client.Event) {
+	l.stats.event(ev.Event)
+	if ev.Event == syncEvent {
+		l.once.Do(func() { close(l.synced) })
+	}
+}
+func (l *listener) OnConnected()         {}
+func (l *listener) OnDisconnected(error) {}
+
+// run connects the users at the connect rate, loads the gateway with them and reports.
+func run(cfg *loadConfig) *Report {
+	s := newStats()
+	rep := &Report{Users: len(cfg.Users)}
+	if cfg.Runtime != nil {
+		rep.Before, _ = cfg.Runtime()
+	}
+	load, stop := context.WithCancel(context.Background())
+	defer stop()
+	var connected, done sync.WaitGroup
+	var mu sync.Mutex
+	var clients []*client.Client
+	interval := time.Duration(float64(time.Second) / cfg.ConnectRate)
+	for i, u := range cfg.Users {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		connected.Add(1)
+		done.Add(1)
+		go func(u user, seed int64) {
+			defer done.Done()
+			c := connect(cfg, u, s)
+			mu.Lock()
+			if c != nil {
+				rep.Connected++
+				clients = append(clients, c)
+			} else {
+				rep.Failed++
+			}
+			mu.Unlock()
+			connected.Done()
+			if c != nil {
+				simulate(load, cfg, c, s, rand.New(rand.NewSource(seed)))
+			}
+		}(u, int64(i))
+	}
+	connected.Wait()
+	if cfg.Runtime != nil {
+		rep.After, _ = cfg.Runtime()
+	}
+	if rep.Before != nil && rep.After != nil && rep.Connected > 0 {
+		rep.HeapPerNew = (int64(rep.After.HeapInuse) - int64(rep.Before.HeapInuse)) / int64(rep.Connected)
+		rep.SysPerNew = (int64(rep.After.Sys) - int64(rep.Before.Sys)) / int64(rep.Connected)
+	}
+	start := time.Now()
+	time.Sleep(cfg.Duration)
+	stop()
+	done.Wait()
+	rep.Duration = time.Since(start).Seconds()
+	for _, c := range clients {
+		_ = c.Close()
+	}
+	rep.Calls, rep.Events = s.report()
+	return rep
+}
+
+// connect dials, logs in and waits for the sync of a user, nil when one of them failed.
+func connect(cfg *loadConfig, u user, s *stats) *client.Client {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	l := &listener{stats: s, synced: make(chan struct{})}
+	start := time.Now()
+	c, err := client.Dial(ctx, client.Config{Addr: cfg.Addr, UserID: u.UserID, Token: u.Token, Timeout: cfg.Timeout,
+		Listener: l})
+	s.record(connectName, time.Since(start), err)
+	if err != nil {
+		return nil
+	}
+	start = time.Now()
+	err = c.Login(ctx)
+	s.record("Login", time.Since(start), err)
+	if err == nil {
+		start = time.Now()
+		select {
+		case <-l.synced:
+		case <-ctx.Done():
+			err = errors.New("sync timeout")
+		}
+		s.record(syncName, time.Since(start), err)
+	}
+	if err != nil {
+		_ = c.Close()
+		return nil
+	}
+	return c
+}
+
+// simulate sends the requests of the mix and the messages of a user until the load is stopped.
+func simulate(load context.Context, cfg *loadConfig, c *client.Client, s *stats, r *rand.Rand) {
+	reqs, msgs := ticker(load, cfg.ReqRate, r), ticker(load, cfg.MsgRate, r)
+	for {
+		select {
+		case <-load.Done():
+			return
+		case <-reqs:
+			name := pick(cfg.Mix, r)
+			ctx, cancel := context.WithTimeout(load, cfg.Timeout)
+			start := time.Now()
+			err := c.Call(ctx, name, nil)
+			cancel()
+			if load.Err() == nil {
+				s.record(name, time.Since(start), err)
+			}
+		case <-msgs:
+			sendMessage(load, cfg, c, s, r)
+		}
+	}
+}
+
+func sendMessage(load context.Context, cfg *loadConfig, c *client.Client, s *stats, r *rand.Rand) {
+	if len(cfg.Users) < 2 {
+		return
+	}
+	peer := cfg.Users[r.Intn(len(cfg.Users))].UserID
+	for peer == c.UserID() {
+		peer = cfg.Users[r.Intn(len(cfg.Users))].UserID
+	}
+	ctx, cancel := context.WithTimeout(load, cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	msg, err := c.CreateTextMessage(ctx, "load "+strconv.FormatInt(start.UnixMilli(), 10))
+	if load.Err() != nil {
+		return
+	}
+	s.record("CreateTextMessage", time.Since(start), err)
+	if err != nil {
+		return
+	}
+	start = time.Now()
+	_, err = c.SendMessage(ctx, msg, peer, "", nil, false)
+	if load.Err() == nil {
+		s.record("SendMessage", time.Since(start), err)
+	}
+}
+
+// ticker fires rate times per second after a random first delay, so the users do not fire together,
+// until ctx is done. It never fires when rate is 0.
+func ticker(ctx context.Context, rate float64, r *rand.Rand) <-chan time.Time {
+	out := make(chan time.Time, 1)
+	if rate <= 0 {
+		return out
+	}
+	interval := max(time.Duration(float64(time.Second)/rate), time.Nanosecond) // rates over 1e9 round down to 0
+	delay := time.Duration(r.Int63n(int64(interval)))
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-t.C:
+				select {
+				case out <- now:
+				default: // the user is still busy with the previous call
+				}
+			}
+		}
+	}()
+	return out
+}
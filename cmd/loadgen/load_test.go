@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("GetSelfUserInfo=3, GetFriendList ,")
+	assert.Nil(t, err)
+	assert.Equal(t, []mixEntry{{"GetSelfUserInfo", 3}, {"GetFriendList", 1}}, mix)
+	_, err = parseMix("GetSelfUserInfo=0")
+	assert.NotNil(t, err)
+	_, err = parseMix("GetSelfUserInfo=x")
+	assert.NotNil(t, err)
+
+	counts := make(map[string]int)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 4000; i++ {
+		counts[pick(mix, r)]++
+	}
+	assert.InDelta(t, 3000, counts["GetSelfUserInfo"], 150)
+	assert.InDelta(t, 1000, counts["GetFriendList"], 150)
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50.0, percentile(sorted, 0.5))
+	assert.Equal(t, 99.0, percentile(sorted, 0.99))
+	assert.Equal(t, 100.0, percentile(sorted, 1))
+	assert.Equal(t, 0.0, percentile(nil, 0.5))
+	assert.Equal(t, "1.5 KiB", bytesString(1536))
+}
+
+func TestTickerHugeRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	<-ticker(ctx, 1e10, rand.New(rand.NewSource(1)))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/yrzs/openimwssdk/admin"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/fakeim"
+	"github.com/yrzs/openimwssdk/fakeim/fakegate"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const defaultMix = "GetSelfUserInfo=4,GetAllConversationList=3,GetFriendList=2,GetJoinedGroupList=1"
+
+// The main function opens the simulated users against a gateway, loads it and prints the report.
+func main() {
+	addr := flag.String("addr", "", "ws address of the gateway, empty runs a gateway and a fake OpenIM server in process")
+	users := flag.Int("users", 100, "simulated users")
+	userPrefix := flag.String("user_prefix", "load_", "prefix of the userIDs registered on the fake OpenIM server")
+	tokensFile := flag.String("tokens", "", "file of \"userID token\" lines for a gateway backed by a real OpenIM server")
+	fakeListen := flag.String("fake_listen", "", "serve the fake OpenIM server on this address for a gateway run apart")
+	connectRate := flag.Float64("connect_rate", 10, "users connected per second")
+	reqRate := flag.Float64("req_rate", 1, "requests of the mix per user per second")
+	msgRate := flag.Float64("msg_rate", 0.1, "text messages per user per second")
+	mixFlag := flag.String("mix", defaultMix, "weighted reqFuncNames taking no args, name=weight separated by commas")
+	duration := flag.Duration("duration", 30*time.Second, "load once every user is connected")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of a connect or a call")
+	adminAddr := flag.String("admin_addr", "", "admin api of the gateway, e.g. http://127.0.0.1:10005, for the memory figures")
+	adminToken := flag.String("admin_token", "", "bearer token of the admin api")
+	dbDir := flag.String("db_dir", "", "db dir of the in process gateway, a temporary one when empty")
+	jsonOut := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+	if err := logger.Init(logger.FormatText, log.LevelWarn, os.Stderr); err != nil {
+		exit(err)
+	}
+	mix, err := parseMix(*mixFlag)
+	if err != nil {
+		exit(err)
+	}
+	if *reqRate > 0 && len(mix) == 0 {
+		exit(errors.New("-mix is empty"))
+	}
+	known := make(map[string]bool)
+	for _, name := range core_func.ReqFuncNames() {
+		known[name] = true
+	}
+	for _, e := range mix {
+		if !known[e.name] {
+			exit(fmt.Errorf("%s of -mix is not a reqFuncName", e.name))
+		}
+	}
+	if *connectRate <= 0 {
+		exit(errors.New("-connect_rate must be positive"))
+	}
+
+	cfg := &loadConfig{Addr: *addr, ConnectRate: *connectRate, ReqRate: *reqRate, MsgRate: *msgRate, Mix: mix,
+		Duration: *duration, Timeout: *timeout}
+	switch {
+	case *addr == "":
+		fake := fakeim.NewServer()
+		defer fake.Close()
+		cfg.Users = fakeUsers(fake, *userPrefix, *users)
+		if cfg.Addr, err = startGateway(fake, *dbDir); err != nil {
+			exit(err)
+		}
+		cfg.Runtime = func() (*admin.RuntimeStats, error) { return admin.ReadRuntimeStats(true), nil }
+	case *tokensFile != "":
+		if cfg.Users, err = readTokens(*tokensFile, *users); err != nil {
+			exit(err)
+		}
+	case *fakeListen != "":
+		fake, err := fakeim.Listen(*fakeListen)
+		if err != nil {
+			exit(err)
+		}
+		defer fake.Close()
+		cfg.Users = fakeUsers(fake, *userPrefix, *users)
+		fmt.Fprintf(os.Stderr, "fake OpenIM server on %s, the gateway needs -openIM_api_address %s -openIM_ws_address %s\n",
+			*fakeListen, fake.ApiAddr, fake.WsAddr)
+	default:
+		exit(errors.New("-addr needs -tokens or -fake_listen for the users"))
+	}
+	if *adminAddr != "" {
+		cfg.Runtime = func() (*admin.RuntimeStats, error) { return readRuntime(*adminAddr, *adminToken, *timeout) }
+	}
+
+	rep := run(cfg)
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	} else {
+		rep.print(os.Stdout)
+	}
+	if rep.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
+
+// fakeUsers registers n users on the fake server.
+func fakeUsers(fake *fakeim.Server, prefix string, n int) []user {
+	users := make([]user, n)
+	for i := range users {
+		users[i].UserID = fmt.Sprintf("%s%d", prefix, i)
+		users[i].Token = fake.AddUser(users[i].UserID, users[i].UserID)
+	}
+	return users
+}
+
+// readTokens reads up to n "userID token" lines.
+func readTokens(file string, n int) ([]user, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var users []user
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() && len(users) < n {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: %q is not a \"userID token\" line", file, scanner.Text())
+		}
+		users = append(users, user{UserID: fields[0], Token: fields[1]})
+	}
+	return users, scanner.Err()
+}
+
+// startGateway runs a gateway backed by the fake server in process and returns its ws address.
+func startGateway(fake *fakeim.Server, dbDir string) (string, error) {
+	if dbDir == "" {
+		dir, err := os.MkdirTemp("", "oimws_loadgen")
+		if err != nil {
+			return "", err
+		}
+		dbDir = dir
+	}
+	core_func.Config.LogLevel = 2
+	g, err := fakegate.Start(fake, dbDir)
+	if err != nil {
+		return "", err
+	}
+	return g.Addr, nil
+}
+
+// readRuntime reads the memory of a gateway from its admin api, after a garbage collection.
+func readRuntime(adminAddr, token string, timeout time.Duration) (*admin.RuntimeStats, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(adminAddr, "/")+"/admin/runtime?gc=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		admin.Resp
+		Data *admin.RuntimeStats `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, err
+	}
+	if body.ErrCode != 0 {
+		return nil, fmt.Errorf("admin api errCode %d: %s", body.ErrCode, body.ErrMsg)
+	}
+	return body.Data, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimwssdk/admin"
+)
+
+// latencies keeps the duration of every call of one reqFuncName.
+type latencies struct {
+	durations []time.Duration
+	errors    int
+}
+
+// stats collects the latencies of the calls and the pushed events of all the simulated users.
+type stats struct {
+	mu     sync.Mutex
+	calls  map[string]*latencies
+	events map[string]int
+}
+
+func newStats() *stats {
+	return &stats{calls: make(map[string]*latencies), events: make(map[string]int)}
+}
+
+func (s *stats) record(name string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.calls[name]
+	if !ok {
+		l = &latencies{}
+		s.calls[name] = l
+	}
+	if err != nil {
+		l.errors++
+		return
+	}
+	l.durations = append(l.durations, d)
+}
+
+func (s *stats) event(name string) {
+	s.mu.Lock()
+	s.events[name]++
+	s.mu.Unlock()
+}
+
+// CallReport is the outcome of the calls of one reqFuncName, latencies in milliseconds.
+type CallReport struct {
+	Name   string  `json:"name"`
+	Count  int     `json:"count"`
+	Errors int     `json:"errors"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P99    float64 `json:"p99"`
+	Max    float64 `json:"max"`
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Users      int                 `json:"users"`
+	Connected  int                 `json:"connected"`
+	Failed     int                 `json:"failed"`
+	Duration   float64             `json:"duration"` // seconds of load once the users were connected
+	Calls      []*CallReport       `json:"calls"`
+	Events     map[string]int      `json:"events"`
+	Before     *admin.RuntimeStats `json:"before,omitempty"` // of the gateway, before the users connected
+	After      *admin.RuntimeStats `json:"after,omitempty"`  // once they were connected
+	HeapPerNew int64               `json:"heapPerNewSession"`
+	SysPerNew  int64               `json:"sysPerNewSession"`
+}
+
+// report summarizes the calls, connect first then by name.
+func (s *stats) report() ([]*CallReport, map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := make([]*CallReport, 0, len(s.calls))
+	for name, l := range s.calls {
+		sorted := append([]time.Duration(nil), l.durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		calls = append(calls, &CallReport{Name: name, Count: len(sorted) + l.errors, Errors: l.errors,
+			P50: percentile(sorted, 0.5), P90: percentile(sorted, 0.9), P99: percentile(sorted, 0.99),
+			Max: percentile(sorted, 1)})
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if (calls[i].Name == connectName) != (calls[j].Name == connectName) {
+			return calls[i].Name == connectName
+		}
+		return calls[i].Name < calls[j].Name
+	})
+	events := make(map[string]int, len(s.events))
+	for name, n := range s.events {
+		events[name] = n
+	}
+	return calls, events
+}
+
+// percentile returns the nearest rank percentile of sorted durations in milliseconds.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return float64(sorted[i].Microseconds()) / 1000
+}
+
+func (r *Report) print(w io.Writer) {
+	fmt.Fprintf(w, "connections: %d users, %d connected, %d failed\n", r.Users, r.Connected, r.Failed)
+	fmt.Fprintf(w, "load: %.1fs once connected\n\n", r.Duration)
+	fmt.Fprintf(w, "%-32s %8s %7s %9s %9s %9s %9s\n", "reqFuncName", "count", "errors", "p50 ms", "p90 ms", "p99 ms", "max ms")
+	for _, c := range r.Calls {
+		fmt.Fprintf(w, "%-32s %8d %7d %9.1f %9.1f %9.1f %9.1f\n", c.Name, c.Count, c.Errors, c.P50, c.P90, c.P99, c.Max)
+	}
+	if len(r.Events) > 0 {
+		names := make([]string, 0, len(r.Events))
+		for name := range r.Events {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(w, "\npushed events:\n")
+		for _, name := range names {
+			fmt.Fprintf(w, "  %-30s %8d\n", name, r.Events[name])
+		}
+	}
+	if r.After != nil {
+		fmt.Fprintf(w, "\ngateway: %d sessions, %d goroutines, heap %s, sys %s\n", r.After.Sessions, r.After.Goroutines,
+			bytesString(int64(r.After.HeapInuse)), bytesString(int64(r.After.Sys)))
+		fmt.Fprintf(w, "per new session: heap %s, sys %s\n", bytesString(r.HeapPerNew), bytesString(r.SysPerNew))
+	}
+}
+
+func bytesString(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	f, exp := float64(n), 0
+	for math.Abs(f) >= unit && exp < 4 {
+		f /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", f, "KMGT"[exp-1])
+}
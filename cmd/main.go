@@ -1,19 +1,30 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"github.com/go-kratos/kratos/v2/log"
+	"github.com/yrzs/openimwssdk/admin"
+	"github.com/yrzs/openimwssdk/audit"
+	"github.com/yrzs/openimwssdk/bot"
 	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/datadir"
 	"github.com/yrzs/openimwssdk/gate"
+	"github.com/yrzs/openimwssdk/logger"
 	"github.com/yrzs/openimwssdk/module"
 	"github.com/yrzs/openimwssdk/network/tjson"
+	"github.com/yrzs/openimwssdk/panics"
+	"github.com/yrzs/openimwssdk/record"
+	"github.com/yrzs/openimwssdk/registry"
+	"github.com/yrzs/openimwssdk/tracing"
+	"github.com/yrzs/openimwssdk/webhook"
 )
 
 const (
@@ -61,36 +72,197 @@ func (gt *GateNet) CloseGate() {
 
 // The main function sets up the WebSocket server and handles graceful shutdowns.
 func main() {
-	var sdkWsPort, logLevel *int
-	var openIMWsAddress, openIMApiAddress, openIMDbDir *string
+	var sdkWsPort, logLevel, statusWsPort, adminPort *int
+	var openIMWsAddress, openIMApiAddress, openIMDbDir, statusToken, adminToken *string
 	openIMApiAddress = flag.String("openIM_api_address", "http://127.0.0.1:10002",
 		"openIM api listening address")
 	openIMWsAddress = flag.String("openIM_ws_address", "ws://127.0.0.1:10001",
 		"openIM ws listening address")
 	sdkWsPort = flag.Int("sdk_ws_port", 10003, "openIMSDK ws listening port")
+	sdkTCPPort := flag.Int("sdk_tcp_port", 0, "openIMSDK length prefixed tcp listening port, 0 disables it")
+	sdkTCPLenMsgLen := flag.Int("sdk_tcp_len_msg_len", 4, "bytes of the frame length on the tcp port, 1, 2 or 4")
+	sdkSSEPort := flag.Int("sdk_sse_port", 0, "openIMSDK http POST and server-sent events listening port, 0 disables it")
 	logLevel = flag.Int("openIM_log_level", 5, "control log output level")
+	logFormat := flag.String("log_format", logger.FormatText, "log output format, text or json")
 	openIMDbDir = flag.String("openIMDbDir", "./db", "openIM db dir")
+	dbPerUser := flag.Bool("db_per_user", true, "keep the db of every user in its own subdirectory of openIMDbDir")
+	dbUserQuota := flag.Int64("db_user_quota_mb", 0, "db size of one user before an alert, 0 means no limit")
+	dbTotalQuota := flag.Int64("db_total_quota_mb", 0, "size of openIMDbDir before an alert, 0 means no limit")
+	dbTTL := flag.Duration("db_ttl", 0, "the db of a user inactive longer is removed, 0 keeps it forever")
+	dbCheckInterval := flag.Duration("db_check_interval", datadir.DefaultCheckInterval, "period of the db quota check and eviction")
+	statusWsPort = flag.Int("status_ws_port", 10004, "operator status ws listening port, 0 disables it")
+	statusToken = flag.String("status_token", "", "token operators must present on the status ws")
+	adminPort = flag.Int("admin_port", 10005, "admin http api listening port, 0 disables it")
+	adminToken = flag.String("admin_token", "", "bearer token of the admin http api, empty disables it")
+	auditDir := flag.String("audit_dir", "", "directory of the audit stream, empty disables it")
+	auditArgs := flag.String("audit_args", audit.ArgsNone, "argument capture of the audit stream, none or full")
+	auditMaxArgsLen := flag.Int("audit_max_args_len", 1024, "bytes of arguments kept per audit record, 0 means no limit")
+	auditMaxSize := flag.Int64("audit_max_size_mb", 100, "size of an audit file before it is rotated")
+	auditMaxBackups := flag.Int("audit_max_backups", audit.DefaultMaxBackups, "rotated audit files kept")
+	recordDir := flag.String("record_dir", "", "directory of the session recordings, empty disables recording")
+	recordUsers := flag.String("record_users", "", "userIDs recorded from the start, separated by commas")
+	recordMaxSize := flag.Int64("record_max_size_mb", 100, "size of one session recording before its frames are dropped")
+	traceEndpoint := flag.String("trace_otlp_endpoint", "", "OTLP/HTTP collector host:port the spans are exported to")
+	traceInsecure := flag.Bool("trace_otlp_insecure", true, "export to the collector over plain http")
+	traceFile := flag.String("trace_file", "", "file the spans are written to as JSON, for local debugging")
+	traceSampleRatio := flag.Float64("trace_sample_ratio", 1, "ratio of the requests traced")
+	panicLimit := flag.Int("panic_limit", panics.DefaultConfig.Limit, "panics of a method within panic_window disabling it, 0 never disables")
+	panicClients := flag.Int("panic_clients", panics.DefaultConfig.Clients, "distinct clients among the panics disabling a method")
+	panicWindow := flag.Duration("panic_window", panics.DefaultConfig.Window, "span the panics of a method are counted over")
+	panicCooldown := flag.Duration("panic_cooldown", panics.DefaultConfig.Cooldown, "how long a panicking method stays disabled")
+	mailboxSize := flag.Int("mailbox_size", module.DefaultMailboxSize, "requests a session queues before it refuses them")
+	mailboxOverflowLimit := flag.Int("mailbox_overflow_limit", module.DefaultMailboxOverflowLimit,
+		"requests refused in a row because the mailbox is full before the session is closed")
+	replayBufferSize := flag.Int("replay_buffer_size", module.DefaultReplayBufferSize, "unacknowledged events kept per user for replay on reconnect")
+	replayTTL := flag.Duration("replay_ttl", module.DefaultReplayTTL, "how long the replay buffer of a user outlives their last session")
+	nodeID := flag.String("node_id", "", "name of this node in the session registry, the host name with a random suffix when empty")
+	registryKind := flag.String("registry", "memory", "session registry shared by the nodes, memory or redis")
+	registryAddr := flag.String("registry_redis_addr", "127.0.0.1:6379", "host:port of the redis registry")
+	registryPassword := flag.String("registry_redis_password", "", "password of the redis registry")
+	registryDB := flag.Int("registry_redis_db", 0, "database of the redis registry")
+	registryPrefix := flag.String("registry_redis_prefix", registry.DefaultRedisPrefix, "prefix of the redis registry keys and channels")
+	botAccounts := flag.String("bot_accounts", "", "JSON file of the bot accounts run by the gateway, empty runs none")
+	webhooks := flag.String("webhooks", "", "JSON file of the webhooks the listener events are posted to, empty disables them")
+	webhookDir := flag.String("webhook_dir", "./webhook", "directory of the webhook delivery queue and dead-letter log")
+	webhookWorkers := flag.Int("webhook_workers", webhook.DefaultWorkers, "webhook deliveries attempted at once")
+	webhookMaxAttempts := flag.Int("webhook_max_attempts", webhook.DefaultMaxAttempts, "attempts of a webhook delivery before it is dead-lettered")
+	webhookBackoff := flag.Duration("webhook_backoff", webhook.DefaultBackoff, "delay before the first retry of a webhook delivery, doubled for each one after it")
+	webhookMaxBackoff := flag.Duration("webhook_max_backoff", webhook.DefaultMaxBackoff, "longest delay between two attempts of a webhook delivery")
+	webhookDedupWindow := flag.Duration("webhook_dedup_window", webhook.DefaultDedupWindow, "an event of a user seen by several of their sessions within it is posted once, negative posts every one")
 	flag.Parse()
+	ctx := context.Background()
+	if err := logger.Init(*logFormat, logger.LevelFromSDK(*logLevel), os.Stdout); err != nil {
+		logger.Fatal(ctx, "logger init error", "err", err)
+	}
 	core_func.Config.WsAddr = *openIMWsAddress
 	core_func.Config.ApiAddr = *openIMApiAddress
 	core_func.Config.DataDir = *openIMDbDir
 	core_func.Config.LogLevel = uint32(*logLevel)
 	core_func.Config.IsLogStandardOutput = true
-	fmt.Println("Client starting....")
-	log.Info("Client starting....")
+	datadir.Init(datadir.Config{Root: *openIMDbDir, PerUser: *dbPerUser, UserQuota: *dbUserQuota * 1024 * 1024,
+		TotalQuota: *dbTotalQuota * 1024 * 1024, TTL: *dbTTL, CheckInterval: *dbCheckInterval,
+		InUse:   func(userID string) bool { return module.GJsActors.Online(userID) || bot.Online(userID) },
+		OnAlert: module.GStatusHub.PublishDiskAlert})
+	if err := audit.Init(audit.Config{Dir: *auditDir, MaxSize: *auditMaxSize * 1024 * 1024,
+		MaxBackups: *auditMaxBackups, ArgsMode: *auditArgs, MaxArgsLen: *auditMaxArgsLen}); err != nil {
+		logger.Fatal(ctx, "audit init error", "err", err)
+	}
+	if *webhooks != "" {
+		hooks, err := webhook.LoadHooks(*webhooks)
+		if err != nil {
+			logger.Fatal(ctx, "webhooks error", "err", err)
+		}
+		if err := webhook.Init(webhook.Config{Dir: *webhookDir, Hooks: hooks, Workers: *webhookWorkers,
+			MaxAttempts: *webhookMaxAttempts, Backoff: *webhookBackoff, MaxBackoff: *webhookMaxBackoff,
+			DedupWindow: *webhookDedupWindow}); err != nil {
+			logger.Fatal(ctx, "webhook init error", "err", err)
+		}
+	}
+	if err := record.Init(record.Config{Dir: *recordDir, Users: splitList(*recordUsers),
+		MaxSize: *recordMaxSize * 1024 * 1024}); err != nil {
+		logger.Fatal(ctx, "record init error", "err", err)
+	}
+	if err := tracing.Init(tracing.Config{Endpoint: *traceEndpoint, Insecure: *traceInsecure, File: *traceFile,
+		SampleRatio: *traceSampleRatio}); err != nil {
+		logger.Fatal(ctx, "tracing init error", "err", err)
+	}
+	panics.Init(panics.Config{Limit: *panicLimit, Clients: *panicClients, Window: *panicWindow, Cooldown: *panicCooldown})
+	if *mailboxSize > 0 {
+		module.MailboxSize = *mailboxSize
+	}
+	module.MailboxOverflowLimit = *mailboxOverflowLimit
+	if *replayBufferSize > 0 {
+		module.ReplayBufferSize = *replayBufferSize
+	}
+	module.ReplayTTL = *replayTTL
+	if *nodeID == "" {
+		*nodeID = module.DefaultNodeID()
+	}
+	var reg registry.Registry
+	switch *registryKind {
+	case "memory":
+		reg = registry.NewMemory()
+	case "redis":
+		reg = registry.NewRedis(registry.RedisConfig{Addr: *registryAddr, Password: *registryPassword, DB: *registryDB,
+			Prefix: *registryPrefix})
+	default:
+		logger.Fatal(ctx, "unknown registry", "registry", *registryKind)
+	}
+	if err := module.UseRegistry(reg, *nodeID); err != nil {
+		logger.Fatal(ctx, "registry init error", "err", err)
+	}
+	logger.Info(ctx, "Client starting....", "node", *nodeID)
 	gatenet := Initsever(*sdkWsPort)
+	if *sdkTCPPort > 0 {
+		gatenet.TCPAddr = ":" + fmt.Sprintf("%d", *sdkTCPPort)
+		gatenet.LenMsgLen = *sdkTCPLenMsgLen
+	}
+	if *sdkSSEPort > 0 {
+		gatenet.SSEAddr = ":" + fmt.Sprintf("%d", *sdkSSEPort)
+	}
 	gatenet.SetMsgFun(module.NewAgent, module.CloseAgent, module.DataRecv)
 	go gatenet.Runloop()
 	/////////////////////////////////////
-	//statusGate := Initsever(90)
-	//gatenet.SetMsgFun(module.NewStatusAgent, module.CloseStatusAgent, module.DataRecvStatus)
-	//go gatenet.Runloop()
+	var statusGate *GateNet
+	if *statusWsPort > 0 {
+		module.StatusToken = *statusToken
+		statusGate = Initsever(*statusWsPort)
+		statusGate.SetMsgFun(module.NewStatusAgent, module.CloseStatusAgent, module.DataRecvStatus)
+		go statusGate.Runloop()
+		module.GStatusHub.Start(module.StatusReportInterval)
+	}
+	var adminServer *admin.Server
+	if *adminPort > 0 && *adminToken != "" {
+		adminServer = admin.NewServer(":"+fmt.Sprintf("%d", *adminPort), *adminToken, HTTPTimeout)
+		if err := adminServer.Start(); err != nil {
+			logger.Fatal(ctx, "admin server start error", "err", err)
+		}
+	} else {
+		logger.Info(ctx, "admin api disabled, set admin_port and admin_token to enable it")
+	}
+	if *botAccounts != "" {
+		accounts, err := bot.LoadAccounts(*botAccounts)
+		if err != nil {
+			logger.Fatal(ctx, "bot accounts error", "err", err)
+		}
+		module.ReservedUser = bot.Online
+		bot.StartAll(ctx, accounts)
+	}
 	module.ProgressStartTime = time.Now().Unix()
 	///////////////////////////////////////////
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGQUIT, syscall.SIGTERM)
 	sig := <-c
-	log.Info("wsconn server closing down ", "sig", sig)
+	logger.Info(ctx, "wsconn server closing down", "sig", sig)
+	if adminServer != nil {
+		adminServer.Close()
+	}
+	bot.CloseAll()
 	gatenet.CloseGate()
-	//statusGate.CloseGate()
+	if statusGate != nil {
+		module.GStatusHub.Stop()
+		statusGate.CloseGate()
+	}
+	if err := module.GRegistry().Close(); err != nil {
+		logger.Error(ctx, "registry close error", "err", err)
+	}
+	audit.Close()
+	webhook.Close()
+	datadir.Close()
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(shutdownCtx); err != nil {
+		logger.Error(ctx, "tracing shutdown error", "err", err)
+	}
+}
+
+// splitList splits a comma separated flag, dropping the empty items.
+func splitList(s string) []string {
+	var ret []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
 }
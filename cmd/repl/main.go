@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/yrzs/openimwssdk/client"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+// The main function connects to a gateway as one user and runs the calls typed or read from a script.
+func main() {
+	addr := flag.String("addr", "ws://127.0.0.1:10003", "ws address of the gateway")
+	userID := flag.String("user", "", "userID of the session")
+	token := flag.String("token", "", "token of the user")
+	platformID := flag.Int("platform", client.DefaultPlatformID, "platformID of the session")
+	login := flag.Bool("login", true, "log in once connected")
+	timeout := flag.Duration("timeout", client.DefaultTimeout, "timeout of one call")
+	script := flag.String("script", "", "run this script and exit instead of reading the terminal")
+	historyFile := flag.String("history", filepath.Join(os.TempDir(), "oimws_repl_history"), "history file of the terminal")
+	flag.Parse()
+	if *userID == "" || *token == "" {
+		fmt.Fprintln(os.Stderr, "-user and -token are required")
+		os.Exit(2)
+	}
+	// the client logs reconnects at info, keep the terminal for the responses
+	if err := logger.Init(logger.FormatText, log.LevelWarn, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var rl *readline.Instance
+	r := newRepl(os.Stdout, *timeout)
+	if *script == "" {
+		var err error
+		words := append(core_func.ReqFuncNames(), ".wait", ".events", ".save", ".replay", ".help", ".quit")
+		rl, err = readline.NewEx(&readline.Config{Prompt: *userID + "> ", HistoryFile: *historyFile,
+			AutoComplete: &completer{words: words}, InterruptPrompt: "^C", EOFPrompt: ".quit"})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer rl.Close()
+		r.out = rl.Stdout()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	c, err := client.Dial(ctx, client.Config{Addr: *addr, UserID: *userID, Token: *token, PlatformID: *platformID,
+		Timeout: *timeout, Listener: r})
+	cancel()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connect error:", err)
+		os.Exit(1)
+	}
+	defer c.Close()
+	r.c = c
+	if *login {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err := c.Login(ctx)
+		cancel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "login error:", err)
+			os.Exit(1)
+		}
+		r.printf("logged in as %s\n", *userID)
+	}
+
+	if *script != "" {
+		if err := r.replay(*script); err != nil && !errors.Is(err, errQuit) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	r.printf("type .help for the commands, tab completes the reqFuncNames\n")
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			if strings.TrimSpace(line) == "" {
+				return
+			}
+			continue
+		} else if errors.Is(err, io.EOF) {
+			return
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if err := r.exec(line); errors.Is(err, errQuit) {
+			return
+		} else if err != nil {
+			r.printf("error: %v\n", err)
+		}
+	}
+}
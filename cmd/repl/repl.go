@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimwssdk/client"
+)
+
+const helpText = `<ReqFuncName> [args...]   call a FuncRouter method, args are JSON values separated by spaces,
+                          e.g. GetUsersInfo ["u1","u2"] or SendMessage {...} "u2" "" {} false
+.wait <event> [timeout]   wait for a pushed event received since the previous call, e.g. .wait OnSyncServerFinish 10s
+.events on|off            print the pushed events or not
+.save <file>              write the calls and waits of the session to a script
+.replay <file>            run a script, stopping at the first failed line
+.help                     this text
+.quit                     leave`
+
+var errQuit = errors.New("quit")
+
+// repl runs the command lines against a client and prints the responses and pushed events.
+type repl struct {
+	c       *client.Client
+	timeout time.Duration
+	history []string // the lines .save writes
+
+	mu     sync.Mutex
+	out    io.Writer
+	quiet  bool
+	serial int            // of the last event received
+	seen   map[string]int // event -> serial of its last occurrence
+	mark   int            // serial when the last call was sent
+	notify chan struct{}  // closed and replaced on every event
+}
+
+func newRepl(out io.Writer, timeout time.Duration) *repl {
+	return &repl{out: out, timeout: timeout, seen: make(map[string]int), notify: make(chan struct{})}
+}
+
+func (r *repl) printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.out, format, args...)
+}
+
+// OnEvent prints a pushed event and wakes up .wait.
+func (r *repl) OnEvent(ev *client.Event) {
+	r.mu.Lock()
+	r.serial++
+	r.seen[ev.Event] = r.serial
+	close(r.notify)
+	r.notify = make(chan struct{})
+	quiet := r.quiet
+	r.mu.Unlock()
+	if quiet {
+		return
+	}
+	if ev.ErrCode != 0 || ev.ErrMsg != "" {
+		r.printf("<- %s error %d: %s\n", ev.Event, ev.ErrCode, ev.ErrMsg)
+		return
+	}
+	r.printf("<- %s %s\n", ev.Event, pretty(ev.Data))
+}
+
+func (r *repl) OnConnected() { r.printf("<- connected\n") }
+
+func (r *repl) OnDisconnected(err error) { r.printf("<- disconnected: %v, reconnecting\n", err) }
+
+// exec runs one line, errQuit asks to leave.
+func (r *repl) exec(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	name, rest, _ := strings.Cut(line, " ")
+	if !strings.HasPrefix(name, ".") {
+		err := r.call(name, rest)
+		r.history = append(r.history, line)
+		return err
+	}
+	arg := strings.TrimSpace(rest)
+	switch name {
+	case ".help":
+		r.printf("%s\n", helpText)
+	case ".quit", ".exit":
+		return errQuit
+	case ".events":
+		r.mu.Lock()
+		r.quiet = arg == "off"
+		r.mu.Unlock()
+	case ".save":
+		if arg == "" {
+			return errors.New(".save needs a file")
+		}
+		data := strings.Join(r.history, "\n") + "\n"
+		if err := os.WriteFile(arg, []byte(data), 0644); err != nil {
+			return err
+		}
+		r.printf("%d lines saved to %s\n", len(r.history), arg)
+	case ".replay":
+		if arg == "" {
+			return errors.New(".replay needs a file")
+		}
+		return r.replay(arg)
+	case ".wait":
+		err := r.wait(arg)
+		r.history = append(r.history, line)
+		return err
+	default:
+		return fmt.Errorf("unknown command %s, see .help", name)
+	}
+	return nil
+}
+
+// call sends a request and prints its response.
+func (r *repl) call(reqFuncName, rest string) error {
+	args, err := parseArgs(rest)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.mark = r.serial
+	r.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	start := time.Now()
+	var data json.RawMessage
+	if err := r.c.Call(ctx, reqFuncName, &data, args...); err != nil {
+		return err
+	}
+	r.printf("%s (%s)\n", pretty(string(data)), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// wait blocks until the event was received after the last call was sent.
+func (r *repl) wait(arg string) error {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 || len(fields) > 2 {
+		return errors.New("usage: .wait <event> [timeout]")
+	}
+	timeout := r.timeout
+	if len(fields) == 2 {
+		var err error
+		if timeout, err = time.ParseDuration(fields[1]); err != nil {
+			return err
+		}
+	}
+	deadline := time.After(timeout)
+	for {
+		r.mu.Lock()
+		received := r.seen[fields[0]] > r.mark
+		notify := r.notify
+		r.mu.Unlock()
+		if received {
+			return nil
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return fmt.Errorf("%s not received within %s", fields[0], timeout)
+		}
+	}
+}
+
+// replay runs the lines of a script.
+func (r *repl) replay(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 10*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r.printf("> %s\n", line)
+		if err := r.exec(line); err != nil {
+			if errors.Is(err, errQuit) {
+				return err
+			}
+			return fmt.Errorf("%s:%d: %w", file, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseArgs decodes the JSON values of a command line.
+func parseArgs(s string) ([]any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var args []any
+	for {
+		var v any
+		if err := dec.Decode(&v); err == io.EOF {
+			return args, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("args are not JSON values: %w", err)
+		}
+		if v == nil {
+			return nil, errors.New("null is not a valid arg")
+		}
+		args = append(args, v)
+	}
+}
+
+// pretty indents JSON data, "ok" stands for an empty result.
+func pretty(data string) string {
+	if data == "" || data == `""` {
+		return "ok"
+	}
+	buf := bytes.Buffer{}
+	if json.Indent(&buf, []byte(data), "", "  ") != nil {
+		return data
+	}
+	return buf.String()
+}
+
+// completer completes the first word of a line with the reqFuncNames and commands.
+type completer struct {
+	words []string
+}
+
+func (c *completer) Do(line []rune, pos int) ([][]rune, int) {
+	prefix := string(line[:pos])
+	if strings.ContainsAny(prefix, " \t") {
+		return nil, 0
+	}
+	var candidates [][]rune
+	for _, word := range c.words {
+		if strings.HasPrefix(word, prefix) {
+			candidates = append(candidates, []rune(word[len(prefix):]+" "))
+		}
+	}
+	return candidates, len([]rune(prefix))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/client"
+)
+
+func TestParseArgs(t *testing.T) {
+	args, err := parseArgs(` {"text":"hi"} "u2" ""  12 false ["a"]`)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{map[string]any{"text": "hi"}, "u2", "", json.Number("12"), false, []any{"a"}}, args)
+	data, err := client.EncodeArgs(args...)
+	assert.Nil(t, err)
+	assert.Equal(t, `["{\"text\":\"hi\"}","u2","",12,false,"[\"a\"]"]`, data)
+
+	args, err = parseArgs("")
+	assert.Nil(t, err)
+	assert.Empty(t, args)
+	_, err = parseArgs(`u2`)
+	assert.NotNil(t, err)
+	_, err = parseArgs(`"u2" null`)
+	assert.NotNil(t, err)
+}
+
+func TestCompleter(t *testing.T) {
+	c := &completer{words: []string{"GetFriendList", "GetFriendListPage", "GetSelfUserInfo", ".wait"}}
+	candidates, n := c.Do([]rune("GetFr"), 5)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, [][]rune{[]rune("iendList "), []rune("iendListPage ")}, candidates)
+	candidates, _ = c.Do([]rune("GetFriendList x"), 15)
+	assert.Empty(t, candidates)
+}
+
+func TestWaitAndScript(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := newRepl(out, time.Second)
+	r.OnEvent(&client.Event{Event: "OnSyncServerFinish"})
+	assert.Nil(t, r.exec(".wait OnSyncServerFinish"))
+	assert.Contains(t, out.String(), "<- OnSyncServerFinish ok")
+
+	r.mark = r.serial // a call was sent since
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		r.OnEvent(&client.Event{Event: "OnSyncServerFinish"})
+	}()
+	assert.Nil(t, r.exec(".wait OnSyncServerFinish"))
+	assert.NotNil(t, r.exec(".wait OnRecvNewMessages 10ms"))
+
+	script := filepath.Join(t.TempDir(), "session.txt")
+	assert.Nil(t, r.exec(".save "+script))
+	saved, err := os.ReadFile(script)
+	assert.Nil(t, err)
+	assert.Equal(t, ".wait OnSyncServerFinish\n.wait OnSyncServerFinish\n.wait OnRecvNewMessages 10ms\n", string(saved))
+	err = r.replay(script)
+	assert.ErrorContains(t, err, "session.txt:3: OnRecvNewMessages not received")
+	assert.ErrorIs(t, r.exec(".quit"), errQuit)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// defaultIgnore are the keys whose values change from one run to the next: ids and times the sdk or the
+// server assign.
+const defaultIgnore = "clientMsgID,serverMsgID,createTime,sendTime,seq,latestMsg,latestMsgSendTime"
+
+// maxDiffs per response, the rest is summed up.
+const maxDiffs = 20
+
+// diffData compares the data of two responses, json values key by key, other strings as they are.
+func diffData(recorded, replayed string, ignore map[string]bool) []string {
+	if recorded == replayed {
+		return nil
+	}
+	want, errWant := decodeJSON(recorded)
+	got, errGot := decodeJSON(replayed)
+	if errWant != nil || errGot != nil {
+		return []string{fmt.Sprintf("data: recorded %q, replayed %q", recorded, replayed)}
+	}
+	var diffs []string
+	diffJSON("data", want, got, ignore, &diffs)
+	if len(diffs) > maxDiffs {
+		diffs = append(diffs[:maxDiffs], fmt.Sprintf("... %d more", len(diffs)-maxDiffs))
+	}
+	return diffs
+}
+
+func decodeJSON(s string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("trailing data")
+	}
+	return v, nil
+}
+
+// diffJSON appends a line per path where the two decoded values differ.
+func diffJSON(path string, want, got any, ignore map[string]bool, diffs *[]string) {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool, len(w)+len(g))
+		for k := range w {
+			keys[k] = true
+		}
+		for k := range g {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			if !ignore[k] {
+				sorted = append(sorted, k)
+			}
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			wv, wok := w[k]
+			gv, gok := g[k]
+			switch {
+			case !wok:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: not recorded, replayed %s", path, k, compact(gv)))
+			case !gok:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: recorded %s, not replayed", path, k, compact(wv)))
+			default:
+				diffJSON(path+"."+k, wv, gv, ignore, diffs)
+			}
+		}
+		return
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			break
+		}
+		if len(w) != len(g) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: recorded %d items, replayed %d", path, len(w), len(g)))
+			return
+		}
+		for i := range w {
+			diffJSON(path+"["+strconv.Itoa(i)+"]", w[i], g[i], ignore, diffs)
+		}
+		return
+	default:
+		if want == got {
+			return
+		}
+	}
+	*diffs = append(*diffs, fmt.Sprintf("%s: recorded %s, replayed %s", path, compact(want), compact(got)))
+}
+
+func compact(v any) string {
+	b, _ := json.Marshal(v)
+	if len(b) > 80 {
+		return string(b[:77]) + "..."
+	}
+	return string(b)
+}
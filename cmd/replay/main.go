@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/yrzs/openimwssdk/client"
+	"github.com/yrzs/openimwssdk/fakeim"
+	"github.com/yrzs/openimwssdk/fakeim/fakegate"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/record"
+)
+
+// The main function feeds a session recording back to a gateway and prints the responses that changed.
+func main() {
+	code, err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(code)
+}
+
+// run returns 1 when a response changed, 2 when the replay could not run.
+func run() (int, error) {
+	addr := flag.String("addr", "", "ws address of the gateway, empty runs a gateway and a fake OpenIM server in process")
+	userID := flag.String("user", "", "userID of the replayed session, the recorded one when empty")
+	token := flag.String("token", "", "token replacing the recorded one in Login, recorded as <redacted>; needed against a real OpenIM server")
+	platformID := flag.Int("platform", 0, "platformID of the replayed session, the recorded one when 0")
+	timeout := flag.Duration("timeout", client.DefaultTimeout, "timeout of the connect and of one request")
+	pace := flag.Bool("pace", true, "wait between two requests as long as the recorded session did")
+	maxGap := flag.Duration("max_gap", 5*time.Second, "longest wait between two requests with -pace")
+	codesOnly := flag.Bool("codes_only", false, "compare the errCode and errMsg of the responses but not their data")
+	ignore := flag.String("ignore", defaultIgnore, "data keys left out of the comparison, separated by commas")
+	jsonOut := flag.Bool("json", false, "print the summary as JSON")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		return 2, errors.New("usage: replay [flags] <recording.jsonl>")
+	}
+	if err := logger.Init(logger.FormatText, log.LevelWarn, os.Stderr); err != nil {
+		return 2, err
+	}
+	entries, err := record.ReadFile(flag.Arg(0))
+	if err != nil {
+		return 2, err
+	}
+	open := entries[0]
+	if *userID == "" {
+		*userID = open.UserID
+	}
+	if *platformID == 0 {
+		*platformID, _ = strconv.Atoi(open.PlatformID)
+	}
+
+	if *addr == "" {
+		fake := fakeim.NewServer()
+		defer fake.Close()
+		*token = fake.AddUser(*userID, *userID)
+		dbDir, err := os.MkdirTemp("", "oimws_replay")
+		if err != nil {
+			return 2, err
+		}
+		defer os.RemoveAll(dbDir)
+		g, err := fakegate.Start(fake, dbDir)
+		if err != nil {
+			return 2, err
+		}
+		defer g.Close()
+		*addr = g.Addr
+	}
+	opt := &options{Token: *token, Timeout: *timeout, Pace: *pace, MaxGap: *maxGap, CodesOnly: *codesOnly,
+		Ignore: make(map[string]bool)}
+	if *userID != open.UserID {
+		opt.UserID = *userID
+	}
+	for _, k := range strings.Split(*ignore, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			opt.Ignore[k] = true
+		}
+	}
+
+	ctx := context.Background()
+	dialCtx, cancel := context.WithTimeout(ctx, *timeout)
+	c, err := client.Dial(dialCtx, client.Config{Addr: *addr, UserID: *userID, Token: *token, PlatformID: *platformID,
+		Timeout: *timeout, ReconnectInterval: -1})
+	cancel()
+	if err != nil {
+		return 2, fmt.Errorf("connect error: %w", err)
+	}
+	sum := replay(ctx, c, entries, opt, os.Stdout)
+	_ = c.Close()
+	if *jsonOut {
+		_ = json.NewEncoder(os.Stdout).Encode(sum)
+	} else {
+		fmt.Printf("%d requests: %d matched, %d differ, %d skipped\n", sum.Requests, sum.Matched, sum.Differ, sum.Skipped)
+	}
+	if sum.Differ > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
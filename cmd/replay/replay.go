@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yrzs/openimwssdk/client"
+	"github.com/yrzs/openimwssdk/record"
+)
+
+const loginName = "Login"
+
+// options of a replay.
+type options struct {
+	UserID  string // replaces the userID of the recorded Login, empty keeps it
+	Token   string // replaces the token of the recorded Login, empty keeps it
+	Timeout time.Duration
+	Pace    bool          // wait between two requests as long as the recording did
+	MaxGap  time.Duration // longest of those waits
+	// CodesOnly compares the event, errCode and errMsg of the responses but not their data.
+	CodesOnly bool
+	Ignore    map[string]bool // data keys left out of the comparison
+}
+
+// summary of a replay.
+type summary struct {
+	Requests int `json:"requests"`
+	Matched  int `json:"matched"`
+	Differ   int `json:"differ"`
+	Skipped  int `json:"skipped"` // not sent, or without a recorded response to compare with
+}
+
+// replay sends the recorded requests in their order and writes the responses differing from the recorded ones.
+func replay(ctx context.Context, c *client.Client, entries []*record.Entry, opt *options, w io.Writer) *summary {
+	sum := &summary{}
+	var last int64
+	for i, e := range entries {
+		if e.Dir != record.DirIn {
+			continue
+		}
+		line := i + 1
+		sum.Requests++
+		req := &client.Req{}
+		if err := json.Unmarshal([]byte(e.Msg), req); err != nil {
+			sum.Skipped++
+			fmt.Fprintf(w, "line %d: skipped, not a request: %v\n", line, err)
+			continue
+		}
+		if req.OperationID == "" {
+			sum.Skipped++
+			fmt.Fprintf(w, "line %d %s: skipped, no operationID to match the response\n", line, req.ReqFuncName)
+			continue
+		}
+		if opt.Pace && last != 0 {
+			if gap := time.Duration(e.Time-last) * time.Millisecond; gap > 0 {
+				sleep(ctx, min(gap, opt.MaxGap))
+			}
+		}
+		last = e.Time
+		if req.ReqFuncName == loginName {
+			req.Data = rewriteLogin(req.Data, opt.UserID, opt.Token)
+		}
+		recorded := recordedResp(entries[i+1:], req.OperationID)
+		callCtx, cancel := context.WithTimeout(ctx, opt.Timeout)
+		replayed, err := c.Do(callCtx, req)
+		cancel()
+		var diffs []string
+		switch {
+		case err != nil && recorded == nil: // no response then either, e.g. after a Logout
+		case err != nil:
+			diffs = []string{fmt.Sprintf("no response: %v", err)}
+		case recorded == nil:
+			sum.Skipped++
+			fmt.Fprintf(w, "line %d %s %s: no recorded response, replayed errCode %d\n", line, req.ReqFuncName,
+				req.OperationID, replayed.ErrCode)
+			continue
+		default:
+			diffs = diffResp(recorded, replayed, opt)
+		}
+		if len(diffs) == 0 {
+			sum.Matched++
+			continue
+		}
+		sum.Differ++
+		fmt.Fprintf(w, "line %d %s %s:\n", line, req.ReqFuncName, req.OperationID)
+		for _, d := range diffs {
+			fmt.Fprintf(w, "  %s\n", d)
+		}
+	}
+	return sum
+}
+
+// recordedResp returns the first response to operationID in entries.
+func recordedResp(entries []*record.Entry, operationID string) *client.Event {
+	for _, e := range entries {
+		if e.Dir != record.DirOut {
+			continue
+		}
+		ev := &client.Event{}
+		if json.Unmarshal([]byte(e.Msg), ev) == nil && ev.OperationID == operationID {
+			return ev
+		}
+	}
+	return nil
+}
+
+func diffResp(recorded, replayed *client.Event, opt *options) []string {
+	var diffs []string
+	if recorded.Event != replayed.Event {
+		diffs = append(diffs, fmt.Sprintf("event: recorded %q, replayed %q", recorded.Event, replayed.Event))
+	}
+	if recorded.ErrCode != replayed.ErrCode {
+		diffs = append(diffs, fmt.Sprintf("errCode: recorded %d, replayed %d", recorded.ErrCode, replayed.ErrCode))
+	}
+	if recorded.ErrMsg != replayed.ErrMsg {
+		diffs = append(diffs, fmt.Sprintf("errMsg: recorded %q, replayed %q", recorded.ErrMsg, replayed.ErrMsg))
+	}
+	if !opt.CodesOnly {
+		diffs = append(diffs, diffData(recorded.Data, replayed.Data, opt.Ignore)...)
+	}
+	return diffs
+}
+
+// rewriteLogin replaces the userID and the token of the args of a Login request.
+func rewriteLogin(data string, userID, token string) string {
+	if userID == "" && token == "" {
+		return data
+	}
+	var args []any
+	if err := json.Unmarshal([]byte(data), &args); err != nil || len(args) < 2 {
+		return data
+	}
+	if userID != "" {
+		args[0] = userID
+	}
+	if token != "" {
+		args[1] = token
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return data
+	}
+	return string(b)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
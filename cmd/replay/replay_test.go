@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/client"
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/fakeim"
+	"github.com/yrzs/openimwssdk/fakeim/fakegate"
+	"github.com/yrzs/openimwssdk/module"
+	"github.com/yrzs/openimwssdk/record"
+)
+
+func TestDiffData(t *testing.T) {
+	ignore := map[string]bool{"sendTime": true}
+	assert.Nil(t, diffData(`{"a":1,"sendTime":2}`, `{"sendTime":3,"a":1}`, ignore))
+	assert.Equal(t, []string{"data.a: recorded 1, replayed \"1\"", "data.b[1]: recorded 2, replayed 3",
+		"data.c: recorded true, not replayed"},
+		diffData(`{"a":1,"b":[1,2],"c":true}`, `{"a":"1","b":[1,3]}`, ignore))
+	assert.Equal(t, []string{"data: recorded 1 items, replayed 2"}, diffData(`[1]`, `[1,2]`, ignore))
+	assert.Equal(t, []string{`data: recorded "", replayed "x"`}, diffData(``, `x`, ignore))
+	assert.Equal(t, `["u2","t2"]`, rewriteLogin(`["u1","t1"]`, "u2", "t2"))
+}
+
+// TestRecordAndReplay records a session on an in-process gateway and replays it against the same gateway.
+func TestRecordAndReplay(t *testing.T) {
+	fake := fakeim.NewServer()
+	t.Cleanup(fake.Close)
+	g, err := fakegate.Start(fake, t.TempDir())
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		g.Close()
+		datadir.Init(datadir.Config{})
+	})
+	dir := t.TempDir()
+	assert.Nil(t, record.Init(record.Config{Dir: dir, Users: []string{"u1"}}))
+	t.Cleanup(func() { _ = record.Init(record.Config{}) })
+	token := fake.AddUser("u1", "nick_u1")
+	fake.AddUser("u2", "nick_u2")
+
+	ctx := context.Background()
+	c := dial(t, g.Addr, "u1", token)
+	assert.Nil(t, c.Login(ctx))
+	_, err = c.GetSelfUserInfo(ctx)
+	assert.Nil(t, err)
+	_, err = c.GetUsersInfo(ctx, []string{"u2"})
+	assert.Nil(t, err)
+	assert.NotNil(t, c.Call(ctx, "GetUsersInfo", nil, 5)) // args the router cannot convert
+	assert.NotNil(t, c.Call(ctx, "NoSuchMethod", nil))
+	assert.Nil(t, c.Close())
+	assert.Eventually(t, func() bool { return !module.GJsActors.Online("u1") }, 5*time.Second, 20*time.Millisecond)
+	record.Stop("u1")
+
+	files, err := filepath.Glob(filepath.Join(dir, "u1", "*.jsonl"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	entries, err := record.ReadFile(files[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "u1", entries[0].UserID)
+	for _, e := range entries {
+		assert.NotContains(t, e.Msg, token)
+	}
+
+	opt := &options{Token: token, Timeout: 10 * time.Second, Ignore: map[string]bool{"createTime": true}}
+	c = dial(t, g.Addr, "u1", token)
+	out := &bytes.Buffer{}
+	sum := replay(ctx, c, entries, opt, out)
+	assert.Equal(t, &summary{Requests: 5, Matched: 5}, sum, out.String())
+	assert.Nil(t, c.Close())
+
+	// a changed conversion shows up as a diff
+	for _, e := range entries {
+		if e.Dir == record.DirOut && bytes.Contains([]byte(e.Msg), []byte(`"nickname\":\"nick_u2`)) {
+			e.Msg = string(bytes.Replace([]byte(e.Msg), []byte("nick_u2"), []byte("nick_old"), 1))
+		}
+	}
+	c = dial(t, g.Addr, "u1", token)
+	out.Reset()
+	sum = replay(ctx, c, entries, opt, out)
+	assert.Equal(t, 1, sum.Differ, out.String())
+	assert.Contains(t, out.String(), `data[0].publicInfo.nickname: recorded "nick_old", replayed "nick_u2"`)
+	assert.Nil(t, c.Close())
+	_ = os.RemoveAll(dir)
+}
+
+func dial(t *testing.T, addr, userID, token string) *client.Client {
+	var c *client.Client
+	assert.Eventually(t, func() bool {
+		var err error
+		c, err = client.Dial(context.Background(), client.Config{Addr: addr, UserID: userID, Token: token,
+			ReconnectInterval: -1})
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	return c
+}
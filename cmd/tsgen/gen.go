@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yrzs/openimwssdk/core_func"
+)
+
+const (
+	corePkg      = "github.com/yrzs/openimwssdk/core_func"
+	routerType   = "FuncRouter"
+	listenerFile = "ws_listener.go"
+	eventFunc    = "getSelfFuncName" // the listener callbacks send their own name as event
+)
+
+// wrapperFuncs are the FuncRouter helpers taking the wrapped sdk function as second argument.
+var wrapperFuncs = map[string]bool{"call": true, "messageCall": true, "callLocal": true}
+
+// method is a reqFuncName with the args and the result of the sdk function it wraps.
+type method struct {
+	Name    string
+	Wrapped string // Type.Func of the sdk, empty when it was not found
+	Args    []string
+	Result  string
+}
+
+// generator turns go types into TypeScript, declaring every named struct it meets once.
+type generator struct {
+	names map[*types.TypeName]string // declared types -> TypeScript name
+	taken map[string]bool
+	decls map[string]string
+	queue []*types.TypeName
+}
+
+func newGenerator() *generator {
+	return &generator{names: make(map[*types.TypeName]string), taken: make(map[string]bool),
+		decls: make(map[string]string)}
+}
+
+// loadCore type checks core_func with its syntax, so the calls of the FuncRouter methods can be inspected.
+func loadCore() (*pkgInfo, error) {
+	return loadPkg(corePkg)
+}
+
+// methods finds the sdk function every reqFuncName hands to call or messageCall and converts its signature.
+func (g *generator) methods(pkg *pkgInfo, reqFuncNames []string) ([]*method, []string) {
+	decls := make(map[string]*ast.FuncDecl)
+	for _, file := range pkg.files {
+		for _, d := range file.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv != nil && fd.Body != nil && recvName(fd) == routerType {
+				decls[fd.Name.Name] = fd
+			}
+		}
+	}
+	var ret []*method
+	var warnings []string
+	for _, name := range reqFuncNames {
+		m := &method{Name: name, Result: "unknown"}
+		ret = append(ret, m)
+		fd, ok := decls[name]
+		if !ok {
+			warnings = append(warnings, name+": declaration not found")
+			continue
+		}
+		fn := wrappedFunc(fd)
+		if fn == nil {
+			warnings = append(warnings, name+": no call of the sdk found")
+			continue
+		}
+		sig, ok := pkg.info.TypeOf(fn).(*types.Signature)
+		if !ok {
+			warnings = append(warnings, name+": the wrapped value is not a function")
+			continue
+		}
+		m.Wrapped = wrappedName(pkg.info, fn)
+		m.Args, m.Result = g.signature(sig)
+	}
+	return ret, warnings
+}
+
+func recvName(fd *ast.FuncDecl) string {
+	t := fd.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// wrappedFunc returns the function expression passed to the first f.call or f.messageCall of a method.
+func wrappedFunc(fd *ast.FuncDecl) ast.Expr {
+	var fn ast.Expr
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || fn != nil {
+			return fn == nil
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && wrapperFuncs[sel.Sel.Name] && len(call.Args) >= 2 {
+			fn = call.Args[1]
+			return false
+		}
+		return true
+	})
+	return fn
+}
+
+// wrappedName names a method value as Type.Method.
+func wrappedName(info *types.Info, fn ast.Expr) string {
+	sel, ok := fn.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if s, ok := info.Selections[sel]; ok {
+		recv := s.Recv()
+		if p, ok := recv.(*types.Pointer); ok {
+			recv = p.Elem()
+		}
+		if n, ok := recv.(*types.Named); ok {
+			return n.Obj().Name() + "." + sel.Sel.Name
+		}
+	}
+	return sel.Sel.Name
+}
+
+// signature converts the params after the context into the request args and the results before the error
+// into the response data, as the router encodes them.
+func (g *generator) signature(sig *types.Signature) ([]string, string) {
+	var args []string
+	for i := 1; i < sig.Params().Len(); i++ {
+		p := sig.Params().At(i)
+		name := p.Name()
+		if name == "" || name == "_" {
+			name = "arg" + strconv.Itoa(i)
+		}
+		args = append(args, name+": "+g.argType(p.Type()))
+	}
+	res := sig.Results()
+	n := res.Len()
+	if n > 0 && isError(res.At(n-1).Type()) {
+		n--
+	}
+	switch n {
+	case 0:
+		return args, `""`
+	case 1:
+		return args, g.tsType(res.At(0).Type())
+	}
+	items := make([]string, n)
+	for i := 0; i < n; i++ {
+		items[i] = g.tsType(res.At(i).Type())
+	}
+	return args, "[" + strings.Join(items, ", ") + "]"
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// argType is the type of a request arg: structs, slices, arrays and maps travel as JSON strings, 64 bit integers
+// may be sent as decimal strings to keep them exact beyond 2^53.
+func (g *generator) argType(t types.Type) string {
+	if b, ok := t.Underlying().(*types.Basic); ok {
+		switch b.Kind() {
+		case types.Int, types.Int64, types.Uint, types.Uint64:
+			return "number | string"
+		}
+	}
+	u := t
+	for {
+		p, ok := u.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		u = p.Elem()
+	}
+	switch u.Underlying().(type) {
+	case *types.Struct, *types.Slice, *types.Array, *types.Map:
+		return "JSONString<" + g.tsType(t) + ">"
+	}
+	return g.tsType(t)
+}
+
+// tsType converts a go type the way encoding/json marshals it.
+func (g *generator) tsType(t types.Type) string {
+	if n, ok := t.(*types.Named); ok {
+		obj := n.Obj()
+		if obj.Pkg() != nil {
+			switch obj.Pkg().Path() + "." + obj.Name() {
+			case "time.Time":
+				return "string"
+			case "encoding/json.RawMessage":
+				return "unknown"
+			}
+		}
+		if _, ok := n.Underlying().(*types.Struct); ok {
+			return g.declare(obj)
+		}
+		return g.tsType(n.Underlying())
+	}
+	switch u := t.(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "boolean"
+		case u.Info()&types.IsNumeric != 0:
+			return "number"
+		case u.Info()&types.IsString != 0:
+			return "string"
+		}
+		return "unknown"
+	case *types.Pointer:
+		return g.tsType(u.Elem())
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "string" // base64
+		}
+		return arrayOf(g.tsType(u.Elem()))
+	case *types.Array:
+		return arrayOf(g.tsType(u.Elem()))
+	case *types.Map:
+		return "Record<string, " + g.tsType(u.Elem()) + ">"
+	case *types.Struct:
+		return g.structBody(u, "")
+	case *types.Alias:
+		return g.tsType(types.Unalias(u))
+	}
+	return "unknown"
+}
+
+func arrayOf(elem string) string {
+	if strings.ContainsAny(elem, " |&") {
+		return "(" + elem + ")[]"
+	}
+	return elem + "[]"
+}
+
+// declare returns the TypeScript name of a named struct, queuing its declaration the first time.
+func (g *generator) declare(obj *types.TypeName) string {
+	if name, ok := g.names[obj]; ok {
+		return name
+	}
+	name := obj.Name()
+	if g.taken[name] {
+		name = exported(obj.Pkg().Name()) + name
+		for i := 2; g.taken[name]; i++ {
+			name = exported(obj.Pkg().Name()) + obj.Name() + strconv.Itoa(i)
+		}
+	}
+	g.taken[name] = true
+	g.names[obj] = name
+	g.queue = append(g.queue, obj)
+	return name
+}
+
+func exported(s string) string {
+	s = strings.ReplaceAll(s, "_", "")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// flush writes the queued declarations, those may queue more.
+func (g *generator) flush() {
+	for len(g.queue) > 0 {
+		obj := g.queue[0]
+		g.queue = g.queue[1:]
+		name := g.names[obj]
+		st := obj.Type().Underlying().(*types.Struct)
+		g.decls[name] = fmt.Sprintf("/** %s.%s */\nexport interface %s %s\n", obj.Pkg().Name(), obj.Name(), name,
+			g.structBody(st, ""))
+	}
+}
+
+// field is a json field of a struct, embedded structs are flattened as encoding/json does.
+type field struct {
+	name     string
+	typ      string
+	optional bool
+}
+
+func (g *generator) structBody(st *types.Struct, indent string) string {
+	fields := g.fields(st, make(map[string]bool))
+	if len(fields) == 0 {
+		return "{}"
+	}
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, f := range fields {
+		opt := ""
+		if f.optional {
+			opt = "?"
+		}
+		fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, quoteKey(f.name), opt, f.typ)
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+func (g *generator) fields(st *types.Struct, seen map[string]bool) []field {
+	var ret []field
+	var embedded []*types.Struct
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i)).Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if v.Embedded() && name == "" {
+			t := v.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if s, ok := t.Underlying().(*types.Struct); ok {
+				embedded = append(embedded, s)
+				continue
+			}
+		}
+		if !v.Exported() {
+			continue
+		}
+		if name == "" {
+			name = v.Name()
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		f := field{name: name, typ: g.tsType(v.Type()), optional: strings.Contains(","+opts+",", ",omitempty,")}
+		if strings.Contains(","+opts+",", ",string,") {
+			f.typ = "string"
+		}
+		ret = append(ret, f)
+	}
+	for _, s := range embedded { // the fields of the outer struct win
+		ret = append(ret, g.fields(s, seen)...)
+	}
+	return ret
+}
+
+func quoteKey(k string) string {
+	for i, r := range k {
+		if !(r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return strconv.Quote(k)
+		}
+	}
+	return k
+}
+
+// listenerEvents returns the events of the listener callbacks, the methods of ws_listener.go sending their
+// own name, in the order of the file.
+func listenerEvents(pkg *pkgInfo) []string {
+	var events []string
+	for i, file := range pkg.files {
+		if filepath.Base(pkg.names[i]) != listenerFile {
+			continue
+		}
+		for _, d := range file.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || fd.Body == nil {
+				continue
+			}
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					if id, ok := call.Fun.(*ast.Ident); ok && id.Name == eventFunc {
+						events = append(events, fd.Name.Name)
+						return false
+					}
+				}
+				return true
+			})
+		}
+	}
+	return dedupe(events)
+}
+
+func dedupe(s []string) []string {
+	seen := make(map[string]bool, len(s))
+	ret := s[:0]
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// generate writes the TypeScript definitions of the gateway protocol.
+func generate(pkg *pkgInfo, reqFuncNames []string, gatewayEvents []string) ([]byte, []string) {
+	g := newGenerator()
+	var eventData string
+	if obj, ok := pkg.types.Scope().Lookup("EventData").(*types.TypeName); ok {
+		eventData = g.declare(obj)
+	}
+	methods, warnings := g.methods(pkg, reqFuncNames)
+	events := listenerEvents(pkg)
+	g.flush()
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by cmd/tsgen from core_func.FuncRouter and ws_listener.go. DO NOT EDIT.\n\n")
+	b.WriteString("/** A JSON string holding a T: the gateway takes struct, slice and map args as JSON strings. */\n")
+	b.WriteString("export type JSONString<T> = string & { readonly __json?: T };\n\n")
+	b.WriteString("/** The request frame, data is the JSON array of ReqArgs[K]. */\n")
+	b.WriteString("export interface Req<K extends ReqFuncName = ReqFuncName> {\n")
+	b.WriteString("  reqFuncName: K;\n  operationID: string;\n  data: string;\n}\n\n")
+	fmt.Fprintf(&b, "/** The response and event frame, data of a response is the JSON of RespData[event]. */\n")
+	fmt.Fprintf(&b, "export type Resp = %s;\n\n", eventData)
+
+	b.WriteString("export type ReqFuncName =\n")
+	for _, m := range methods {
+		fmt.Fprintf(&b, "  | %q\n", m.Name)
+	}
+	b.WriteString("  ;\n\n")
+	b.WriteString("/** The args of every reqFuncName, after the operationID. */\n")
+	b.WriteString("export interface ReqArgs {\n")
+	for _, m := range methods {
+		switch {
+		case m.Wrapped != "" && core_func.Deprecated(m.Name):
+			fmt.Fprintf(&b, "  /** %s @deprecated */\n", m.Wrapped)
+		case m.Wrapped != "":
+			fmt.Fprintf(&b, "  /** %s */\n", m.Wrapped)
+		}
+		args := "[" + strings.Join(m.Args, ", ") + "]"
+		if m.Wrapped == "" {
+			args = "unknown[]"
+		}
+		fmt.Fprintf(&b, "  %s: %s;\n", m.Name, args)
+	}
+	b.WriteString("}\n\n")
+	b.WriteString("/** The decoded data of the successful response of every reqFuncName. */\n")
+	b.WriteString("export interface RespData {\n")
+	for _, m := range methods {
+		fmt.Fprintf(&b, "  %s: %s;\n", m.Name, m.Result)
+	}
+	b.WriteString("}\n\n")
+
+	writeUnion(&b, "Events of the sdk listeners, pushed with an empty operationID.", "ListenerEventName", events)
+	writeUnion(&b, "Events the gateway itself pushes.", "GatewayEventName", gatewayEvents)
+	b.WriteString("export type EventName = ListenerEventName | GatewayEventName;\n")
+
+	names := make([]string, 0, len(g.decls))
+	for name := range g.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("\n")
+		b.WriteString(g.decls[name])
+	}
+	return b.Bytes(), warnings
+}
+
+func writeUnion(b *bytes.Buffer, doc, name string, items []string) {
+	fmt.Fprintf(b, "/** %s */\nexport type %s =\n", doc, name)
+	for _, item := range items {
+		fmt.Fprintf(b, "  | %q\n", item)
+	}
+	b.WriteString("  ;\n\n")
+}
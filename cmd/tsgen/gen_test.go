@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/core_func"
+)
+
+// TestGenerated fails when ts/openimws.ts no longer matches the FuncRouter, run go generate ./core_func.
+func TestGenerated(t *testing.T) {
+	pkg, err := loadCore()
+	assert.Nil(t, err)
+	ts, warnings := generate(pkg, core_func.ReqFuncNames(), gatewayEvents)
+	assert.Empty(t, warnings)
+	s := string(ts)
+	assert.Contains(t, s, "  GetAllConversationList: LocalConversation[];\n")
+	assert.Contains(t, s, "  GetUsersInfo: [userIDs: JSONString<string[]>];\n")
+	assert.Contains(t, s, "  Login: \"\";\n")
+	assert.Contains(t, s, "  SendMessage: [s: JSONString<MsgStruct>, recvID: string, groupID: string, "+
+		"p: JSONString<OfflinePushInfo>, isOnlineOnly: boolean];\n")
+	assert.Contains(t, s, "  | \"OnSyncServerFinish\"\n")
+	assert.Contains(t, s, "  | \"OnKickedByAdmin\"\n")
+
+	committed, err := os.ReadFile("../../ts/openimws.ts")
+	assert.Nil(t, err)
+	assert.True(t, string(committed) == s, "ts/openimws.ts is stale, run go generate ./core_func")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// pkgInfo is a package type checked from its syntax, its imports come from the export data of the build.
+type pkgInfo struct {
+	files []*ast.File
+	names []string // file names of files
+	types *types.Package
+	info  *types.Info
+}
+
+type listedPkg struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	Export     string
+}
+
+// loadPkg builds path and its dependencies with go list -export, then type checks path from source.
+func loadPkg(path string) (*pkgInfo, error) {
+	cmd := exec.Command("go", "list", "-export", "-deps", "-json", path)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list %s: %w", path, err)
+	}
+	exports := make(map[string]string)
+	var target *listedPkg
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		p := &listedPkg{}
+		if err := dec.Decode(p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		exports[p.ImportPath] = p.Export
+		if p.ImportPath == path {
+			target = p
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("go list %s: package not listed", path)
+	}
+
+	fset := token.NewFileSet()
+	pkg := &pkgInfo{info: &types.Info{Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs: make(map[*ast.Ident]types.Object), Uses: make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection)}}
+	for _, name := range target.GoFiles {
+		name = filepath.Join(target.Dir, name)
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		pkg.files = append(pkg.files, f)
+		pkg.names = append(pkg.names, name)
+	}
+	imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		f, err := os.Open(export)
+		if err != nil {
+			return nil, err
+		}
+		return struct {
+			*bufio.Reader
+			io.Closer
+		}{bufio.NewReader(f), f}, nil
+	})
+	conf := &types.Config{Importer: imp}
+	pkg.types, err = conf.Check(path, fset, pkg.files, pkg.info)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/module"
+)
+
+// gatewayEvents are pushed by the gateway, not by the sdk listeners.
+var gatewayEvents = []string{module.KickedEventName, module.NoticeEventName, module.ConnectRejectedEventName,
+	module.FlowControlEventName, module.EventsLostEventName}
+
+// The main function writes the TypeScript definitions of the reqFuncNames, their args and results, and the events.
+func main() {
+	out := flag.String("o", "", "output file, stdout when empty")
+	flag.Parse()
+	pkg, err := loadCore()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ts, warnings := generate(pkg, core_func.ReqFuncNames(), gatewayEvents)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	if *out == "" {
+		_, _ = os.Stdout.Write(ts)
+		return
+	}
+	if err := os.WriteFile(*out, ts, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,5 +1,7 @@
 package common
 
+import "context"
+
 // TAppParam defines the configuration parameters related to an application.
 type TAppParam struct {
 	ModuleType    string // The type of the module, used to identify different modules or features.
@@ -22,8 +24,9 @@ type TAgentUserData struct {
 
 // TWSData defines the structure for WebSocket data transmission.
 type TWSData struct {
-	MsgType int    // The type of the message, used to handle different data or requests.
-	Msg     []byte // The actual message data in bytes.
+	MsgType int             // The type of the message, used to handle different data or requests.
+	Msg     []byte          // The actual message data in bytes.
+	Ctx     context.Context // The trace context of a received frame, nil when the frame is not traced.
 }
 
 const (
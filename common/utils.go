@@ -1,15 +1,13 @@
 package common
 
 import (
-	"github.com/go-kratos/kratos/v2/log"
-	"runtime/debug"
+	"crypto/rand"
+	"encoding/hex"
 )
 
-// TryRecoverAndDebugPrint Method used to capture panic and print stack information.
-func TryRecoverAndDebugPrint() {
-	errs := recover()
-	if errs == nil {
-		return
-	}
-	log.Fatalf("[Panic] err:%v, stackInfo: %v", errs, debug.Stack())
+// GenSessionID returns a random id identifying one connection in logs.
+func GenSessionID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
@@ -0,0 +1,332 @@
+package core_func
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+// maxSafeInteger is the largest integer a float64, hence a javascript number, holds exactly.
+const maxSafeInteger = 1<<53 - 1
+
+// argFaultError is why an arg cannot be converted.
+type argFaultError struct {
+	reason string
+	msg    string
+}
+
+func (e *argFaultError) Error() string {
+	return e.msg
+}
+
+func argFault(reason, format string, a ...any) error {
+	return &argFaultError{reason: reason, msg: fmt.Sprintf(format, a...)}
+}
+
+// convertArgs converts the args of a request into the params of fnt following its context param.
+func convertArgs(fnt reflect.Type, args []any) ([]reflect.Value, error) {
+	ins := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		v, err := convertArg(arg, fnt.In(i+1))
+		if err != nil {
+			typ := fnt.In(i + 1).String()
+			e := errcode.Newf(errcode.BadArg, "args[%d] to %s: %s", i, typ, err).WithArg(i, typ)
+			if fault, ok := err.(*argFaultError); ok {
+				e.WithReason(fault.reason)
+			}
+			return nil, e
+		}
+		ins = append(ins, v)
+	}
+	return ins, nil
+}
+
+// convertArg converts an arg decoded from the request data into a value of type t. Numbers come as json.Number
+// from the gateway and as float64 from other callers: integers are taken exactly from a json.Number or a string,
+// and from a float64 only up to 2^53. Struct, slice, array and map params come as JSON strings.
+func convertArg(arg any, t reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Value{}, argFault(errcode.ReasonNull, "is null")
+	}
+	at := reflect.TypeOf(arg)
+	if at == t {
+		return reflect.ValueOf(arg), nil
+	}
+	if t.Kind() == reflect.Interface {
+		if n, ok := arg.(json.Number); ok {
+			v, err := numberValue(n)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			arg, at = v, reflect.TypeOf(v)
+		}
+		if !at.Implements(t) {
+			return reflect.Value{}, argFault(errcode.ReasonType, "%s does not implement it", at)
+		}
+		return reflect.ValueOf(arg), nil
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(arg, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(i).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := toUint(arg, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(u).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(arg, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+	case reflect.Bool:
+		b, err := toBool(arg)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+	case reflect.String:
+		if s, ok := arg.(string); ok {
+			return reflect.ValueOf(s).Convert(t), nil
+		}
+	}
+	if s, ok := arg.(string); ok {
+		return unmarshalArg(s, t)
+	}
+	return reflect.Value{}, argFault(errcode.ReasonType, "got a %s", jsonKind(arg))
+}
+
+// numberValue is the value of a number for an interface param: an int64 for an integer, as a float64 would round
+// those beyond 2^53, and a float64 otherwise.
+func numberValue(n json.Number) (any, error) {
+	s := string(n)
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return nil, argFault(errcode.ReasonPrecision, "%s overflows int64 and would lose precision, send it as a string", s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, argFault(errcode.ReasonNotNumber, "%q is not a number", s)
+	}
+	if f == math.Trunc(f) && math.Abs(f) > maxSafeInteger {
+		return nil, argFault(errcode.ReasonPrecision, "%s is beyond 2^53 and may have lost precision, write it without fraction or exponent", s)
+	}
+	return f, nil
+}
+
+// unmarshalArg decodes a JSON string into a struct, slice, array or map, or pointers to them.
+func unmarshalArg(s string, t reflect.Type) (reflect.Value, error) {
+	var ptr int
+	elem := t
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+		ptr++
+	}
+	switch elem.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return reflect.Value{}, argFault(errcode.ReasonType, "got a string")
+	}
+	v := reflect.New(elem)
+	if err := json.Unmarshal([]byte(s), v.Interface()); err != nil {
+		return reflect.Value{}, argFault(errcode.ReasonJSON, "invalid JSON: %s", err)
+	}
+	if ptr == 0 {
+		return v.Elem(), nil
+	}
+	for ; ptr > 1; ptr-- {
+		temp := reflect.New(v.Type())
+		temp.Elem().Set(v)
+		v = temp
+	}
+	return v, nil
+}
+
+func toInt(arg any, bits int) (int64, error) {
+	var i int64
+	switch a := arg.(type) {
+	case float64:
+		if a != math.Trunc(a) {
+			return 0, argFault(errcode.ReasonNotInteger, "%v is not an integer", a)
+		}
+		if math.Abs(a) > maxSafeInteger {
+			return 0, argFault(errcode.ReasonPrecision, "%v is beyond 2^53 and may have lost precision, send it as a string", a)
+		}
+		i = int64(a)
+	case json.Number:
+		n, err := parseInt(string(a))
+		if err != nil {
+			return 0, err
+		}
+		i = n
+	case string:
+		n, err := parseInt(a)
+		if err != nil {
+			return 0, err
+		}
+		i = n
+	default:
+		return 0, argFault(errcode.ReasonType, "got a %s", jsonKind(arg))
+	}
+	if bits < 64 && (i < -1<<(bits-1) || i >= 1<<(bits-1)) {
+		return 0, argFault(errcode.ReasonOverflow, "%d overflows int%d", i, bits)
+	}
+	return i, nil
+}
+
+func toUint(arg any, bits int) (uint64, error) {
+	var u uint64
+	switch a := arg.(type) {
+	case float64:
+		if a != math.Trunc(a) {
+			return 0, argFault(errcode.ReasonNotInteger, "%v is not an integer", a)
+		}
+		if a < 0 {
+			return 0, argFault(errcode.ReasonNegative, "%v is not an unsigned integer", a)
+		}
+		if a > maxSafeInteger {
+			return 0, argFault(errcode.ReasonPrecision, "%v is beyond 2^53 and may have lost precision, send it as a string", a)
+		}
+		u = uint64(a)
+	case json.Number:
+		n, err := parseUint(string(a))
+		if err != nil {
+			return 0, err
+		}
+		u = n
+	case string:
+		n, err := parseUint(a)
+		if err != nil {
+			return 0, err
+		}
+		u = n
+	default:
+		return 0, argFault(errcode.ReasonType, "got a %s", jsonKind(arg))
+	}
+	if bits < 64 && u >= 1<<bits {
+		return 0, argFault(errcode.ReasonOverflow, "%d overflows uint%d", u, bits)
+	}
+	return u, nil
+}
+
+func toFloat(arg any, bits int) (float64, error) {
+	var f float64
+	switch a := arg.(type) {
+	case float64:
+		f = a
+	case json.Number:
+		n, err := strconv.ParseFloat(string(a), 64)
+		if err != nil {
+			return 0, argFault(errcode.ReasonNotNumber, "%q is not a number", a)
+		}
+		f = n
+	case string:
+		n, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return 0, argFault(errcode.ReasonNotNumber, "%q is not a number", a)
+		}
+		f = n
+	default:
+		return 0, argFault(errcode.ReasonType, "got a %s", jsonKind(arg))
+	}
+	if bits == 32 && math.Abs(f) > math.MaxFloat32 {
+		return 0, argFault(errcode.ReasonOverflow, "%v overflows float32", f)
+	}
+	return f, nil
+}
+
+// toBool takes true and false, the numbers 0 and 1 and the strings of both.
+func toBool(arg any) (bool, error) {
+	switch a := arg.(type) {
+	case bool:
+		return a, nil
+	case float64:
+		if a == 0 || a == 1 {
+			return a == 1, nil
+		}
+	case json.Number:
+		if a == "0" || a == "1" {
+			return a == "1", nil
+		}
+	case string:
+		if b, err := strconv.ParseBool(a); err == nil {
+			return b, nil
+		}
+	default:
+		return false, argFault(errcode.ReasonType, "got a %s", jsonKind(arg))
+	}
+	return false, argFault(errcode.ReasonNotBoolean, "%v is not a boolean", arg)
+}
+
+// parseInt parses a decimal integer, also written as a JSON number with a fraction or exponent, such as 1e3.
+func parseInt(s string) (int64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return 0, argFault(errcode.ReasonOverflow, "%s overflows int64", s)
+	}
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil {
+		return 0, argFault(errcode.ReasonNotNumber, "%q is not a number", s)
+	}
+	if f != math.Trunc(f) {
+		return 0, argFault(errcode.ReasonNotInteger, "%q is not an integer", s)
+	}
+	if math.Abs(f) >= 1<<63 {
+		return 0, argFault(errcode.ReasonOverflow, "%s overflows int64", s)
+	}
+	if math.Abs(f) > maxSafeInteger {
+		return 0, argFault(errcode.ReasonPrecision, "%s is beyond 2^53 and may have lost precision, write it without fraction or exponent", s)
+	}
+	return int64(f), nil
+}
+
+// parseUint is parseInt for unsigned integers.
+func parseUint(s string) (uint64, error) {
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err == nil {
+		return u, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange && s[0] != '-' {
+		return 0, argFault(errcode.ReasonOverflow, "%s overflows uint64", s)
+	}
+	i, err := parseInt(s)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, argFault(errcode.ReasonNegative, "%d is not an unsigned integer", i)
+	}
+	return uint64(i), nil
+}
+
+// jsonKind names the JSON kind of a decoded arg in errors.
+func jsonKind(arg any) string {
+	switch arg.(type) {
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", arg)
+}
@@ -0,0 +1,90 @@
+package core_func
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+func TestConvertArg(t *testing.T) {
+	type named int32
+	cases := []struct {
+		arg  any
+		to   any
+		want any
+	}{
+		{json.Number("1715000000123456789"), int64(0), int64(1715000000123456789)},
+		{"1715000000123456789", int64(0), int64(1715000000123456789)},
+		{json.Number("18446744073709551615"), uint64(0), uint64(18446744073709551615)},
+		{float64(42), named(0), named(42)},
+		{json.Number("1e3"), int(0), 1000},
+		{json.Number("1.5"), float32(0), float32(1.5)},
+		{float64(1), false, true},
+		{json.Number("0"), false, false},
+		{"true", false, true},
+		{`["a"]`, []string(nil), []string{"a"}},
+		{`{"a":1}`, (*map[string]int)(nil), &map[string]int{"a": 1}},
+		{json.Number("7"), (*any)(nil), int64(7)},
+		{json.Number("1715000000123456789"), (*any)(nil), int64(1715000000123456789)},
+		{json.Number("1.5"), (*any)(nil), 1.5},
+	}
+	for _, c := range cases {
+		to := reflect.TypeOf(c.to)
+		if to.Kind() == reflect.Ptr && to.Elem().Kind() == reflect.Interface {
+			to = to.Elem()
+		}
+		v, err := convertArg(c.arg, to)
+		if assert.Nil(t, err, "%v to %s", c.arg, to) {
+			assert.Equal(t, c.want, v.Interface(), "%v to %s", c.arg, to)
+		}
+	}
+
+	errs := []struct {
+		arg    any
+		to     any
+		reason string
+	}{
+		{float64(1 << 60), int64(0), "beyond 2^53"},
+		{json.Number("1.5"), int64(0), "not an integer"},
+		{json.Number("300"), int8(0), "overflows int8"},
+		{json.Number("-1"), uint(0), "not an unsigned integer"},
+		{json.Number("99999999999999999999"), int64(0), "overflows int64"},
+		{json.Number("9007199254740993.0"), int64(0), "beyond 2^53"},
+		{json.Number("2"), false, "not a boolean"},
+		{json.Number("1"), "", "got a number"},
+		{"x", 0, `"x" is not a number`},
+		{"{", []string(nil), "invalid JSON"},
+		{json.Number("18446744073709551615"), (*any)(nil), "overflows int64"},
+		{json.Number("1e300"), (*any)(nil), "beyond 2^53"},
+	}
+	for _, c := range errs {
+		to := reflect.TypeOf(c.to)
+		if to.Kind() == reflect.Ptr && to.Elem().Kind() == reflect.Interface {
+			to = to.Elem()
+		}
+		_, err := convertArg(c.arg, to)
+		if assert.NotNil(t, err, "%v to %s", c.arg, to) {
+			assert.Contains(t, err.Error(), c.reason)
+		}
+	}
+}
+
+func TestCallArgError(t *testing.T) {
+	f := NewFuncRouter(make(chan *EventData, 1), "s1")
+	_, err := f.invoke(context.Background(), &Invocation{OperationID: "op1", Kind: CallLocal,
+		Fn: func(_ context.Context, a string, b int32) {}, Args: []any{"a", json.Number("1e20")}})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "args[1] to int32: 1e20 overflows int64")
+		f.respMessage.sendOnErrorResp("op1", "Fn", err)
+		resp := <-f.respMessage.respMessagesChan
+		assert.Equal(t, errcode.BadArg, resp.ErrCode)
+		assert.Contains(t, resp.ErrMsg, "args[1]")
+		arg := 1
+		assert.Equal(t, &errcode.Detail{Category: errcode.CategoryConversion, Reason: errcode.ReasonOverflow, Arg: &arg,
+			Type: "int32"}, resp.ErrDetail)
+	}
+}
@@ -0,0 +1,36 @@
+package core_func
+
+import (
+	"context"
+
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+// EventAck is the replay buffer of a user after an ack: the events the gateway keeps to send again on reconnect.
+type EventAck struct {
+	Acked   int64 `json:"acked"`   // highest seq acknowledged so far
+	LastSeq int64 `json:"lastSeq"` // seq of the last event pushed
+	Pending int   `json:"pending"` // events kept for replay, pushed after acked
+}
+
+// WithEventAck sets the function acknowledging the events of the session, served by AckEvents.
+func WithEventAck(ack func(seq int64) *EventAck) RouterOption {
+	return func(f *FuncRouter) { f.eventAck = ack }
+}
+
+// AckEvents tells the gateway the client has handled the pushed events up to seq, they are no longer replayed when
+// it reconnects. It works before login.
+func (f *FuncRouter) AckEvents(operationID string, args ...any) {
+	f.callLocal(operationID, gatewayFuncs{f}.AckEvents, args...)
+}
+
+// AckEvents drops the acknowledged events from the replay buffer.
+func (g gatewayFuncs) AckEvents(_ context.Context, seq int64) (*EventAck, error) {
+	if g.f.eventAck == nil {
+		return nil, errcode.New(errcode.Backend, "the session has no replay buffer")
+	}
+	if seq < 0 {
+		return nil, errcode.Newf(errcode.BadArg, "seq %d is negative", seq).WithArg(0, "int64").WithReason(errcode.ReasonNegative)
+	}
+	return g.f.eventAck(seq), nil
+}
@@ -0,0 +1,34 @@
+package core_func
+
+import (
+	"context"
+
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+// FlowControl is the state of the mailbox of a session, where its requests wait until the session serves them.
+type FlowControl struct {
+	Size      int   `json:"size"`      // requests the mailbox holds
+	Credit    int   `json:"credit"`    // requests that can be sent before the mailbox is full
+	SlowDown  bool  `json:"slowDown"`  // the client was asked to slow down until an event with slowDown false
+	Overflows int64 `json:"overflows"` // requests refused with 20501 since the session started
+}
+
+// WithFlowControl sets the function reading the mailbox of the session, served by GetFlowControl.
+func WithFlowControl(read func() *FlowControl) RouterOption {
+	return func(f *FuncRouter) { f.flowControl = read }
+}
+
+// GetFlowControl returns the mailbox state of the session, so a client can pace its requests by the credit left.
+// It works before login.
+func (f *FuncRouter) GetFlowControl(operationID string, args ...any) {
+	f.callLocal(operationID, gatewayFuncs{f}.GetFlowControl)
+}
+
+// GetFlowControl reads the mailbox of the session.
+func (g gatewayFuncs) GetFlowControl(_ context.Context) (*FlowControl, error) {
+	if g.f.flowControl == nil {
+		return nil, errcode.New(errcode.Backend, "the session has no mailbox")
+	}
+	return g.f.flowControl(), nil
+}
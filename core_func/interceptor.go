@@ -0,0 +1,70 @@
+package core_func
+
+import (
+	"context"
+	"sync"
+)
+
+// CallKind tells how a FuncRouter method calls its function.
+type CallKind int
+
+const (
+	CallSDK     CallKind = iota // an sdk function, once the sdk is logged in except for Login
+	CallMessage                 // an sdk function sending a message, its progress is pushed as events
+	CallLocal                   // a function of the gateway itself, served before login as well
+)
+
+// Invocation is the call of a function for a request, handed down the interceptor chain.
+type Invocation struct {
+	Ctx         context.Context // of the function once the default interceptors ran, with the operationID and span
+	Kind        CallKind
+	SessionID   string
+	UserID      string // empty before login
+	OperationID string
+	ReqFuncName string // the event of the response, e.g. GetUsersInfo
+	FuncName    string // full go name of the function
+	Fn          any    // the function, taking Ctx first
+	Args        []any  // as decoded from the request data, converted into the params of Fn by the last handler
+}
+
+// Handler calls the function of an invocation and returns the result sent as response data.
+type Handler func(inv *Invocation) (any, error)
+
+// Interceptor runs around the rest of the chain. It may check or change the invocation, refuse it by returning an
+// error without calling next, or change the result. The error of a refusal is sent back as the response with the
+// errCode errcode.From gives it.
+type Interceptor func(inv *Invocation, next Handler) (any, error)
+
+var (
+	interceptorsMu sync.RWMutex
+	interceptors   []Interceptor
+)
+
+// Use adds interceptors run by every FuncRouter for each call, in the order they are added, the first one
+// outermost. They run inside the default ones, which recover panics and check the operationID, the methods disabled
+// after repeated panics and the sdk resources, and before the args are converted. Use is meant to be called when the
+// gateway is set up, before it serves.
+func Use(i ...Interceptor) {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	interceptors = append(interceptors, i...)
+}
+
+// ResetInterceptors drops the interceptors added with Use.
+func ResetInterceptors() {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	interceptors = nil
+}
+
+// chain wraps h in the defaults, then the interceptors added with Use.
+func chain(h Handler, defaults ...Interceptor) Handler {
+	interceptorsMu.RLock()
+	all := append(defaults[:len(defaults):len(defaults)], interceptors...)
+	interceptorsMu.RUnlock()
+	for i := len(all) - 1; i >= 0; i-- {
+		interceptor, next := all[i], h
+		h = func(inv *Invocation) (any, error) { return interceptor(inv, next) }
+	}
+	return h
+}
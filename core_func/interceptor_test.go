@@ -0,0 +1,69 @@
+package core_func
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimsdkcore/pkg/sdkerrs"
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+func TestInterceptors(t *testing.T) {
+	t.Cleanup(ResetInterceptors)
+	var order []string
+	Use(func(inv *Invocation, next Handler) (any, error) {
+		order = append(order, "outer:"+inv.SessionID+":"+inv.OperationID)
+		if inv.Args[0] == "deny" {
+			return nil, sdkerrs.ErrArgs.WithDetail("denied")
+		}
+		return next(inv)
+	}, func(inv *Invocation, next Handler) (any, error) {
+		order = append(order, "inner")
+		inv.Args[0] = strings.ToUpper(inv.Args[0].(string))
+		res, err := next(inv)
+		return res.(string) + "!", err
+	})
+	ch := make(chan *EventData, 1)
+	f := NewFuncRouter(ch, "s1")
+	echo := func(_ context.Context, s string) (string, error) { return s, nil }
+
+	f.dispatch("op1", CallLocal, echo, []any{"hi"})
+	resp := <-ch
+	assert.Equal(t, int32(0), resp.ErrCode)
+	assert.Equal(t, `"HI!"`, resp.Data)
+	assert.Equal(t, []string{"outer:s1:op1", "inner"}, order)
+
+	f.dispatch("op2", CallLocal, echo, []any{"deny"})
+	resp = <-ch
+	assert.NotEqual(t, int32(0), resp.ErrCode)
+	assert.Contains(t, resp.ErrMsg, "denied")
+	assert.Len(t, order, 3)
+}
+
+func TestDefaultInterceptors(t *testing.T) {
+	t.Cleanup(ResetInterceptors)
+	var reached []string
+	Use(func(inv *Invocation, next Handler) (any, error) {
+		reached = append(reached, inv.OperationID)
+		if inv.Args[0] == "panic" {
+			panic("interceptor")
+		}
+		return next(inv)
+	})
+	f := NewFuncRouter(make(chan *EventData, 1), "s1")
+	echo := func(_ context.Context, s string) (string, error) { return s, nil }
+
+	_, err := f.invoke(context.Background(), &Invocation{Kind: CallLocal, ReqFuncName: "Echo", Fn: echo, Args: []any{"hi"}})
+	assert.Equal(t, errcode.MissingOperationID, errcode.From(err).Code)
+	_, err = f.invoke(context.Background(), &Invocation{OperationID: "op1", Kind: CallSDK, ReqFuncName: "Echo",
+		FuncName: "core_func.Echo", Fn: echo, Args: []any{"hi"}})
+	assert.Equal(t, errcode.NotLoggedIn, errcode.From(err).Code)
+	assert.Empty(t, reached)
+
+	_, err = f.invoke(context.Background(), &Invocation{OperationID: "op2", Kind: CallLocal, ReqFuncName: "Echo",
+		Fn: echo, Args: []any{"panic"}})
+	assert.Equal(t, errcode.Panic, errcode.From(err).Code)
+	assert.Equal(t, []string{"op2"}, reached)
+}
@@ -0,0 +1,155 @@
+package core_func
+
+//go:generate go run ../cmd/tsgen -o ../ts/openimws.ts
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Wire kinds of a request arg, how the router takes it from the request data.
+const (
+	WireString  = "string"
+	WireNumber  = "number"
+	WireBoolean = "boolean"
+	WireJSON    = "json" // a JSON string of the param type
+	WireAny     = "any"
+)
+
+// plumbingMethods have the request signature but are called by the gateway itself, not by clients.
+var plumbingMethods = map[string]bool{
+	"UnInitSDK": true,
+}
+
+// deprecatedMethods are still served but should not be used by new clients.
+var deprecatedMethods = map[string]bool{
+	"GetConversationRecvMessageOpt": true,
+}
+
+// ReqFuncNames returns the sorted names of the FuncRouter methods a client can request, those taking the
+// operationID and optional args.
+func ReqFuncNames() []string {
+	var names []string
+	t := reflect.TypeOf((*FuncRouter)(nil))
+	anys := reflect.TypeOf([]any(nil))
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		mt := m.Type // the receiver is In(0)
+		if plumbingMethods[m.Name] || mt.NumOut() != 0 || mt.NumIn() < 2 || mt.In(1).Kind() != reflect.String {
+			continue
+		}
+		if mt.NumIn() == 2 || mt.NumIn() == 3 && mt.IsVariadic() && mt.In(2) == anys {
+			names = append(names, m.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Deprecated reports whether a reqFuncName is deprecated.
+func Deprecated(reqFuncName string) bool {
+	return deprecatedMethods[reqFuncName]
+}
+
+// ParamInfo is a param of the sdk function a method wraps.
+type ParamInfo struct {
+	Type string `json:"type"` // go type, e.g. []string or *sdk_struct.MsgStruct
+	Wire string `json:"wire"` // how the arg is sent, one of the Wire kinds
+}
+
+// MethodInfo describes a reqFuncName by the signature of the sdk function it wraps.
+type MethodInfo struct {
+	Name       string       `json:"name"`
+	Params     []*ParamInfo `json:"params"`  // after the operationID
+	Results    []string     `json:"results"` // go types of the response data, the error left out
+	Deprecated bool         `json:"deprecated,omitempty"`
+}
+
+// MethodsInfo is what a gateway build serves.
+type MethodsInfo struct {
+	Version    string        `json:"version"`
+	SdkVersion string        `json:"sdkVersion"`
+	Methods    []*MethodInfo `json:"methods"`
+}
+
+var (
+	describeOnce sync.Once
+	described    []*MethodInfo
+)
+
+// Methods returns the reqFuncNames with the params and results of the sdk functions they wrap.
+func Methods() *MethodsInfo {
+	describeOnce.Do(func() { described = describeMethods() })
+	return &MethodsInfo{Version: GatewayVersion(), SdkVersion: SdkVersion(), Methods: described}
+}
+
+// describeMethods calls every method on a router that hands the wrapped function to describe instead of
+// calling it.
+func describeMethods() []*MethodInfo {
+	f := NewFuncRouter(make(chan *EventData, 1), "")
+	var wrapped any
+	f.describe = func(fn any) { wrapped = fn }
+	router := reflect.ValueOf(f)
+	operationID := reflect.ValueOf("")
+	var ret []*MethodInfo
+	for _, name := range ReqFuncNames() {
+		wrapped = nil
+		router.MethodByName(name).Call([]reflect.Value{operationID})
+		m := &MethodInfo{Name: name, Params: []*ParamInfo{}, Results: []string{}, Deprecated: deprecatedMethods[name]}
+		if fnt := reflect.TypeOf(wrapped); fnt != nil && fnt.Kind() == reflect.Func {
+			for i := 1; i < fnt.NumIn(); i++ { // In(0) is the context
+				m.Params = append(m.Params, &ParamInfo{Type: fnt.In(i).String(), Wire: wireKind(fnt.In(i))})
+			}
+			for i := 0; i < fnt.NumOut(); i++ {
+				if out := fnt.Out(i); out != errorType {
+					m.Results = append(m.Results, out.String())
+				}
+			}
+		}
+		ret = append(ret, m)
+	}
+	return ret
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// wireKind tells how the router converts an arg into a param of type t.
+func wireKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Interface:
+		return WireAny
+	case reflect.String:
+		return WireString
+	case reflect.Bool:
+		return WireBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return WireNumber
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return WireJSON
+	}
+	return WireAny
+}
+
+// gatewayFuncs are the functions the gateway serves itself through callLocal, named like their reqFuncNames.
+type gatewayFuncs struct {
+	f *FuncRouter // the router of the session, for the functions reading its state
+}
+
+// GetMethods returns the methods the gateway serves.
+func (gatewayFuncs) GetMethods(_ context.Context) (*MethodsInfo, error) {
+	return Methods(), nil
+}
+
+// GetMethods lists the reqFuncNames of the gateway with their params, deprecation and the gateway version, so
+// clients can detect features. It works before login.
+func (f *FuncRouter) GetMethods(operationID string, args ...any) {
+	f.callLocal(operationID, gatewayFuncs{}.GetMethods)
+}
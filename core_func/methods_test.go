@@ -0,0 +1,50 @@
+package core_func
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReqFuncNames(t *testing.T) {
+	names := ReqFuncNames()
+	assert.Contains(t, names, "Login")
+	assert.Contains(t, names, "GetFriendList")
+	assert.Contains(t, names, "SendMessage")
+	// the exported methods the gateway calls itself
+	router := reflect.TypeOf((*FuncRouter)(nil))
+	for _, name := range []string{"InitSDK", "UnInitSDK", "GetLoginUserID"} {
+		_, ok := router.MethodByName(name)
+		assert.True(t, ok, name)
+		assert.NotContains(t, names, name)
+	}
+	assert.Len(t, names, router.NumMethod()-3)
+	assert.IsIncreasing(t, names)
+}
+
+func TestMethods(t *testing.T) {
+	info := Methods()
+	assert.NotEmpty(t, info.Version)
+	methods := make(map[string]*MethodInfo)
+	for _, m := range info.Methods {
+		methods[m.Name] = m
+	}
+	assert.Len(t, methods, len(ReqFuncNames()))
+	assert.Equal(t, []*ParamInfo{{Type: "string", Wire: WireString}, {Type: "string", Wire: WireString}},
+		methods["Login"].Params)
+	assert.Equal(t, []*ParamInfo{{Type: "[]string", Wire: WireJSON}}, methods["GetUsersInfo"].Params)
+	assert.True(t, methods["GetConversationRecvMessageOpt"].Deprecated)
+	assert.False(t, methods["Login"].Deprecated)
+	assert.Equal(t, []string{"*core_func.MethodsInfo"}, methods["GetMethods"].Results)
+}
+
+func TestGetMethods(t *testing.T) {
+	ch := make(chan *EventData, 1)
+	NewFuncRouter(ch, "s1").GetMethods("op1")
+	resp := <-ch
+	assert.Equal(t, "GetMethods", resp.Event)
+	assert.Equal(t, int32(0), resp.ErrCode)
+	assert.Equal(t, "op1", resp.OperationID)
+	assert.Contains(t, resp.Data, `"name":"GetMethods"`)
+}
@@ -0,0 +1,44 @@
+package core_func
+
+import "sync"
+
+// EventObserver is called with a copy of every listener event of every session, before the subscription of the
+// session filters it. userID is empty before login. It runs on the goroutine of the sdk callback and must not block.
+type EventObserver func(userID, sessionId string, ev EventData)
+
+var (
+	observersMu sync.RWMutex
+	observers   []EventObserver
+)
+
+// Observe adds observers of the listener events. Observe is meant to be called when the gateway is set up, before
+// it serves.
+func Observe(o ...EventObserver) {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	observers = append(observers, o...)
+}
+
+// ResetObservers drops the observers added with Observe.
+func ResetObservers() {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	observers = nil
+}
+
+// observe hands a listener event of the session to the observers.
+func (r *RespMessage) observe(ev *EventData) {
+	observersMu.RLock()
+	all := observers
+	observersMu.RUnlock()
+	if len(all) == 0 {
+		return
+	}
+	userID := ""
+	if r.userID != nil {
+		userID = r.userID()
+	}
+	for _, o := range all {
+		o(userID, r.sessionId, *ev)
+	}
+}
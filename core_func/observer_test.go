@@ -0,0 +1,37 @@
+package core_func
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+func TestObserve(t *testing.T) {
+	var seen []string
+	Observe(func(userID, sessionId string, ev EventData) {
+		assert.Equal(t, "s1", sessionId)
+		seen = append(seen, ev.Event+" "+ev.Data)
+	})
+	defer ResetObservers()
+	ch := make(chan *EventData, 10)
+	sub := &Subscription{Categories: []EventCategory{CategoryMessage}}
+	assert.NoError(t, sub.Validate())
+	assert.Error(t, (&Subscription{Categories: []EventCategory{"nope"}}).Validate())
+	f := NewFuncRouter(ch, "s1", WithSubscription(sub))
+
+	// the observers see the events the subscription suppresses
+	NewFriendCallback(f.respMessage).OnFriendAdded(`{"userID":"u3"}`)
+	NewConnCallback(f.respMessage).OnKickedOffline()
+	assert.Equal(t, []string{`OnFriendAdded {"userID":"u3"}`, "OnKickedOffline "}, seen)
+	assert.Len(t, ch, 1)
+}
+
+func TestEventFailedNoErr(t *testing.T) {
+	ch := make(chan *EventData, 1)
+	NewConversationCallback(NewFuncRouter(ch, "s1").respMessage).OnSyncServerFailed()
+	ev := <-ch
+	assert.Equal(t, errcode.Backend, ev.ErrCode)
+	assert.Equal(t, "OnSyncServerFailed reported a failure without error", ev.ErrMsg)
+	assert.Equal(t, &errcode.Detail{Category: errcode.CategoryBackend, Reason: errcode.ReasonNoError}, ev.ErrDetail)
+}
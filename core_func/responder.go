@@ -1,14 +1,19 @@
 package core_func
 
 import (
-	"errors"
-	"github.com/go-kratos/kratos/v2/log"
+	"context"
+	"sync/atomic"
 
-	"github.com/yrzs/openimsdktools/errs"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
 )
 
 type RespMessage struct {
 	respMessagesChan chan *EventData
+	ctx              context.Context
+	filter           atomic.Pointer[eventFilter] // the subscription of the session, nil for every event
+	sessionId        string
+	userID           func() string // the logged in user, for the observers
 }
 
 // NewRespMessage 创建一个新的RespMessage对象
@@ -16,7 +21,7 @@ type RespMessage struct {
 // respMessagesChan: 用于接收事件数据的通道
 // *RespMessage: 指向新创建的RespMessage对象的指针
 func NewRespMessage(respMessagesChan chan *EventData) *RespMessage {
-	return &RespMessage{respMessagesChan: respMessagesChan}
+	return &RespMessage{respMessagesChan: respMessagesChan, ctx: context.Background()}
 }
 
 // sendOnSuccessResp 在操作成功时发送响应消息
@@ -34,22 +39,19 @@ func (r *RespMessage) sendOnSuccessResp(operationID, event string, data string)
 
 // sendOnErrorResp 在操作失败时发送错误响应消息
 // 将错误信息封装在EventData结构体中，并通过respMessagesChan通道发送出去。
-// 如果err实现了errs.CodeError接口，则将错误码和错误消息填充到EventData中。
+// 错误码、错误消息和错误详情由 errcode.From 得出，总是填充。
 //
 //	operationID: 操作ID，用于标识请求的唯一性
 //	event: 事件类型
 //	err: 发生的错误
 func (r *RespMessage) sendOnErrorResp(operationID, event string, err error) {
-	log.Errorf("SendOnErrorResp operationID: %sevent: %serr: %v", operationID, event, err)
+	logger.Error(logger.WithOperationID(r.ctx, operationID), "SendOnErrorResp", "event", event, "err", err)
 	resp := &EventData{
 		Event:       event,
 		OperationID: operationID,
 	}
-	var code errs.CodeError
-	if errors.As(err, &code) {
-		resp.ErrCode = int32(code.Code())
-		resp.ErrMsg = code.Error()
-	}
+	e := errcode.From(err)
+	resp.ErrCode, resp.ErrMsg, resp.ErrDetail = e.Code, e.Msg, e.Detail
 	r.respMessagesChan <- resp
 }
 
@@ -58,9 +60,18 @@ func (r *RespMessage) sendOnErrorResp(operationID, event string, err error) {
 //
 //	event: 事件类型
 func (r *RespMessage) sendEventFailedRespNoErr(event string) {
-	r.respMessagesChan <- &EventData{
-		Event: event,
+	e := errcode.Newf(errcode.Backend, "%s reported a failure without error", event).WithReason(errcode.ReasonNoError)
+	ev := &EventData{
+		Event:     event,
+		ErrCode:   e.Code,
+		ErrMsg:    e.Msg,
+		ErrDetail: e.Detail,
 	}
+	r.observe(ev)
+	if !r.subscribed(event) {
+		return
+	}
+	r.respMessagesChan <- ev
 }
 
 // sendEventSuccessRespWithData 在事件处理成功时发送带有数据的响应消息
@@ -69,6 +80,13 @@ func (r *RespMessage) sendEventFailedRespNoErr(event string) {
 //	event: 事件类型
 //	data: 与事件相关的数据
 func (r *RespMessage) sendEventSuccessRespWithData(event string, data string) {
+	r.observe(&EventData{Event: event, Data: data})
+	if f := r.filter.Load(); f != nil {
+		var ok bool
+		if data, ok = f.filter(event, data); !ok {
+			return
+		}
+	}
 	r.respMessagesChan <- &EventData{
 		Event: event,
 		Data:  data,
@@ -80,6 +98,10 @@ func (r *RespMessage) sendEventSuccessRespWithData(event string, data string) {
 //
 //	event: 事件类型
 func (r *RespMessage) sendEventSuccessRespNoData(event string) {
+	r.observe(&EventData{Event: event})
+	if !r.subscribed(event) {
+		return
+	}
 	r.respMessagesChan <- &EventData{
 		Event: event,
 	}
@@ -92,9 +114,25 @@ func (r *RespMessage) sendEventSuccessRespNoData(event string) {
 //	errCode: 错误码
 //	errMsg: 错误信息
 func (r *RespMessage) sendEventFailedRespNoData(event string, errCode int32, errMsg string) {
-	r.respMessagesChan <- &EventData{
-		Event:   event,
-		ErrCode: errCode,
-		ErrMsg:  errMsg,
+	ev := &EventData{
+		Event:     event,
+		ErrCode:   errCode,
+		ErrMsg:    errMsg,
+		ErrDetail: &errcode.Detail{Category: errcode.CategoryOf(errCode)},
+	}
+	r.observe(ev)
+	if !r.subscribed(event) {
+		return
+	}
+	r.respMessagesChan <- ev
+}
+
+// subscribed reports whether the subscription of the session lets a listener event without data through.
+func (r *RespMessage) subscribed(event string) bool {
+	f := r.filter.Load()
+	if f == nil {
+		return true
 	}
+	_, ok := f.filter(event, "")
+	return ok
 }
@@ -0,0 +1,194 @@
+package core_func
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/yrzs/openimsdkcore/pkg/utils"
+	"github.com/yrzs/openimsdkcore/sdk_struct"
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+// EventCategory groups the listener events a session can subscribe to, one per sdk listener.
+type EventCategory string
+
+const (
+	CategoryConversation EventCategory = "conversation"
+	CategoryMessage      EventCategory = "message" // the advanced and the batch message listeners
+	CategoryFriend       EventCategory = "friend"
+	CategoryGroup        EventCategory = "group"
+	CategoryUser         EventCategory = "user"
+)
+
+// EventCategories are the categories a Subscription selects from.
+var EventCategories = []EventCategory{CategoryConversation, CategoryMessage, CategoryFriend, CategoryGroup, CategoryUser}
+
+// eventCategories maps the listener events to their category. The connection and sync events, and those the
+// gateway pushes itself, have none and are always sent.
+var eventCategories = make(map[string]EventCategory)
+
+func init() {
+	listeners := map[EventCategory][]any{
+		CategoryConversation: {ConversationCallback{}},
+		CategoryMessage:      {AdvancedMsgCallback{}, &BatchMessageCallback{}},
+		CategoryFriend:       {&FriendCallback{}},
+		CategoryGroup:        {&GroupCallback{}},
+		CategoryUser:         {&UserCallback{}},
+	}
+	for category, ls := range listeners {
+		for _, l := range ls {
+			t := reflect.TypeOf(l)
+			for i := 0; i < t.NumMethod(); i++ {
+				eventCategories[t.Method(i).Name] = category
+			}
+		}
+	}
+	// the sync progress is the state of the session, like the connection events
+	for _, event := range []string{"OnSyncServerStart", "OnSyncServerFinish", "OnSyncServerFailed"} {
+		delete(eventCategories, event)
+	}
+}
+
+// EventCategoryOf returns the category of a listener event, false for the events that have none.
+func EventCategoryOf(event string) (EventCategory, bool) {
+	category, ok := eventCategories[event]
+	return category, ok
+}
+
+// Subscription selects the listener events a session receives, the zero value receives all of them.
+type Subscription struct {
+	Categories      []EventCategory `json:"categories,omitempty"`      // empty for every category
+	ConversationIDs []string        `json:"conversationIDs,omitempty"` // message and conversation events of these only, empty for all
+}
+
+// eventFilter is a checked Subscription.
+type eventFilter struct {
+	categories    map[EventCategory]bool // nil for all
+	conversations map[string]bool        // nil for all
+}
+
+func newEventFilter(sub *Subscription) (*eventFilter, *errcode.Error) {
+	e := &eventFilter{}
+	if len(sub.Categories) > 0 {
+		e.categories = make(map[EventCategory]bool)
+		for _, c := range sub.Categories {
+			if !isEventCategory(c) {
+				return nil, errcode.Newf(errcode.BadArg, "unknown event category %q", c)
+			}
+			e.categories[c] = true
+		}
+	}
+	if len(sub.ConversationIDs) > 0 {
+		e.conversations = make(map[string]bool)
+		for _, id := range sub.ConversationIDs {
+			e.conversations[id] = true
+		}
+	}
+	return e, nil
+}
+
+func isEventCategory(c EventCategory) bool {
+	for _, known := range EventCategories {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the data of an event to send, false when the subscription suppresses it. A list in the data is
+// cut down to the elements of the subscribed conversations.
+func (e *eventFilter) filter(event, data string) (string, bool) {
+	category, ok := eventCategories[event]
+	if !ok {
+		return data, true
+	}
+	if e.categories != nil && !e.categories[category] {
+		return "", false
+	}
+	if e.conversations == nil || category != CategoryMessage && category != CategoryConversation || data == "" {
+		return data, true
+	}
+	switch data[0] {
+	case '{':
+		return data, e.wantsConversation(json.RawMessage(data))
+	case '[':
+		var items []json.RawMessage
+		if json.Unmarshal([]byte(data), &items) != nil {
+			return data, true
+		}
+		kept := make([]json.RawMessage, 0, len(items))
+		for _, item := range items {
+			if e.wantsConversation(item) {
+				kept = append(kept, item)
+			}
+		}
+		if len(kept) == len(items) {
+			return data, true
+		}
+		if len(kept) == 0 {
+			return "", false
+		}
+		b, _ := json.Marshal(kept)
+		return string(b), true
+	}
+	return data, true
+}
+
+// conversationRef holds the fields telling the conversation of a conversation or a message.
+type conversationRef struct {
+	ConversationID string `json:"conversationID"`
+	SessionType    int32  `json:"sessionType"`
+	SendID         string `json:"sendID"`
+	RecvID         string `json:"recvID"`
+	GroupID        string `json:"groupID"`
+}
+
+// wantsConversation reports whether an object is of a subscribed conversation, true when it tells none.
+func (e *eventFilter) wantsConversation(raw json.RawMessage) bool {
+	var ref conversationRef
+	if json.Unmarshal(raw, &ref) != nil {
+		return true
+	}
+	id := ref.ConversationID
+	if id == "" && ref.SessionType != 0 {
+		id = utils.GetConversationIDByMsg(&sdk_struct.MsgStruct{SessionType: ref.SessionType, SendID: ref.SendID,
+			RecvID: ref.RecvID, GroupID: ref.GroupID})
+	}
+	return id == "" || e.conversations[id]
+}
+
+// Validate reports why the subscription cannot be used, nil when it can.
+func (sub *Subscription) Validate() error {
+	if _, err := newEventFilter(sub); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WithSubscription sets the listener events the session receives, the gateway passes the subscription of the
+// connect url once Validate accepted it. An invalid one is ignored, every event is sent.
+func WithSubscription(sub *Subscription) RouterOption {
+	return func(f *FuncRouter) {
+		if e, err := newEventFilter(sub); err == nil {
+			f.respMessage.filter.Store(e)
+		}
+	}
+}
+
+// SubscribeEvents replaces the subscription of the session, the listener events of other categories and
+// conversations are no longer sent. It works before login.
+func (f *FuncRouter) SubscribeEvents(operationID string, args ...any) {
+	f.callLocal(operationID, gatewayFuncs{f}.SubscribeEvents, args...)
+}
+
+// SubscribeEvents sets the subscription and returns it.
+func (g gatewayFuncs) SubscribeEvents(_ context.Context, sub *Subscription) (*Subscription, error) {
+	e, err := newEventFilter(sub)
+	if err != nil {
+		return nil, err.WithArg(0, "*core_func.Subscription")
+	}
+	g.f.respMessage.filter.Store(e)
+	return sub, nil
+}
@@ -0,0 +1,41 @@
+package core_func
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+func TestSubscribeEvents(t *testing.T) {
+	ch := make(chan *EventData, 10)
+	f := NewFuncRouter(ch, "s1")
+	_, err := gatewayFuncs{f}.SubscribeEvents(context.Background(), &Subscription{Categories: []EventCategory{"typing"}})
+	assert.Equal(t, errcode.BadArg, errcode.From(err).Code)
+	_, err = gatewayFuncs{f}.SubscribeEvents(context.Background(), &Subscription{
+		Categories: []EventCategory{CategoryMessage, CategoryConversation}, ConversationIDs: []string{"si_u1_u2"}})
+	assert.NoError(t, err)
+	r := f.respMessage
+
+	// other categories are suppressed, the connection and sync events are not
+	NewFriendCallback(r).OnFriendAdded(`{"userID":"u3"}`)
+	NewConnCallback(r).OnConnectSuccess()
+	NewConversationCallback(r).OnSyncServerFinish()
+	// lists are cut down to the subscribed conversations
+	NewBatchMessageCallback(r).OnRecvNewMessages(`[{"sessionType":1,"sendID":"u2","recvID":"u1"},{"sessionType":3,"groupID":"g1"}]`)
+	NewAdvancedMsgCallback(r).OnRecvNewMessage(`{"sessionType":3,"groupID":"g1"}`)
+	NewConversationCallback(r).OnConversationChanged(`[{"conversationID":"sg_g1"}]`)
+	NewConversationCallback(r).OnTotalUnreadMessageCountChanged(3)
+	close(ch)
+
+	var events []string
+	var data []string
+	for ev := range ch {
+		events = append(events, ev.Event)
+		data = append(data, ev.Data)
+	}
+	assert.Equal(t, []string{"OnConnectSuccess", "OnSyncServerFinish", "OnRecvNewMessages",
+		"OnTotalUnreadMessageCountChanged"}, events)
+	assert.JSONEq(t, `[{"sessionType":1,"sendID":"u2","recvID":"u1"}]`, data[2])
+}
@@ -0,0 +1,49 @@
+package core_func
+
+import (
+	"runtime/debug"
+
+	"github.com/yrzs/openimsdkcore/open_im_sdk"
+)
+
+// Version of the gateway, set at build time with
+// -ldflags "-X github.com/yrzs/openimwssdk/core_func.Version=v1.2.0".
+var Version string
+
+// GatewayVersion returns Version, or the module version and vcs revision of the build when it is not set.
+func GatewayVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			if s.Value == "true" {
+				modified = "-dirty"
+			}
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" {
+		v += "+" + revision + modified
+	}
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
+
+// SdkVersion returns the version of the wrapped openim sdk.
+func SdkVersion() string {
+	return open_im_sdk.GetSdkVersion()
+}
@@ -1,16 +1,50 @@
 package core_func
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/openimsdk/protocol/sdkws"
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimsdkcore/sdk_struct"
+	"github.com/yrzs/openimwssdk/fakeim"
 )
 
 func TestGetAllConversationList(T *testing.T) {
-
-	ev := make(chan *EventData, 10)
-	fu := NewFuncRouter(ev, "123456")
+	fake := newFakeIM(T)
+	fake.AddUser("u2", "Bob")
+	groupID := fake.CreateGroup("u2", "team", "u1")
+	_, err := fake.SendMsg(&sdkws.MsgData{SendID: "u2", GroupID: groupID, ClientMsgID: "c1", SessionType: fakeim.SuperGroupChatType,
+		ContentType: 101, Content: []byte(`{"content":"welcome"}`), CreateTime: time.Now().UnixMilli()})
+	assert.Nil(T, err)
+	fu, ev := login(T, fake, "u1")
 	fu.GetAllConversationList("11111")
 
-	msg := <-fu.respMessage.respMessagesChan
+	msg := waitEvent(T, ev, "GetAllConversationList")
 	fmt.Println("msg:", msg)
+	assert.Contains(T, msg.Data, `"conversationID":"sg_`+groupID)
+	assert.Contains(T, msg.Data, `welcome`)
+}
+
+func TestSendMessage(t *testing.T) {
+	fake := newFakeIM(t)
+	alice, aliceEv := login(t, fake, "u1")
+	_, bobEv := login(t, fake, "u2")
+
+	alice.CreateTextMessage(operationID, "hello")
+	created := waitEvent(t, aliceEv, "CreateTextMessage")
+	alice.SendMessage(operationID, created.Data, "u2", "", "{}", false)
+	sent := waitEvent(t, aliceEv, "SendMessage")
+	msg := &sdk_struct.MsgStruct{}
+	assert.Nil(t, json.Unmarshal([]byte(sent.Data), msg))
+	assert.NotEmpty(t, msg.ServerMsgID)
+
+	recv := waitEvent(t, bobEv, "OnRecvNewMessages")
+	assert.Contains(t, recv.Data, `"content":"hello"`)
+	if stored := fake.Messages("si_u1_u2"); assert.Len(t, stored, 1) {
+		assert.Equal(t, int64(1), stored[0].Seq)
+		assert.Equal(t, msg.ClientMsgID, stored[0].ClientMsgID)
+	}
 }
@@ -0,0 +1,20 @@
+package core_func
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFriendList(t *testing.T) {
+	fake := newFakeIM(t)
+	fake.AddUser("u2", "Bob")
+	fake.AddUser("u1", "Alice")
+	fake.AddFriend("u1", "u2")
+	fu, ev := login(t, fake, "u1")
+
+	fu.GetFriendList(operationID)
+	msg := waitEvent(t, ev, "GetFriendList")
+	assert.Contains(t, msg.Data, `"userID":"u2"`)
+	assert.Contains(t, msg.Data, `"nickname":"Bob"`)
+}
@@ -0,0 +1,22 @@
+package core_func
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateGroup(t *testing.T) {
+	fake := newFakeIM(t)
+	fake.AddUser("u2", "Bob")
+	fu, ev := login(t, fake, "u1")
+
+	fu.CreateGroup(operationID, `{"memberUserIDs":["u2"],"groupInfo":{"groupName":"team","groupType":2}}`)
+	msg := waitEvent(t, ev, "CreateGroup")
+	assert.Contains(t, msg.Data, `"groupName":"team"`)
+
+	fu.GetJoinedGroupList(operationID)
+	msg = waitEvent(t, ev, "GetJoinedGroupList")
+	assert.Contains(t, msg.Data, `"groupName":"team"`)
+	assert.Contains(t, msg.Data, `"memberCount":2`)
+}
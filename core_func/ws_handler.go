@@ -1,21 +1,24 @@
 package core_func
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"github.com/yrzs/openimsdkcore/open_im_sdk_callback"
 	"reflect"
 	"runtime"
 	"runtime/debug"
 	"strings"
 	"time"
 
-	"github.com/go-kratos/kratos/v2/log"
 	"github.com/yrzs/openimsdkcore/open_im_sdk"
 	"github.com/yrzs/openimsdkcore/pkg/ccontext"
-	"github.com/yrzs/openimsdkcore/pkg/sdkerrs"
 	"github.com/yrzs/openimsdkcore/pkg/utils"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/panics"
+	"github.com/yrzs/openimwssdk/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -24,67 +27,131 @@ const (
 )
 
 type EventData struct {
-	Event       string `json:"event"`
-	ErrCode     int32  `json:"errCode"`
-	ErrMsg      string `json:"errMsg"`
-	Data        string `json:"data"`
-	OperationID string `json:"operationID"`
+	Event       string          `json:"event"`
+	ErrCode     int32           `json:"errCode"`
+	ErrMsg      string          `json:"errMsg"`
+	ErrDetail   *errcode.Detail `json:"errDetail,omitempty"` // set on failure, see package errcode
+	Data        string          `json:"data"`
+	OperationID string          `json:"operationID"`
+	Seq         int64           `json:"seq,omitempty"` // set on the events pushed without a request, see AckEvents
 }
 
 type FuncRouter struct {
 	userForSDK  *open_im_sdk.LoginMgr
 	respMessage *RespMessage
 	sessionId   string
+	logCtx      context.Context
+	traceLookup func(operationID string) context.Context // span context of a request, see WithTraceContext
+	dataDir     string                                   // overrides Config.DataDir for the sdk of this session
+	describe    func(fn any)                             // when set, call and messageCall hand it the wrapped function instead of calling it
+	flowControl func() *FlowControl
+	eventAck    func(seq int64) *EventAck
 }
 
 // NewFuncRouter 创建并返回一个FuncRouter实例
 //
 //	respMessagesChan: 用于接收事件数据的通道
 //	sessionId: 会话ID
+//	opts: 由网关设置的会话参数，见 RouterOption
 //	*FuncRouter: 指向新创建的FuncRouter实例的指针
-func NewFuncRouter(respMessagesChan chan *EventData, sessionId string) *FuncRouter {
-	return &FuncRouter{respMessage: NewRespMessage(respMessagesChan),
+func NewFuncRouter(respMessagesChan chan *EventData, sessionId string, opts ...RouterOption) *FuncRouter {
+	f := &FuncRouter{respMessage: NewRespMessage(respMessagesChan),
 		userForSDK: new(open_im_sdk.LoginMgr), sessionId: sessionId}
+	f.respMessage.sessionId = sessionId
+	f.respMessage.userID = f.GetLoginUserID
+	f.setLogContext(logger.NewSessionContext(context.Background(), sessionId, ""))
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// RouterOption sets up a FuncRouter when it is created. The gateway sets the state of a session this way rather
+// than with exported methods, which a request could call as it calls any method of the router.
+type RouterOption func(f *FuncRouter)
+
+// WithLogContext sets the context carrying the session log fields of the router.
+func WithLogContext(ctx context.Context) RouterOption {
+	return func(f *FuncRouter) { f.setLogContext(ctx) }
+}
+
+func (f *FuncRouter) setLogContext(ctx context.Context) {
+	f.logCtx = ctx
+	f.respMessage.ctx = ctx
+}
+
+// WithDataDir sets the directory of the local databases of the session, instead of Config.DataDir.
+func WithDataDir(dir string) RouterOption {
+	return func(f *FuncRouter) { f.dataDir = dir }
+}
+
+// WithTraceContext sets the function returning the span context of the request of an operationID, so that the
+// SDK call it starts is traced as a child of the request.
+func WithTraceContext(lookup func(operationID string) context.Context) RouterOption {
+	return func(f *FuncRouter) { f.traceLookup = lookup }
+}
+
+// traceContext returns the span context of the request of the operationID.
+func (f *FuncRouter) traceContext(operationID string) context.Context {
+	if f.traceLookup != nil {
+		if ctx := f.traceLookup(operationID); ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
 }
 
 // call 函数用于异步调用指定的函数，并处理调用结果
 //
 // 使用go关键字启动一个新的goroutine来异步调用指定的函数fn，并处理调用结果。
-// 首先，通过reflect包获取函数指针，并使用runtime包获取函数名。
-// 然后，对函数名进行分割处理，获取实际的函数名称。
-// 如果处理后的函数名为空，则通过respMessage发送错误响应。
-// 否则，调用call_函数执行实际的函数调用，并处理调用结果。
-// 如果调用成功，则将结果转换为JSON字符串，并通过respMessage发送成功响应。
+// 调用经过拦截器链（见 Use），如果调用成功，则将结果转换为JSON字符串，并通过respMessage发送成功响应。
 // 如果调用失败或结果转换失败，则通过respMessage发送错误响应。
 //
-//     operationID: 操作ID，用于标识请求的唯一性
-//     fn: 要调用的函数
-//     args: 传递给函数的参数列表
-
+//	operationID: 操作ID，用于标识请求的唯一性
+//	fn: 要调用的函数
+//	args: 传递给函数的参数列表
 func (f *FuncRouter) call(operationID string, fn any, args ...any) {
+	f.dispatch(operationID, CallSDK, fn, args)
+}
+
+// messageCall is call for the sdk functions sending a message, the sending progress is pushed as events.
+func (f *FuncRouter) messageCall(operationID string, fn any, args ...any) {
+	f.dispatch(operationID, CallMessage, fn, args)
+}
+
+// callLocal calls fn, a function of the gateway itself, and responds with its result. Unlike call it needs no
+// logged in sdk.
+func (f *FuncRouter) callLocal(operationID string, fn any, args ...any) {
+	f.dispatch(operationID, CallLocal, fn, args)
+}
+
+// dispatch invokes fn in a goroutine and sends its result or error as the response of the request.
+func (f *FuncRouter) dispatch(operationID string, kind CallKind, fn any, args []any) {
+	if f.describe != nil {
+		f.describe(fn)
+		return
+	}
+	traceCtx := f.traceContext(operationID)
 	go func() {
-		funcPtr := reflect.ValueOf(fn).Pointer()
-		funcName := runtime.FuncForPC(funcPtr).Name()
+		funcName := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
 		parts := strings.Split(funcName, ".")
-		var trimFuncName string
-		if trimFuncNameList := strings.Split(parts[len(parts)-1], "-"); len(trimFuncNameList) == 0 {
-			f.respMessage.sendOnErrorResp(operationID, "FuncError", errors.New("call function trimFuncNameList is empty"))
-			return
-		} else {
-			trimFuncName = trimFuncNameList[0]
-		}
-		res, err := f.call_(operationID, fn, funcName, args...)
+		trimFuncName := strings.Split(parts[len(parts)-1], "-")[0]
+		traceCtx, span := tracing.Start(traceCtx, "FuncRouter.invoke", trace.WithAttributes(
+			tracing.AttrOperationID.String(operationID), tracing.AttrReqFuncName.String(trimFuncName)))
+		inv := &Invocation{Kind: kind, SessionID: f.sessionId, UserID: f.GetLoginUserID(), OperationID: operationID,
+			ReqFuncName: trimFuncName, FuncName: funcName, Fn: fn, Args: args}
+		res, err := f.invoke(traceCtx, inv)
+		tracing.End(span, err)
 		if err != nil {
 			f.respMessage.sendOnErrorResp(operationID, trimFuncName, err)
 			return
 		}
 		data, err := json.Marshal(res)
 		if err != nil {
-			f.respMessage.sendOnErrorResp(operationID, trimFuncName, err)
+			f.respMessage.sendOnErrorResp(operationID, trimFuncName, errcode.New(errcode.BadResult, err.Error()))
 			return
-		} else {
-			f.respMessage.sendOnSuccessResp(operationID, trimFuncName, string(data))
 		}
+		f.respMessage.sendOnSuccessResp(operationID, trimFuncName, string(data))
 	}()
 }
 
@@ -107,258 +174,108 @@ func CheckResourceLoad(uSDK *open_im_sdk.LoginMgr, funcName string) error {
 	return nil
 }
 
-// call_ is the internal function that actually invokes the SDK functions.
-func (f *FuncRouter) call_(operationID string, fn any, funcName string, args ...any) (res any, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("panic: %+v\n%s", r, debug.Stack())
-			err = fmt.Errorf("call panic: %+v", r)
-		}
-	}()
-	if operationID == "" {
-		return nil, sdkerrs.ErrArgs.Wrap("call function operationID is empty")
-	}
-	if err := CheckResourceLoad(f.userForSDK, funcName); err != nil {
-		return nil, sdkerrs.ErrResourceLoad.Wrap("not load resource")
-	}
+// invoke runs the call of a request through the default interceptors, then the ones added with Use, traceCtx
+// carries the span of the call.
+func (f *FuncRouter) invoke(traceCtx context.Context, inv *Invocation) (any, error) {
+	inv.Ctx = traceCtx
+	return chain(f.callFn, f.defaultInterceptors()...)(inv)
+}
 
-	ctx := ccontext.WithOperationID(f.userForSDK.BaseCtx(), operationID)
+// defaultInterceptors check the request can be served, ahead of the interceptors added with Use.
+func (f *FuncRouter) defaultInterceptors() []Interceptor {
+	return []Interceptor{f.recoverPanic, requireOperationID, rejectDisabled, f.loadContext}
+}
 
-	fnv := reflect.ValueOf(fn)
-	if fnv.Kind() != reflect.Func {
-		return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("call function fn is not function, is %T", fn))
-	}
-	fnt := fnv.Type()
-	nin := fnt.NumIn()
-	if len(args)+1 != nin {
-		return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("go code error: fn in args num is not match"))
-	}
-	t := time.Now()
-	log.Info(ctx, "input req", "function name", funcName, "args", args)
-	ins := make([]reflect.Value, 0, nin)
-	ins = append(ins, reflect.ValueOf(ctx))
-	for i := 0; i < len(args); i++ {
-		inFnField := fnt.In(i + 1)
-		arg := reflect.TypeOf(args[i])
-		if arg.String() == inFnField.String() || inFnField.Kind() == reflect.Interface {
-			ins = append(ins, reflect.ValueOf(args[i]))
-			continue
-		}
-		//convert float64 to int when javascript call with number,because javascript only have double
-		//precision floating-point format
-		if arg.String() == "float64" && isInteger(inFnField) {
-			ins = append(ins, reflect.ValueOf(convert(args[i].(float64), inFnField)))
-			continue
-		}
-		if arg.Kind() == reflect.String { // json
-			var ptr int
-			for inFnField.Kind() == reflect.Ptr {
-				inFnField = inFnField.Elem()
-				ptr++
-			}
-			switch inFnField.Kind() {
-			case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
-				v := reflect.New(inFnField)
-				if err := json.Unmarshal([]byte(args[i].(string)), v.Interface()); err != nil {
-					return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("go call json.Unmarshal error: %s",
-						err))
-				}
-				if ptr == 0 {
-					v = v.Elem()
-				} else if ptr != 1 {
-					for i := ptr - 1; i > 0; i-- {
-						temp := reflect.New(v.Type())
-						temp.Elem().Set(v)
-						v = temp
-					}
-				}
-				ins = append(ins, v)
-				continue
-			}
-		}
-		return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("go code error: fn in args type is not match"))
-	}
-	outs := fnv.Call(ins)
-	if len(outs) == 0 {
-		return "", nil
-	}
-	if fnt.Out(len(outs) - 1).Implements(reflect.ValueOf(new(error)).Elem().Type()) {
-		if errValueOf := outs[len(outs)-1]; !errValueOf.IsNil() {
-			log.Error(ctx, "fn call error", errValueOf.Interface().(error), "function name",
-				funcName, "cost time", time.Since(t))
-			return nil, errValueOf.Interface().(error)
-		}
-		if len(outs) == 1 {
-			return "", nil
-		}
-		outs = outs[:len(outs)-1]
-	}
-	for i := 0; i < len(outs); i++ {
-		out := outs[i]
-		switch out.Kind() {
-		case reflect.Map:
-			if out.IsNil() {
-				outs[i] = reflect.MakeMap(out.Type())
+// recoverPanic turns a panic of the rest of the chain into an errcode.Panic error, counted against the method.
+func (f *FuncRouter) recoverPanic(inv *Invocation, next Handler) (res any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logCtx := logger.WithOperationID(f.logCtx, inv.OperationID)
+			panics.Report(logCtx, "method "+inv.ReqFuncName, r, debug.Stack())
+			client := inv.UserID
+			if client == "" {
+				client = inv.SessionID
 			}
-		case reflect.Slice:
-			if out.IsNil() {
-				outs[i] = reflect.MakeSlice(out.Type(), 0, 0)
+			if panics.MethodPanicked(inv.ReqFuncName, client) {
+				logger.Warn(logCtx, "method disabled after repeated panics", "method", inv.ReqFuncName)
 			}
+			err = errcode.Newf(errcode.Panic, "call panic: %+v", r)
 		}
-	}
-	if len(outs) == 1 {
-		log.Info(ctx, "output resp", "function name", funcName, "resp", outs[0].Interface(),
-			"cost time", time.Since(t))
-		return outs[0].Interface(), nil
-	}
-	val := make([]any, 0, len(outs))
-	for i := range outs {
-		val = append(val, outs[i].Interface())
-	}
-	log.Info(ctx, "output resp", "function name", funcName, "resp", val, "cost time", time.Since(t))
-	return val, nil
+	}()
+	return next(inv)
 }
-func isInteger(arg reflect.Type) bool {
-	switch arg.Kind() {
-	case reflect.Int:
-		fallthrough
-	case reflect.Int8:
-		fallthrough
-	case reflect.Int16:
-		fallthrough
-	case reflect.Int32:
-		fallthrough
-	case reflect.Int64:
-		return true
-	default:
-		return false
 
+// requireOperationID refuses a request without operationID.
+func requireOperationID(inv *Invocation, next Handler) (any, error) {
+	if inv.OperationID == "" {
+		return nil, errcode.New(errcode.MissingOperationID, "call function operationID is empty")
 	}
+	return next(inv)
 }
-func convert(arg float64, p reflect.Type) any {
-	switch p.Kind() {
-	case reflect.Int:
-		return int(arg)
-	case reflect.Int8:
-		return int8(arg)
-	case reflect.Int16:
-		return int16(arg)
-	case reflect.Int32:
-		return int32(arg)
-	case reflect.Int64:
-		return int64(arg)
-	default:
-		return arg
 
+// rejectDisabled refuses the methods disabled after repeated panics.
+func rejectDisabled(inv *Invocation, next Handler) (any, error) {
+	if until, ok := panics.Disabled(inv.ReqFuncName); ok {
+		return nil, errcode.Newf(errcode.MethodDisabled, "%s is disabled after repeated panics until %s", inv.ReqFuncName,
+			until.Format(time.RFC3339))
 	}
+	return next(inv)
 }
-func (f *FuncRouter) messageCall(operationID string, fn any, args ...any) {
-	go func() {
-		funcPtr := reflect.ValueOf(fn).Pointer()
-		funcName := runtime.FuncForPC(funcPtr).Name()
-		parts := strings.Split(funcName, ".")
-		var trimFuncName string
-		if trimFuncNameList := strings.Split(parts[len(parts)-1], "-"); len(trimFuncNameList) == 0 {
-			f.respMessage.sendOnErrorResp(operationID, "FuncError",
-				errors.New("call function trimFuncNameList is empty"))
-			return
-		} else {
-			trimFuncName = trimFuncNameList[0]
-		}
-		sendMessageCallback := NewSendMessageCallback(trimFuncName, f.respMessage)
-		res, err := f.messageCall_(sendMessageCallback, operationID, fn, funcName, args...)
-		if err != nil {
-			f.respMessage.sendOnErrorResp(operationID, trimFuncName, err)
-			return
-		}
-		data, err := json.Marshal(res)
-		if err != nil {
-			f.respMessage.sendOnErrorResp(operationID, trimFuncName, err)
-			return
-		} else {
-			f.respMessage.sendOnSuccessResp(operationID, trimFuncName, string(data))
-		}
-	}()
-}
-func (f *FuncRouter) messageCall_(callback open_im_sdk_callback.SendMsgCallBack, operationID string,
-	fn any, funcName string, args ...any) (res any, err error) {
 
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("panic: %+v\n%s", r, debug.Stack())
-			err = fmt.Errorf("call panic: %+v", r)
+// loadContext checks the sdk resources an sdk function needs and sets the context of the function, keeping the span
+// of the call.
+func (f *FuncRouter) loadContext(inv *Invocation, next Handler) (any, error) {
+	var ctx context.Context
+	switch inv.Kind {
+	case CallLocal:
+		ctx = ccontext.WithOperationID(context.Background(), inv.OperationID)
+	case CallMessage:
+		if err := CheckResourceLoad(f.userForSDK, ""); err != nil {
+			return nil, errcode.New(errcode.NotLoggedIn, "not load resource")
 		}
-	}()
-	if operationID == "" {
-		return nil, sdkerrs.ErrArgs.Wrap("call function operationID is empty")
-	}
-	if err := CheckResourceLoad(f.userForSDK, ""); err != nil {
-		return nil, sdkerrs.ErrResourceLoad.Wrap("not load resource")
+		ctx = ccontext.WithOperationID(f.userForSDK.BaseCtx(), inv.OperationID)
+		ctx = ccontext.WithSendMessageCallback(ctx, NewSendMessageCallback(inv.ReqFuncName, f.respMessage))
+	default:
+		if err := CheckResourceLoad(f.userForSDK, inv.FuncName); err != nil {
+			return nil, errcode.New(errcode.NotLoggedIn, "not load resource")
+		}
+		ctx = ccontext.WithOperationID(f.userForSDK.BaseCtx(), inv.OperationID)
 	}
+	inv.Ctx = trace.ContextWithSpan(ctx, trace.SpanFromContext(inv.Ctx))
+	return next(inv)
+}
 
-	ctx := ccontext.WithOperationID(f.userForSDK.BaseCtx(), operationID)
-	ctx = ccontext.WithSendMessageCallback(ctx, callback)
-
-	fnv := reflect.ValueOf(fn)
+// callFn is the last handler of the chain, it converts the args into the params of the function and calls it.
+func (f *FuncRouter) callFn(inv *Invocation) (any, error) {
+	logCtx := logger.WithOperationID(f.logCtx, inv.OperationID)
+	fnv := reflect.ValueOf(inv.Fn)
 	if fnv.Kind() != reflect.Func {
-		return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("call function fn is not function, is %T", fn))
+		return nil, errcode.Newf(errcode.Backend, "call function fn is not function, is %T", inv.Fn)
 	}
-	log.Info(ctx, "input req", "function name", funcName, "args", args)
 	fnt := fnv.Type()
 	nin := fnt.NumIn()
-	if len(args)+1 != nin {
-		return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("go code error: fn in args num is not match"))
+	if len(inv.Args)+1 != nin {
+		return nil, errcode.Newf(errcode.ArgCount, "%d args for %d params", len(inv.Args), nin-1)
 	}
 	t := time.Now()
+	logger.Debug(logCtx, "input req", "funcName", inv.FuncName, "args", inv.Args)
 	ins := make([]reflect.Value, 0, nin)
-	ins = append(ins, reflect.ValueOf(ctx))
-	for i := 0; i < len(args); i++ {
-		inFnField := fnt.In(i + 1)
-		arg := reflect.TypeOf(args[i])
-		if arg.String() == inFnField.String() || inFnField.Kind() == reflect.Interface {
-			ins = append(ins, reflect.ValueOf(args[i]))
-			continue
-		}
-		if arg.String() == "float64" && isInteger(inFnField) {
-			ins = append(ins, reflect.ValueOf(convert(args[i].(float64), inFnField)))
-			continue
-		}
-		if arg.Kind() == reflect.String { // json
-			var ptr int
-			for inFnField.Kind() == reflect.Ptr {
-				inFnField = inFnField.Elem()
-				ptr++
-			}
-			switch inFnField.Kind() {
-			case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
-				v := reflect.New(inFnField)
-				if err := json.Unmarshal([]byte(args[i].(string)), v.Interface()); err != nil {
-					return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("go call json.Unmarshal error: %s",
-						err))
-				}
-				if ptr == 0 {
-					v = v.Elem()
-				} else if ptr != 1 {
-					for i := ptr - 1; i > 0; i-- {
-						temp := reflect.New(v.Type())
-						temp.Elem().Set(v)
-						v = temp
-					}
-				}
-				ins = append(ins, v)
-				continue
-			}
-		}
-		return nil, sdkerrs.ErrSdkInternal.Wrap(fmt.Sprintf("go code error: fn in args type is not match"))
+	ins = append(ins, reflect.ValueOf(inv.Ctx))
+	converted, err := convertArgs(fnt, inv.Args)
+	if err != nil {
+		return nil, err
 	}
+	ins = append(ins, converted...)
+	_, sdkSpan := tracing.Start(inv.Ctx, "sdk.call", trace.WithAttributes(attribute.String("sdk.func", inv.FuncName)))
 	outs := fnv.Call(ins)
+	sdkSpan.End()
 	if len(outs) == 0 {
 		return "", nil
 	}
-	if fnt.Out(len(outs) - 1).Implements(reflect.ValueOf(new(error)).Elem().Type()) {
+	if fnt.Out(len(outs) - 1).Implements(errorType) {
 		if errValueOf := outs[len(outs)-1]; !errValueOf.IsNil() {
-			log.Error(ctx, "fn call error", errValueOf.Interface().(error), "function name",
-				funcName, "cost time", time.Since(t))
+			logger.Error(logCtx, "fn call error", "err", errValueOf.Interface().(error), "funcName", inv.FuncName,
+				"cost", time.Since(t))
 			return nil, errValueOf.Interface().(error)
 		}
 		if len(outs) == 1 {
@@ -377,19 +294,16 @@ func (f *FuncRouter) messageCall_(callback open_im_sdk_callback.SendMsgCallBack,
 			if out.IsNil() {
 				outs[i] = reflect.MakeSlice(out.Type(), 0, 0)
 			}
-		default:
-			log.Errorf("unhandled default case out.Kind():%v", out.Kind())
 		}
 	}
 	if len(outs) == 1 {
-		log.Info(ctx, "output resp", "function name", funcName, "resp", outs[0].Interface(),
-			"cost time", time.Since(t))
+		logger.Debug(logCtx, "output resp", "funcName", inv.FuncName, "resp", outs[0].Interface(), "cost", time.Since(t))
 		return outs[0].Interface(), nil
 	}
 	val := make([]any, 0, len(outs))
 	for i := range outs {
 		val = append(val, outs[i].Interface())
 	}
-	log.Info(ctx, "output resp", "function name", funcName, "resp", val, "cost time", time.Since(t))
+	logger.Debug(logCtx, "output resp", "funcName", inv.FuncName, "resp", val, "cost", time.Since(t))
 	return val, nil
 }
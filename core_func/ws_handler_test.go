@@ -0,0 +1,33 @@
+package core_func
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/panics"
+)
+
+func TestCallPanic(t *testing.T) {
+	panics.Init(panics.Config{Limit: 2, Window: time.Minute, Cooldown: time.Minute})
+	t.Cleanup(func() { panics.Init(panics.DefaultConfig) })
+	f := NewFuncRouter(make(chan *EventData, 1), "s1")
+	inv := func() *Invocation {
+		return &Invocation{OperationID: "op1", Kind: CallLocal, ReqFuncName: "Boom",
+			Fn: func(_ context.Context) { panic("boom") }}
+	}
+
+	_, err := f.invoke(context.Background(), inv())
+	assert.Equal(t, errcode.Panic, errcode.From(err).Code)
+	_, err = f.invoke(context.Background(), inv())
+	assert.Equal(t, errcode.Panic, errcode.From(err).Code)
+	_, err = f.invoke(context.Background(), inv())
+	assert.Equal(t, errcode.MethodDisabled, errcode.From(err).Code)
+	assert.Equal(t, int64(2), panics.ReadStats().ByWhere["method Boom"])
+
+	assert.True(t, panics.Enable("Boom"))
+	_, err = f.invoke(context.Background(), inv())
+	assert.Equal(t, errcode.Panic, errcode.From(err).Code)
+}
@@ -3,9 +3,9 @@ package core_func
 import (
 	"strconv"
 
-	"github.com/go-kratos/kratos/v2/log"
 	"github.com/yrzs/openimsdkcore/pkg/sdkerrs"
 	"github.com/yrzs/openimsdkcore/sdk_struct"
+	"github.com/yrzs/openimwssdk/logger"
 )
 
 var Config sdk_struct.IMConfig
@@ -17,7 +17,7 @@ const (
 
 // InitSDK initializes the SDK with the given operation ID and platform ID.
 func (f *FuncRouter) InitSDK(operationID, platformID string) {
-	log.Infof("InitSDK:  platformID: %s, operationID: %s", platformID, operationID)
+	logger.Info(logger.WithOperationID(f.logCtx, operationID), "InitSDK", "platformID", platformID)
 	callback := NewConnCallback(f.respMessage)
 	j, err := strconv.ParseInt(platformID, 10, 64)
 	if err != nil {
@@ -41,6 +41,9 @@ func (f *FuncRouter) InitSDK(operationID, platformID string) {
 	//	f.respMessage.sendOnErrorResp(operationID, "InitSDK", err)
 	//	return
 	//}
+	if f.dataDir != "" {
+		config.DataDir = f.dataDir
+	}
 	if f.userForSDK.InitSDK(config, callback) {
 		f.respMessage.sendOnSuccessResp(operationID, "InitSDK", "")
 	} else {
@@ -51,7 +54,7 @@ func (f *FuncRouter) InitSDK(operationID, platformID string) {
 // UnInitSDK unInitializes the SDK.
 func (f *FuncRouter) UnInitSDK(operationID string) {
 	if f.userForSDK == nil {
-		log.Errorf("UserForSDK is nil, operationID: %s", operationID)
+		logger.Error(logger.WithOperationID(f.logCtx, operationID), "UserForSDK is nil")
 		return
 	}
 	f.userForSDK.UnInitSDK()
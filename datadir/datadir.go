@@ -0,0 +1,342 @@
+package datadir
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimsdkcore/pkg/constant"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const DefaultCheckInterval = 10 * time.Minute
+
+var (
+	ErrInvalidUserID = errors.New("invalid userID")
+	ErrInUse         = errors.New("user is online")
+	ErrNotFound      = errors.New("no local state of the user")
+)
+
+// Config of the local sdk databases kept on the gateway disk.
+type Config struct {
+	Root          string
+	PerUser       bool                     // every user gets Root/<userID>, otherwise all users share Root
+	UserQuota     int64                    // bytes of one user directory before an alert, 0 means no limit
+	TotalQuota    int64                    // bytes of Root before an alert, 0 means no limit
+	TTL           time.Duration            // user directories inactive longer are removed, 0 keeps them
+	CheckInterval time.Duration            // period of the quota check and eviction
+	InUse         func(userID string) bool // reports whether the user has a session, its directory is never removed
+	OnAlert       func(alert *Alert)       // called for every quota exceeded during a check
+}
+
+// Usage is the disk usage of one user directory.
+type Usage struct {
+	UserID     string `json:"userID"`
+	Size       int64  `json:"size"`       // bytes
+	LastActive int64  `json:"lastActive"` // unix milliseconds
+}
+
+// Alert reports a quota exceeded, UserID is empty for the total quota.
+type Alert struct {
+	UserID string `json:"userID"`
+	Size   int64  `json:"size"`
+	Quota  int64  `json:"quota"`
+}
+
+// Manager hands out the user directories and cleans them up.
+type Manager struct {
+	cfg       Config
+	mu        sync.Mutex // serializes directory creation with eviction
+	closeChan chan bool
+	wg        sync.WaitGroup
+}
+
+var std = NewManager(Config{})
+
+// Init replaces the package manager and starts its periodic check when a quota or TTL is set.
+func Init(cfg Config) {
+	std.Stop()
+	std = NewManager(cfg)
+	if cfg.UserQuota > 0 || cfg.TotalQuota > 0 || cfg.TTL > 0 {
+		std.Start()
+	}
+}
+
+// Close stops the periodic check of the package manager.
+func Close() {
+	std.Stop()
+}
+
+// UserDir returns the directory of the user's sdk databases, see Manager.UserDir.
+func UserDir(userID string) (string, error) {
+	return std.UserDir(userID)
+}
+
+// Touch marks the user active, see Manager.Touch.
+func Touch(userID string) {
+	std.Touch(userID)
+}
+
+// Purge removes the local state of one user, see Manager.Purge.
+func Purge(userID string) error {
+	return std.Purge(userID)
+}
+
+// Usages returns the disk usage of every user, see Manager.Usages.
+func Usages() ([]*Usage, int64, error) {
+	return std.Usages()
+}
+
+// NewManager creates a manager, Root defaults to the working directory.
+func NewManager(cfg Config) *Manager {
+	if cfg.Root == "" {
+		cfg.Root = "."
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultCheckInterval
+	}
+	return &Manager{cfg: cfg}
+}
+
+// userPath maps a userID to its directory, refusing ids that would escape Root.
+func (m *Manager) userPath(userID string) (string, error) {
+	if userID == "" || userID == "." || userID == ".." || strings.ContainsAny(userID, "/\\\x00") {
+		return "", ErrInvalidUserID
+	}
+	return filepath.Join(m.cfg.Root, userID), nil
+}
+
+// UserDir creates the user's directory if needed, marks it active and returns it. The databases the user has in the
+// shared Root, from before PerUser, are moved into it, so the user does not sync everything again.
+func (m *Manager) UserDir(userID string) (string, error) {
+	if !m.cfg.PerUser {
+		return m.cfg.Root, nil
+	}
+	dir, err := m.userPath(userID)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := m.moveShared(userID, dir); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return dir, os.Chtimes(dir, now, now)
+}
+
+// moveShared moves the database files of the user from the shared Root into dir, unless dir already has them.
+func (m *Manager) moveShared(userID, dir string) error {
+	db := dbName(userID)
+	if _, err := os.Stat(filepath.Join(dir, db)); err == nil {
+		return nil
+	}
+	for _, name := range []string{db, db + "-wal", db + "-shm"} {
+		err := os.Rename(filepath.Join(m.cfg.Root, name), filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbName is the name the sdk gives the database of a user.
+func dbName(userID string) string {
+	return "OpenIM_" + constant.BigVersion + "_" + userID + ".db"
+}
+
+// Touch marks the user's directory active, so that the TTL counts from the last session.
+func (m *Manager) Touch(userID string) {
+	if !m.cfg.PerUser {
+		return
+	}
+	dir, err := m.userPath(userID)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+}
+
+// Purge removes the user's directory, refused while the user is online.
+func (m *Manager) Purge(userID string) error {
+	dir, err := m.userPath(userID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.inUse(userID) {
+		return ErrInUse
+	}
+	if !m.cfg.PerUser {
+		return m.purgeShared(userID)
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return os.RemoveAll(dir)
+}
+
+// purgeShared removes the database files of the user from the shared Root: the database the sdk names
+// OpenIM_<version>_<userID>.db and its -wal and -shm files. The name is matched exactly, a userID may end with
+// the userID of another user.
+func (m *Manager) purgeShared(userID string) error {
+	db := dbName(userID)
+	removed := false
+	for _, name := range []string{db, db + "-wal", db + "-shm"} {
+		err := os.Remove(filepath.Join(m.cfg.Root, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		removed = true
+	}
+	if !removed {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *Manager) inUse(userID string) bool {
+	return m.cfg.InUse != nil && m.cfg.InUse(userID)
+}
+
+// Usages returns the disk usage of every user directory ordered by size, and the size of Root.
+func (m *Manager) Usages() ([]*Usage, int64, error) {
+	entries, err := os.ReadDir(m.cfg.Root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	var ret []*Usage
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if !entry.IsDir() {
+			total += info.Size()
+			continue
+		}
+		size := dirSize(filepath.Join(m.cfg.Root, entry.Name()))
+		total += size
+		if m.cfg.PerUser {
+			ret = append(ret, &Usage{UserID: entry.Name(), Size: size, LastActive: info.ModTime().UnixMilli()})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Size > ret[j].Size })
+	return ret, total, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// Check raises the quota alerts and evicts the directories inactive longer than the TTL.
+func (m *Manager) Check() {
+	ctx := context.Background()
+	usages, total, err := m.Usages()
+	if err != nil {
+		logger.Error(ctx, "datadir usage error", "root", m.cfg.Root, "err", err)
+		return
+	}
+	deadline := time.Now().Add(-m.cfg.TTL).UnixMilli()
+	for _, usage := range usages {
+		if m.cfg.TTL > 0 && usage.LastActive < deadline && m.evict(usage.UserID) {
+			total -= usage.Size
+			continue
+		}
+		if m.cfg.UserQuota > 0 && usage.Size > m.cfg.UserQuota {
+			m.alert(&Alert{UserID: usage.UserID, Size: usage.Size, Quota: m.cfg.UserQuota})
+		}
+	}
+	if m.cfg.TotalQuota > 0 && total > m.cfg.TotalQuota {
+		m.alert(&Alert{Size: total, Quota: m.cfg.TotalQuota})
+	}
+}
+
+// evict removes an inactive user directory, the activity is checked again under the lock
+// so that a session starting meanwhile keeps its databases.
+func (m *Manager) evict(userID string) bool {
+	dir, err := m.userPath(userID)
+	if err != nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info, err := os.Stat(dir)
+	if err != nil || time.Since(info.ModTime()) < m.cfg.TTL || m.inUse(userID) {
+		return false
+	}
+	ctx := logger.NewSessionContext(context.Background(), "", userID)
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Error(ctx, "datadir evict error", "dir", dir, "err", err)
+		return false
+	}
+	logger.Info(ctx, "datadir evicted", "dir", dir, "lastActive", info.ModTime())
+	return true
+}
+
+func (m *Manager) alert(alert *Alert) {
+	logger.Warn(logger.NewSessionContext(context.Background(), "", alert.UserID), "datadir quota exceeded",
+		"size", alert.Size, "quota", alert.Quota)
+	if m.cfg.OnAlert != nil {
+		m.cfg.OnAlert(alert)
+	}
+}
+
+// Start runs Check every CheckInterval until Stop is called.
+func (m *Manager) Start() {
+	m.closeChan = make(chan bool, 1)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.cfg.CheckInterval)
+		defer ticker.Stop()
+		m.Check()
+		for {
+			select {
+			case <-ticker.C:
+				m.Check()
+			case <-m.closeChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic check.
+func (m *Manager) Stop() {
+	if m.closeChan != nil {
+		m.closeChan <- true
+		m.wg.Wait()
+		m.closeChan = nil
+	}
+}
@@ -0,0 +1,80 @@
+package datadir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserDir(t *testing.T) {
+	m := NewManager(Config{Root: t.TempDir(), PerUser: true})
+	dir, err := m.UserDir("u1")
+	assert.NoError(t, err)
+	assert.DirExists(t, dir)
+	for _, userID := range []string{"", "..", "a/b", `a\b`} {
+		_, err = m.UserDir(userID)
+		assert.ErrorIs(t, err, ErrInvalidUserID)
+	}
+
+	shared := NewManager(Config{Root: "db"})
+	dir, _ = shared.UserDir("u1")
+	assert.Equal(t, "db", dir)
+}
+
+func TestUserDirMovesShared(t *testing.T) {
+	m := NewManager(Config{Root: t.TempDir(), PerUser: true})
+	for _, name := range []string{"OpenIM_v3_u1.db", "OpenIM_v3_u1.db-wal", "OpenIM_v3_a_u1.db"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(m.cfg.Root, name), []byte(name), 0644))
+	}
+	dir, err := m.UserDir("u1")
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "OpenIM_v3_u1.db"))
+	assert.FileExists(t, filepath.Join(dir, "OpenIM_v3_u1.db-wal"))
+	assert.NoFileExists(t, filepath.Join(m.cfg.Root, "OpenIM_v3_u1.db"))
+	assert.FileExists(t, filepath.Join(m.cfg.Root, "OpenIM_v3_a_u1.db"))
+}
+
+func TestCheckEvictsAndAlerts(t *testing.T) {
+	var alerts []*Alert
+	online := map[string]bool{"busy": true}
+	m := NewManager(Config{Root: t.TempDir(), PerUser: true, UserQuota: 10, TTL: time.Hour,
+		InUse: func(userID string) bool { return online[userID] }, OnAlert: func(a *Alert) { alerts = append(alerts, a) }})
+	old := time.Now().Add(-2 * time.Hour)
+	for _, userID := range []string{"idle", "busy", "big"} {
+		dir, err := m.UserDir(userID)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "OpenIM_v3_"+userID+".db"), make([]byte, 20), 0644))
+		if userID != "big" {
+			assert.NoError(t, os.Chtimes(dir, old, old))
+		}
+	}
+	m.Check()
+	assert.NoDirExists(t, filepath.Join(m.cfg.Root, "idle"))
+	assert.DirExists(t, filepath.Join(m.cfg.Root, "busy"))
+	if assert.Len(t, alerts, 2) {
+		assert.ElementsMatch(t, []string{"busy", "big"}, []string{alerts[0].UserID, alerts[1].UserID})
+	}
+
+	assert.ErrorIs(t, m.Purge("busy"), ErrInUse)
+	assert.NoError(t, m.Purge("big"))
+	usages, total, err := m.Usages()
+	assert.NoError(t, err)
+	assert.Len(t, usages, 1)
+	assert.Equal(t, int64(20), total)
+}
+
+func TestPurgeShared(t *testing.T) {
+	m := NewManager(Config{Root: t.TempDir()})
+	for _, name := range []string{"OpenIM_v3_b.db", "OpenIM_v3_b.db-wal", "OpenIM_v3_a_b.db", "OpenIM_v3_a_b.db-wal"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(m.cfg.Root, name), nil, 0644))
+	}
+	assert.NoError(t, m.Purge("b"))
+	assert.NoFileExists(t, filepath.Join(m.cfg.Root, "OpenIM_v3_b.db"))
+	assert.NoFileExists(t, filepath.Join(m.cfg.Root, "OpenIM_v3_b.db-wal"))
+	assert.FileExists(t, filepath.Join(m.cfg.Root, "OpenIM_v3_a_b.db"))
+	assert.FileExists(t, filepath.Join(m.cfg.Root, "OpenIM_v3_a_b.db-wal"))
+	assert.ErrorIs(t, m.Purge("b"), ErrNotFound)
+}
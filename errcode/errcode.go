@@ -0,0 +1,147 @@
+// Package errcode is the errCode space of the failures of the gateway itself, 20100 to 20799, one hundred per
+// category. The codes of the sdk and of the OpenIM server pass through unchanged and fall in the backend category.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yrzs/openimsdktools/errs"
+)
+
+// Category groups the codes by what failed.
+type Category string
+
+const (
+	CategoryAuth       Category = "auth"       // the connection is refused, 201xx
+	CategoryProtocol   Category = "protocol"   // the frame is not a valid request, 202xx
+	CategoryDispatch   Category = "dispatch"   // the request cannot be served now, 203xx
+	CategoryConversion Category = "conversion" // an arg or the result does not fit the sdk function, 204xx
+	CategoryOverload   Category = "overload"   // the gateway sheds load, 205xx
+	CategoryTimeout    Category = "timeout"    // no response came in time, 206xx
+	CategoryBackend    Category = "backend"    // the sdk or the OpenIM server failed, 207xx and their own codes
+)
+
+// Codes of the gateway.
+const (
+	TokenMissing  int32 = 20101 // the connection carries no token
+	InvalidUserID int32 = 20102 // the connection carries no valid sendID
+	TokenInvalid  int32 = 20103 // the token was refused
+
+	BadFrame      int32 = 20201 // the frame is not a JSON request
+	UnknownMethod int32 = 20202 // reqFuncName is not served
+	BadData       int32 = 20203 // data is not a JSON array
+	BadURL        int32 = 20204 // the connection url cannot be parsed
+
+	MissingOperationID int32 = 20301
+	NotLoggedIn        int32 = 20302 // the sdk resources are not loaded, Login first
+	Panic              int32 = 20303 // the call panicked, the session goes on
+	SessionFailed      int32 = 20304 // the session could not be set up
+	MethodDisabled     int32 = 20305 // the method kept panicking and is disabled for a while
+
+	BadArg    int32 = 20401 // an arg cannot be converted into its param, see Detail.Arg
+	ArgCount  int32 = 20402 // the number of args does not match the params
+	BadResult int32 = 20403 // the result cannot be encoded
+
+	Overloaded int32 = 20501 // too many connections or requests, retry later
+
+	Timeout int32 = 20601 // no response came in time
+
+	Backend int32 = 20701 // the sdk failed without an errCode of its own
+)
+
+// Reasons of BadArg.
+const (
+	ReasonNull       = "null"
+	ReasonType       = "type"        // the JSON kind does not fit the param
+	ReasonJSON       = "json"        // the JSON string does not decode into the param
+	ReasonNotNumber  = "not_number"  // a string is not a number
+	ReasonNotInteger = "not_integer" // the number has a fraction
+	ReasonNegative   = "negative"    // a negative number for an unsigned param
+	ReasonOverflow   = "overflow"    // the number does not fit the param
+	ReasonPrecision  = "precision"   // the integer is beyond 2^53 in a float64, send it as a string
+	ReasonNotBoolean = "not_boolean"
+)
+
+// Reasons of Backend.
+const (
+	ReasonNoError = "no_error" // a listener reported a failure without an error of its own
+)
+
+// Detail is the machine readable part of a failed response, sent as errDetail.
+type Detail struct {
+	Category Category `json:"category"`
+	Reason   string   `json:"reason,omitempty"` // stable name of the cause within the code, e.g. overflow
+	Arg      *int     `json:"arg,omitempty"`    // index of the arg at fault in the data array
+	Type     string   `json:"type,omitempty"`   // go type of the param of that arg
+}
+
+// Error is a failure with its errCode.
+type Error struct {
+	Code   int32
+	Msg    string
+	Detail *Detail
+}
+
+// New returns an error of a gateway code.
+func New(code int32, msg string) *Error {
+	return &Error{Code: code, Msg: msg, Detail: &Detail{Category: CategoryOf(code)}}
+}
+
+// Newf is New with a formatted message.
+func Newf(code int32, format string, a ...any) *Error {
+	return New(code, fmt.Sprintf(format, a...))
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Msg)
+}
+
+// WithReason sets the reason of the detail.
+func (e *Error) WithReason(reason string) *Error {
+	e.Detail.Reason = reason
+	return e
+}
+
+// WithArg sets the arg at fault and the type of its param.
+func (e *Error) WithArg(index int, typ string) *Error {
+	e.Detail.Arg = &index
+	e.Detail.Type = typ
+	return e
+}
+
+// CategoryOf returns the category of a code, backend for the codes outside the gateway space.
+func CategoryOf(code int32) Category {
+	switch code / 100 {
+	case 201:
+		return CategoryAuth
+	case 202:
+		return CategoryProtocol
+	case 203:
+		return CategoryDispatch
+	case 204:
+		return CategoryConversion
+	case 205:
+		return CategoryOverload
+	case 206:
+		return CategoryTimeout
+	}
+	return CategoryBackend
+}
+
+// From returns err as an Error: an Error in its chain as it is, an errs.CodeError of the sdk or the server with its
+// code, anything else as Backend. It returns nil for a nil err.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var code errs.CodeError
+	if errors.As(err, &code) && code.Code() != 0 {
+		return &Error{Code: int32(code.Code()), Msg: code.Error(), Detail: &Detail{Category: CategoryOf(int32(code.Code()))}}
+	}
+	return New(Backend, err.Error())
+}
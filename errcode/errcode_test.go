@@ -0,0 +1,35 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimsdkcore/pkg/sdkerrs"
+)
+
+func TestFrom(t *testing.T) {
+	assert.Nil(t, From(nil))
+
+	e := New(BadArg, "args[0] is null").WithArg(0, "string").WithReason(ReasonNull)
+	assert.Same(t, e, From(fmt.Errorf("wrapped: %w", e)))
+	assert.Equal(t, CategoryConversion, e.Detail.Category)
+
+	sdk := From(sdkerrs.ErrUserIDNotFound.Wrap("u1"))
+	assert.Equal(t, int32(sdkerrs.ErrUserIDNotFound.Code()), sdk.Code)
+	assert.Equal(t, CategoryBackend, sdk.Detail.Category)
+
+	plain := From(errors.New("boom"))
+	assert.Equal(t, Backend, plain.Code)
+	assert.Equal(t, "boom", plain.Msg)
+}
+
+func TestCategoryOf(t *testing.T) {
+	assert.Equal(t, CategoryAuth, CategoryOf(TokenInvalid))
+	assert.Equal(t, CategoryProtocol, CategoryOf(BadFrame))
+	assert.Equal(t, CategoryDispatch, CategoryOf(NotLoggedIn))
+	assert.Equal(t, CategoryOverload, CategoryOf(Overloaded))
+	assert.Equal(t, CategoryTimeout, CategoryOf(Timeout))
+	assert.Equal(t, CategoryBackend, CategoryOf(1001))
+}
@@ -0,0 +1,361 @@
+package fakeim
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	pbconversation "github.com/openimsdk/protocol/conversation"
+	"github.com/openimsdk/protocol/group"
+	"github.com/openimsdk/protocol/msg"
+	"github.com/openimsdk/protocol/relation"
+	"github.com/openimsdk/protocol/sdkws"
+	"github.com/openimsdk/protocol/user"
+	"github.com/yrzs/openimsdktools/errs"
+)
+
+// codeError is an api error, sent as the errCode and errMsg of the response.
+type codeError struct {
+	Code int
+	Msg  string
+}
+
+func (e *codeError) Error() string { return e.Msg }
+
+func (e *codeError) wrap(msg string) *codeError {
+	return &codeError{Code: e.Code, Msg: e.Msg + ": " + msg}
+}
+
+var (
+	errArgs          = &codeError{Code: errs.ArgsError, Msg: "ArgsError"}
+	errToken         = &codeError{Code: errs.TokenInvalidError, Msg: "TokenInvalidError"}
+	errGroupNotFound = &codeError{Code: errs.RecordNotFoundError, Msg: "group not found"}
+	errNotInGroup    = &codeError{Code: errs.NoPermissionError, Msg: "not in group"}
+)
+
+type apiResp struct {
+	ErrCode int    `json:"errCode"`
+	ErrMsg  string `json:"errMsg"`
+	ErrDlt  string `json:"errDlt"`
+	Data    any    `json:"data"`
+}
+
+// handler serves one api route for the user owning the token.
+type handler func(s *Server, userID string, body []byte) (any, error)
+
+// route adapts a typed handler, decoding the request body into Req.
+func route[Req any, Resp any](fn func(s *Server, userID string, req *Req) (*Resp, error)) handler {
+	return func(s *Server, userID string, body []byte) (any, error) {
+		req := new(Req)
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, errArgs.wrap(err.Error())
+		}
+		return fn(s, userID, req)
+	}
+}
+
+// registerRoutes sets the routes holding state, the others answer an empty success.
+func (s *Server) registerRoutes() {
+	s.routes = map[string]handler{
+		"/user/get_users_info": route(getUsersInfo),
+
+		"/friend/get_friend_list":        route(getFriendList),
+		"/friend/get_designated_friends": route(getDesignatedFriends),
+		"/friend/add_friend":             route(addFriend),
+		"/friend/delete_friend":          route(deleteFriend),
+
+		"/group/create_group":            route(createGroup),
+		"/group/get_joined_group_list":   route(getJoinedGroupList),
+		"/group/get_groups_info":         route(getGroupsInfo),
+		"/group/get_group_member_list":   route(getGroupMemberList),
+		"/group/get_group_members_info":  route(getGroupMembersInfo),
+		"/group/get_group_abstract_info": route(getGroupAbstractInfo),
+		"/group/invite_user_to_group":    route(inviteUserToGroup),
+		"/group/kick_group":              route(kickGroupMember),
+		"/group/quit_group":              route(quitGroup),
+		"/group/dismiss_group":           route(dismissGroup),
+
+		"/conversation/get_all_conversations": route(getAllConversations),
+		"/conversation/get_conversations":     route(getConversations),
+
+		"/msg/get_conversations_has_read_and_max_seq": route(getHasReadAndMaxSeqs),
+		"/msg/mark_conversation_as_read":              route(markConversationAsRead),
+		"/msg/set_conversation_has_read_seq":          route(setConversationHasReadSeq),
+		"/msg/get_server_time":                        route(getServerTime),
+		"/chat/pull_msg_by_seq":                       route(pullMsgBySeqs),
+	}
+}
+
+// serveApi answers every api post with the OpenIM response envelope.
+func (s *Server) serveApi(w http.ResponseWriter, r *http.Request) {
+	resp := &apiResp{Data: struct{}{}}
+	defer func() {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		resp.ErrCode, resp.ErrMsg = errArgs.Code, err.Error()
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastReq = time.Now()
+	userID, ok := s.tokens[r.Header.Get("token")]
+	if !ok {
+		resp.ErrCode, resp.ErrMsg = errToken.Code, errToken.Msg
+		return
+	}
+	fn, ok := s.routes[r.URL.Path]
+	if !ok {
+		return
+	}
+	data, err := fn(s, userID, body)
+	if err != nil {
+		resp.ErrCode, resp.ErrMsg = errArgs.Code, err.Error()
+		if e, ok := err.(*codeError); ok {
+			resp.ErrCode = e.Code
+		}
+		return
+	}
+	resp.Data = data
+}
+
+// page returns the page of list the pagination selects, the sdk asks the pages until one is short.
+func page[T any](list []T, pagination *sdkws.RequestPagination) []T {
+	if pagination == nil || pagination.ShowNumber <= 0 {
+		return list
+	}
+	start := int(pagination.PageNumber-1) * int(pagination.ShowNumber)
+	if start < 0 || start >= len(list) {
+		return nil
+	}
+	end := start + int(pagination.ShowNumber)
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[start:end]
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func getUsersInfo(s *Server, _ string, req *user.GetDesignateUsersReq) (*user.GetDesignateUsersResp, error) {
+	resp := &user.GetDesignateUsersResp{}
+	for _, userID := range req.UserIDs {
+		if u, ok := s.users[userID]; ok {
+			resp.UsersInfo = append(resp.UsersInfo, u)
+		}
+	}
+	return resp, nil
+}
+
+func getFriendList(s *Server, userID string, req *relation.GetPaginationFriendsReq) (*relation.GetPaginationFriendsResp, error) {
+	var friends []*sdkws.FriendInfo
+	for _, friendUserID := range sortedKeys(s.friends[userID]) {
+		friends = append(friends, s.friends[userID][friendUserID])
+	}
+	return &relation.GetPaginationFriendsResp{FriendsInfo: page(friends, req.Pagination), Total: int32(len(friends))}, nil
+}
+
+func getDesignatedFriends(s *Server, userID string, req *relation.GetDesignatedFriendsReq) (*relation.GetDesignatedFriendsResp, error) {
+	resp := &relation.GetDesignatedFriendsResp{}
+	for _, friendUserID := range req.FriendUserIDs {
+		if friend, ok := s.friends[userID][friendUserID]; ok {
+			resp.FriendsInfo = append(resp.FriendsInfo, friend)
+		}
+	}
+	return resp, nil
+}
+
+// addFriend accepts the application at once, the clients see the friendship on their next sync.
+func addFriend(s *Server, userID string, req *relation.ApplyToAddFriendReq) (*struct{}, error) {
+	if _, ok := s.users[req.ToUserID]; !ok {
+		return nil, errArgs.wrap("user not found " + req.ToUserID)
+	}
+	s.addFriend(userID, req.ToUserID)
+	s.addFriend(req.ToUserID, userID)
+	return &struct{}{}, nil
+}
+
+func deleteFriend(s *Server, userID string, req *relation.DeleteFriendReq) (*struct{}, error) {
+	delete(s.friends[userID], req.FriendUserID)
+	return &struct{}{}, nil
+}
+
+func createGroup(s *Server, userID string, req *group.CreateGroupReq) (*group.CreateGroupResp, error) {
+	if req.GroupInfo == nil {
+		return nil, errArgs.wrap("groupInfo is empty")
+	}
+	ownerUserID := req.OwnerUserID
+	if ownerUserID == "" {
+		ownerUserID = userID
+	}
+	info := s.createGroup(req.GroupInfo, ownerUserID, append(req.MemberUserIDs, req.AdminUserIDs...))
+	return &group.CreateGroupResp{GroupInfo: info}, nil
+}
+
+func getJoinedGroupList(s *Server, userID string, req *group.GetJoinedGroupListReq) (*group.GetJoinedGroupListResp, error) {
+	fromUserID := req.FromUserID
+	if fromUserID == "" {
+		fromUserID = userID
+	}
+	var groups []*sdkws.GroupInfo
+	for _, groupID := range sortedKeys(s.members) {
+		if _, ok := s.members[groupID][fromUserID]; ok {
+			groups = append(groups, s.groups[groupID])
+		}
+	}
+	return &group.GetJoinedGroupListResp{Total: uint32(len(groups)), Groups: page(groups, req.Pagination)}, nil
+}
+
+func getGroupsInfo(s *Server, _ string, req *group.GetGroupsInfoReq) (*group.GetGroupsInfoResp, error) {
+	resp := &group.GetGroupsInfoResp{}
+	for _, groupID := range req.GroupIDs {
+		if info, ok := s.groups[groupID]; ok {
+			resp.GroupInfos = append(resp.GroupInfos, info)
+		}
+	}
+	return resp, nil
+}
+
+func getGroupMemberList(s *Server, _ string, req *group.GetGroupMemberListReq) (*group.GetGroupMemberListResp, error) {
+	var members []*sdkws.GroupMemberFullInfo
+	for _, userID := range sortedKeys(s.members[req.GroupID]) {
+		members = append(members, s.members[req.GroupID][userID])
+	}
+	return &group.GetGroupMemberListResp{Total: uint32(len(members)), Members: page(members, req.Pagination)}, nil
+}
+
+func getGroupMembersInfo(s *Server, _ string, req *group.GetGroupMembersInfoReq) (*group.GetGroupMembersInfoResp, error) {
+	resp := &group.GetGroupMembersInfoResp{}
+	for _, userID := range req.UserIDs {
+		if member, ok := s.members[req.GroupID][userID]; ok {
+			resp.Members = append(resp.Members, member)
+		}
+	}
+	return resp, nil
+}
+
+// getGroupAbstractInfo leaves the member hash zero, so the sdk always syncs the member list.
+func getGroupAbstractInfo(s *Server, _ string, req *group.GetGroupAbstractInfoReq) (*group.GetGroupAbstractInfoResp, error) {
+	resp := &group.GetGroupAbstractInfoResp{}
+	for _, groupID := range req.GroupIDs {
+		if _, ok := s.groups[groupID]; ok {
+			resp.GroupAbstractInfos = append(resp.GroupAbstractInfos,
+				&group.GroupAbstractInfo{GroupID: groupID, GroupMemberNumber: uint32(len(s.members[groupID]))})
+		}
+	}
+	return resp, nil
+}
+
+func inviteUserToGroup(s *Server, userID string, req *group.InviteUserToGroupReq) (*struct{}, error) {
+	if _, ok := s.groups[req.GroupID]; !ok {
+		return nil, errGroupNotFound
+	}
+	for _, invitedUserID := range req.InvitedUserIDs {
+		s.joinGroup(req.GroupID, invitedUserID, userID, RoleLevelOrdinary)
+	}
+	return &struct{}{}, nil
+}
+
+func kickGroupMember(s *Server, _ string, req *group.KickGroupMemberReq) (*struct{}, error) {
+	for _, kickedUserID := range req.KickedUserIDs {
+		s.leaveGroup(req.GroupID, kickedUserID)
+	}
+	return &struct{}{}, nil
+}
+
+func quitGroup(s *Server, userID string, req *group.QuitGroupReq) (*struct{}, error) {
+	if req.UserID != "" {
+		userID = req.UserID
+	}
+	s.leaveGroup(req.GroupID, userID)
+	return &struct{}{}, nil
+}
+
+func dismissGroup(s *Server, _ string, req *group.DismissGroupReq) (*struct{}, error) {
+	delete(s.groups, req.GroupID)
+	delete(s.members, req.GroupID)
+	return &struct{}{}, nil
+}
+
+func getAllConversations(s *Server, userID string, _ *pbconversation.GetAllConversationsReq) (*pbconversation.GetAllConversationsResp, error) {
+	resp := &pbconversation.GetAllConversationsResp{}
+	for _, conversationID := range sortedKeys(s.convs[userID]) {
+		resp.Conversations = append(resp.Conversations, s.convs[userID][conversationID])
+	}
+	return resp, nil
+}
+
+func getConversations(s *Server, userID string, req *pbconversation.GetConversationsReq) (*pbconversation.GetConversationsResp, error) {
+	resp := &pbconversation.GetConversationsResp{}
+	for _, conversationID := range req.ConversationIDs {
+		if conv, ok := s.convs[userID][conversationID]; ok {
+			resp.Conversations = append(resp.Conversations, conv)
+		}
+	}
+	return resp, nil
+}
+
+func getHasReadAndMaxSeqs(s *Server, userID string, req *msg.GetConversationsHasReadAndMaxSeqReq) (*msg.GetConversationsHasReadAndMaxSeqResp, error) {
+	conversationIDs := req.ConversationIDs
+	if len(conversationIDs) == 0 {
+		conversationIDs = sortedKeys(s.convs[userID])
+	}
+	resp := &msg.GetConversationsHasReadAndMaxSeqResp{Seqs: make(map[string]*msg.Seqs)}
+	for _, conversationID := range conversationIDs {
+		msgs := s.msgs[conversationID]
+		seqs := &msg.Seqs{MaxSeq: int64(len(msgs)), HasReadSeq: s.hasRead[userID][conversationID]}
+		if len(msgs) > 0 {
+			seqs.MaxSeqTime = msgs[len(msgs)-1].SendTime
+		}
+		resp.Seqs[conversationID] = seqs
+	}
+	return resp, nil
+}
+
+func markConversationAsRead(s *Server, userID string, req *msg.MarkConversationAsReadReq) (*struct{}, error) {
+	s.setHasRead(userID, req.ConversationID, req.HasReadSeq)
+	return &struct{}{}, nil
+}
+
+func setConversationHasReadSeq(s *Server, userID string, req *msg.SetConversationHasReadSeqReq) (*struct{}, error) {
+	s.setHasRead(userID, req.ConversationID, req.HasReadSeq)
+	return &struct{}{}, nil
+}
+
+func getServerTime(*Server, string, *msg.GetServerTimeReq) (*msg.GetServerTimeResp, error) {
+	return &msg.GetServerTimeResp{ServerTime: time.Now().UnixMilli()}, nil
+}
+
+func pullMsgBySeqs(s *Server, _ string, req *sdkws.PullMessageBySeqsReq) (*sdkws.PullMessageBySeqsResp, error) {
+	return s.pullMsgs(req), nil
+}
+
+// pullMsgs returns the messages of the seq ranges, ascending.
+func (s *Server) pullMsgs(req *sdkws.PullMessageBySeqsReq) *sdkws.PullMessageBySeqsResp {
+	resp := &sdkws.PullMessageBySeqsResp{Msgs: make(map[string]*sdkws.PullMsgs),
+		NotificationMsgs: make(map[string]*sdkws.PullMsgs)}
+	for _, seqRange := range req.SeqRanges {
+		msgs := s.msgs[seqRange.ConversationID]
+		pulled := &sdkws.PullMsgs{IsEnd: seqRange.End >= int64(len(msgs))}
+		for seq := max(seqRange.Begin, 1); seq <= seqRange.End && seq <= int64(len(msgs)); seq++ {
+			if seqRange.Num > 0 && int64(len(pulled.Msgs)) >= seqRange.Num {
+				pulled.IsEnd = false
+				break
+			}
+			pulled.Msgs = append(pulled.Msgs, msgs[seq-1])
+		}
+		resp.Msgs[seqRange.ConversationID] = pulled
+	}
+	return resp
+}
@@ -0,0 +1,51 @@
+// Package fakegate runs a gateway in process against a fakeim server, for the tools and tests that need
+// a whole stack without a deployment.
+package fakegate
+
+import (
+	"net"
+	"time"
+
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/fakeim"
+	"github.com/yrzs/openimwssdk/gate"
+	"github.com/yrzs/openimwssdk/module"
+	"github.com/yrzs/openimwssdk/network/tjson"
+)
+
+// Gateway is a gateway listening on a local port, Addr is its ws address.
+type Gateway struct {
+	Addr     string
+	closeSig chan bool
+	stopped  chan struct{}
+}
+
+// Start points the sdk config at fake, keeps the local databases in dbDir and starts a gateway.
+func Start(fake *fakeim.Server, dbDir string) (*Gateway, error) {
+	core_func.Config.ApiAddr = fake.ApiAddr
+	core_func.Config.WsAddr = fake.WsAddr
+	core_func.Config.DataDir = dbDir
+	datadir.Init(datadir.Config{Root: dbDir, PerUser: true, InUse: module.GJsActors.Online})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	g := gate.NewGate(100*100*10, 1024*1024*10, tjson.NewProcessor(), addr, 10*time.Second, 1000)
+	g.SetFun(module.NewAgent, module.CloseAgent, module.DataRecv)
+	gw := &Gateway{Addr: "ws://" + addr, closeSig: make(chan bool), stopped: make(chan struct{})}
+	go func() {
+		g.Run(gw.closeSig)
+		close(gw.stopped)
+	}()
+	module.ProgressStartTime = time.Now().Unix()
+	return gw, nil
+}
+
+// Close stops the gateway and waits until its sessions are closed.
+func (g *Gateway) Close() {
+	g.closeSig <- true
+	<-g.stopped
+}
@@ -0,0 +1,283 @@
+// Package fakeim is an in-process OpenIM server for tests. It serves the http api and the
+// websocket long connection the sdk talks to, keeping users, friends, groups, conversations
+// and messages in memory.
+package fakeim
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pbconversation "github.com/openimsdk/protocol/conversation"
+	"github.com/openimsdk/protocol/sdkws"
+)
+
+// Chat types of the sdk, see openimsdkcore pkg/constant.
+const (
+	SingleChatType     = 1
+	SuperGroupChatType = 3
+	WorkingGroup       = 2
+)
+
+// Server is a fake OpenIM server, ApiAddr and WsAddr are set as the sdk config.
+type Server struct {
+	ApiAddr string
+	WsAddr  string
+
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	users    map[string]*sdkws.UserInfo
+	tokens   map[string]string                                  // token -> userID
+	friends  map[string]map[string]*sdkws.FriendInfo            // owner -> friend userID
+	groups   map[string]*sdkws.GroupInfo                        // groupID
+	members  map[string]map[string]*sdkws.GroupMemberFullInfo   // groupID -> userID
+	convs    map[string]map[string]*pbconversation.Conversation // owner -> conversationID
+	msgs     map[string][]*sdkws.MsgData                        // conversationID -> messages, msgs[i].Seq == i+1
+	hasRead  map[string]map[string]int64                        // owner -> conversationID -> seq
+	conns    map[string]map[*conn]struct{}                      // userID -> long connections
+	routes   map[string]handler
+	sequence int
+	lastReq  time.Time // of the last api request, see WaitIdle
+}
+
+// NewServer starts a fake server listening on a local port.
+func NewServer() *Server {
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("fakeim: failed to listen on a port: %v", err))
+	}
+	return s
+}
+
+// Listen starts a fake server listening on addr, e.g. for a gateway running in another process.
+func Listen(addr string) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		users:   make(map[string]*sdkws.UserInfo),
+		tokens:  make(map[string]string),
+		friends: make(map[string]map[string]*sdkws.FriendInfo),
+		groups:  make(map[string]*sdkws.GroupInfo),
+		members: make(map[string]map[string]*sdkws.GroupMemberFullInfo),
+		convs:   make(map[string]map[string]*pbconversation.Conversation),
+		msgs:    make(map[string][]*sdkws.MsgData),
+		hasRead: make(map[string]map[string]int64),
+		conns:   make(map[string]map[*conn]struct{}),
+	}
+	s.registerRoutes()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.serveWs)
+	mux.HandleFunc("/", s.serveApi)
+	s.srv = &httptest.Server{Listener: ln, Config: &http.Server{Handler: mux}}
+	s.srv.Start()
+	s.ApiAddr = s.srv.URL
+	s.WsAddr = "ws" + strings.TrimPrefix(s.srv.URL, "http") + "/ws"
+	return s, nil
+}
+
+// Close drops the long connections and stops the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, conns := range s.conns {
+		for c := range conns {
+			_ = c.ws.Close()
+		}
+	}
+	s.mu.Unlock()
+	s.srv.Close()
+}
+
+// WaitIdle blocks until no api request came for quiet, e.g. once the background syncs of a login
+// are over. It reports false when the server is still busy after timeout.
+func (s *Server) WaitIdle(quiet, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		s.mu.Lock()
+		idle := time.Since(s.lastReq)
+		s.mu.Unlock()
+		if idle >= quiet {
+			return true
+		}
+		if time.Now().Add(quiet - idle).After(deadline) {
+			return false
+		}
+		time.Sleep(quiet - idle)
+	}
+}
+
+// AddUser registers a user and returns the token it logs in with.
+func (s *Server) AddUser(userID, nickname string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID] = &sdkws.UserInfo{UserID: userID, Nickname: nickname, CreateTime: time.Now().UnixMilli()}
+	token := "token_" + userID
+	s.tokens[token] = userID
+	return token
+}
+
+// AddFriend makes the two users friends of each other.
+func (s *Server) AddFriend(userID, friendUserID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addFriend(userID, friendUserID)
+	s.addFriend(friendUserID, userID)
+}
+
+func (s *Server) addFriend(ownerUserID, friendUserID string) {
+	if s.friends[ownerUserID] == nil {
+		s.friends[ownerUserID] = make(map[string]*sdkws.FriendInfo)
+	}
+	s.friends[ownerUserID][friendUserID] = &sdkws.FriendInfo{OwnerUserID: ownerUserID, FriendUser: s.user(friendUserID),
+		CreateTime: time.Now().UnixMilli(), OperatorUserID: ownerUserID}
+}
+
+// CreateGroup creates a working group owned by ownerUserID and returns its id.
+func (s *Server) CreateGroup(ownerUserID, groupName string, memberUserIDs ...string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createGroup(&sdkws.GroupInfo{GroupName: groupName, GroupType: WorkingGroup}, ownerUserID, memberUserIDs).GroupID
+}
+
+func (s *Server) createGroup(info *sdkws.GroupInfo, ownerUserID string, memberUserIDs []string) *sdkws.GroupInfo {
+	s.sequence++
+	if info.GroupID == "" {
+		info.GroupID = fmt.Sprintf("g%d", s.sequence)
+	}
+	info.OwnerUserID = ownerUserID
+	if info.CreatorUserID == "" {
+		info.CreatorUserID = ownerUserID
+	}
+	info.CreateTime = time.Now().UnixMilli()
+	s.groups[info.GroupID] = info
+	s.members[info.GroupID] = make(map[string]*sdkws.GroupMemberFullInfo)
+	s.joinGroup(info.GroupID, ownerUserID, ownerUserID, RoleLevelOwner)
+	for _, userID := range memberUserIDs {
+		s.joinGroup(info.GroupID, userID, ownerUserID, RoleLevelOrdinary)
+	}
+	return info
+}
+
+// Group member roles, see openimsdkcore pkg/constant.
+const (
+	RoleLevelOrdinary = 20
+	RoleLevelOwner    = 100
+)
+
+func (s *Server) joinGroup(groupID, userID, inviterUserID string, roleLevel int32) {
+	if _, ok := s.members[groupID][userID]; ok {
+		return
+	}
+	user := s.user(userID)
+	s.members[groupID][userID] = &sdkws.GroupMemberFullInfo{GroupID: groupID, UserID: userID, RoleLevel: roleLevel,
+		JoinTime: time.Now().UnixMilli(), Nickname: user.Nickname, FaceURL: user.FaceURL, InviterUserID: inviterUserID,
+		OperatorUserID: inviterUserID}
+	s.groups[groupID].MemberCount = uint32(len(s.members[groupID]))
+	s.conversation(userID, "sg_"+groupID, SuperGroupChatType, "", groupID)
+}
+
+func (s *Server) leaveGroup(groupID, userID string) {
+	delete(s.members[groupID], userID)
+	if group, ok := s.groups[groupID]; ok {
+		group.MemberCount = uint32(len(s.members[groupID]))
+	}
+}
+
+// Messages returns the messages stored in a conversation, ordered by seq.
+func (s *Server) Messages(conversationID string) []*sdkws.MsgData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*sdkws.MsgData(nil), s.msgs[conversationID]...)
+}
+
+// user returns the registered user, or a bare one for an unknown id.
+func (s *Server) user(userID string) *sdkws.UserInfo {
+	if user, ok := s.users[userID]; ok {
+		return user
+	}
+	return &sdkws.UserInfo{UserID: userID}
+}
+
+// conversation returns the conversation of the owner, creating it on first use.
+func (s *Server) conversation(ownerUserID, conversationID string, conversationType int32, userID, groupID string) *pbconversation.Conversation {
+	if s.convs[ownerUserID] == nil {
+		s.convs[ownerUserID] = make(map[string]*pbconversation.Conversation)
+	}
+	conv, ok := s.convs[ownerUserID][conversationID]
+	if !ok {
+		conv = &pbconversation.Conversation{OwnerUserID: ownerUserID, ConversationID: conversationID,
+			ConversationType: conversationType, UserID: userID, GroupID: groupID}
+		s.convs[ownerUserID][conversationID] = conv
+	}
+	conv.MaxSeq = int64(len(s.msgs[conversationID]))
+	return conv
+}
+
+// conversationID returns the id the sdk uses for the conversation of a message.
+func conversationID(msg *sdkws.MsgData) string {
+	if msg.SessionType == SingleChatType {
+		ids := []string{msg.SendID, msg.RecvID}
+		sort.Strings(ids)
+		return "si_" + strings.Join(ids, "_")
+	}
+	return "sg_" + msg.GroupID
+}
+
+// storeMsg assigns the seq of a message and returns the users it is pushed to.
+func (s *Server) storeMsg(msg *sdkws.MsgData) (string, []string, error) {
+	var recipients []string
+	switch msg.SessionType {
+	case SingleChatType:
+		recipients = []string{msg.SendID, msg.RecvID}
+	case SuperGroupChatType:
+		members, ok := s.members[msg.GroupID]
+		if !ok {
+			return "", nil, errGroupNotFound
+		}
+		if _, ok := members[msg.SendID]; !ok {
+			return "", nil, errNotInGroup
+		}
+		for userID := range members {
+			recipients = append(recipients, userID)
+		}
+	default:
+		return "", nil, errArgs.wrap(fmt.Sprintf("session type %d not supported", msg.SessionType))
+	}
+	id := conversationID(msg)
+	s.sequence++
+	msg.ServerMsgID = fmt.Sprintf("server_%d", s.sequence)
+	msg.SendTime = time.Now().UnixMilli()
+	msg.Seq = int64(len(s.msgs[id]) + 1)
+	s.msgs[id] = append(s.msgs[id], msg)
+	for _, userID := range recipients {
+		if msg.SessionType == SingleChatType {
+			peer := msg.RecvID
+			if userID == msg.RecvID {
+				peer = msg.SendID
+			}
+			s.conversation(userID, id, SingleChatType, peer, "")
+		} else {
+			s.conversation(userID, id, SuperGroupChatType, "", msg.GroupID)
+		}
+	}
+	if msg.SendID != "" {
+		s.setHasRead(msg.SendID, id, msg.Seq)
+	}
+	return id, recipients, nil
+}
+
+func (s *Server) setHasRead(ownerUserID, conversationID string, seq int64) {
+	if s.hasRead[ownerUserID] == nil {
+		s.hasRead[ownerUserID] = make(map[string]int64)
+	}
+	if seq > s.hasRead[ownerUserID][conversationID] {
+		s.hasRead[ownerUserID][conversationID] = seq
+	}
+}
@@ -0,0 +1,249 @@
+package fakeim
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/openimsdk/protocol/sdkws"
+	"google.golang.org/protobuf/proto"
+)
+
+// Request identifiers of the long connection, see openimsdkcore pkg/constant.
+const (
+	GetNewestSeq        = 1001
+	PullMsgBySeqList    = 1002
+	SendMsg             = 1003
+	SendSignalMsg       = 1004
+	PushMsg             = 2001
+	KickOnlineMsg       = 2002
+	SetBackgroundStatus = 2004
+)
+
+// GeneralWsReq is the frame the sdk sends, gob encoded and gzip compressed.
+type GeneralWsReq struct {
+	ReqIdentifier int
+	Token         string
+	SendID        string
+	OperationID   string
+	MsgIncr       string
+	Data          []byte
+}
+
+// GeneralWsResp is the frame the sdk receives.
+type GeneralWsResp struct {
+	ReqIdentifier int
+	ErrCode       int
+	ErrMsg        string
+	MsgIncr       string
+	OperationID   string
+	Data          []byte
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+// conn is the long connection of one sdk.
+type conn struct {
+	ws       *websocket.Conn
+	userID   string
+	compress bool
+	mu       sync.Mutex // serializes the writes
+}
+
+// serveWs accepts the long connection of a user, refusing an unknown token like the real gateway.
+func (s *Server) serveWs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	s.mu.Lock()
+	userID, ok := s.tokens[query.Get("token")]
+	s.mu.Unlock()
+	if !ok || userID != query.Get("sendID") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(&apiResp{ErrCode: errToken.Code, ErrMsg: errToken.Msg})
+		return
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{ws: ws, userID: userID, compress: query.Get("compression") == "gzip"}
+	s.mu.Lock()
+	if s.conns[userID] == nil {
+		s.conns[userID] = make(map[*conn]struct{})
+	}
+	s.conns[userID][c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns[userID], c)
+		s.mu.Unlock()
+		_ = ws.Close()
+	}()
+	for {
+		messageType, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		req := &GeneralWsReq{}
+		if err := c.decode(data, req); err != nil {
+			return
+		}
+		resp, pushes := s.handleWsReq(c, req)
+		if err := c.write(resp); err != nil {
+			return
+		}
+		if pushes != nil {
+			pushes()
+		}
+	}
+}
+
+// handleWsReq answers one request, a sent message also returns its pushes, run once the answer is written.
+func (s *Server) handleWsReq(c *conn, req *GeneralWsReq) (*GeneralWsResp, func()) {
+	resp := &GeneralWsResp{ReqIdentifier: req.ReqIdentifier, MsgIncr: req.MsgIncr, OperationID: req.OperationID}
+	var out proto.Message
+	var pushes func()
+	var err error
+	switch req.ReqIdentifier {
+	case GetNewestSeq:
+		s.mu.Lock()
+		out = s.maxSeqs(c.userID)
+		s.mu.Unlock()
+	case PullMsgBySeqList:
+		in := &sdkws.PullMessageBySeqsReq{}
+		if err = proto.Unmarshal(req.Data, in); err == nil {
+			s.mu.Lock()
+			out = s.pullMsgs(in)
+			s.mu.Unlock()
+		}
+	case SendMsg, SendSignalMsg:
+		msg := &sdkws.MsgData{}
+		if err = proto.Unmarshal(req.Data, msg); err == nil {
+			msg.SendID = c.userID
+			if _, pushes, err = s.sendMsg(msg); err == nil {
+				out = &sdkws.UserSendMsgResp{ServerMsgID: msg.ServerMsgID, ClientMsgID: msg.ClientMsgID, SendTime: msg.SendTime}
+			}
+		}
+	case SetBackgroundStatus:
+	default:
+		err = errArgs.wrap("reqIdentifier not supported")
+	}
+	if err == nil && out != nil {
+		resp.Data, err = proto.Marshal(out)
+	}
+	if err != nil {
+		resp.ErrCode, resp.ErrMsg = errArgs.Code, err.Error()
+		if e, ok := err.(*codeError); ok {
+			resp.ErrCode = e.Code
+		}
+	}
+	return resp, pushes
+}
+
+func (s *Server) maxSeqs(userID string) *sdkws.GetMaxSeqResp {
+	resp := &sdkws.GetMaxSeqResp{MaxSeqs: make(map[string]int64), MinSeqs: make(map[string]int64)}
+	for conversationID := range s.convs[userID] {
+		resp.MaxSeqs[conversationID] = int64(len(s.msgs[conversationID]))
+	}
+	return resp
+}
+
+// SendMsg stores a message as if a client had sent it and pushes it to the online recipients,
+// including the sender. It returns the conversation id of the message.
+func (s *Server) SendMsg(msg *sdkws.MsgData) (string, error) {
+	conversationID, pushes, err := s.sendMsg(msg)
+	if err != nil {
+		return "", err
+	}
+	pushes()
+	return conversationID, nil
+}
+
+// sendMsg stores a message and returns the function pushing it.
+func (s *Server) sendMsg(msg *sdkws.MsgData) (string, func(), error) {
+	s.mu.Lock()
+	conversationID, recipients, err := s.storeMsg(msg)
+	var conns []*conn
+	for _, userID := range recipients {
+		for c := range s.conns[userID] {
+			conns = append(conns, c)
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return "", nil, err
+	}
+	push, err := proto.Marshal(&sdkws.PushMessages{Msgs: map[string]*sdkws.PullMsgs{
+		conversationID: {Msgs: []*sdkws.MsgData{msg}}}})
+	if err != nil {
+		return "", nil, err
+	}
+	return conversationID, func() {
+		for _, c := range conns {
+			_ = c.write(&GeneralWsResp{ReqIdentifier: PushMsg, Data: push})
+		}
+	}, nil
+}
+
+// Kick sends the kicked offline frame to every connection of the user.
+func (s *Server) Kick(userID string) {
+	s.mu.Lock()
+	var conns []*conn
+	for c := range s.conns[userID] {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		_ = c.write(&GeneralWsResp{ReqIdentifier: KickOnlineMsg})
+	}
+}
+
+// Online reports whether the user has a long connection.
+func (s *Server) Online(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns[userID]) > 0
+}
+
+func (c *conn) decode(data []byte, req *GeneralWsReq) error {
+	if c.compress {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if data, err = io.ReadAll(reader); err != nil {
+			return err
+		}
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(req)
+}
+
+func (c *conn) write(resp *GeneralWsResp) error {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(resp); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if c.compress {
+		zipped := bytes.Buffer{}
+		gz := gzip.NewWriter(&zipped)
+		if _, err := gz.Write(data); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		data = zipped.Bytes()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws.WriteMessage(websocket.BinaryMessage, data)
+}
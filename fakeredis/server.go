@@ -0,0 +1,461 @@
+// Package fakeredis is a stand-in Redis server for tests, speaking the protocol for the string, set, transaction
+// and pub/sub commands the gateway registry uses. It keeps everything in memory.
+package fakeredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server listens on a local port until Close.
+type Server struct {
+	ln       net.Listener
+	password string
+	mu       sync.Mutex
+	strs     map[string]*strValue
+	sets     map[string]map[string]struct{}
+	versions map[string]uint64 // bumped on every write of a key, for WATCH
+	subs     map[string]map[*client]struct{}
+	clients  map[*client]struct{}
+	wg       sync.WaitGroup
+}
+
+type strValue struct {
+	val      string
+	expireAt time.Time // zero for no expiry
+}
+
+// client is one connection.
+type client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	wmu     sync.Mutex // publishes write to subscribers from other connections
+	w       *bufio.Writer
+	authed  bool
+	multi   bool
+	queued  [][]string
+	watched map[string]uint64
+	subs    map[string]bool
+}
+
+// NewServer starts a server on a random local port, password empty accepts any connection.
+func NewServer(password string) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{ln: ln, password: password, strs: make(map[string]*strValue), sets: make(map[string]map[string]struct{}),
+		versions: make(map[string]uint64), subs: make(map[string]map[*client]struct{}), clients: make(map[*client]struct{})}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr is the host:port to dial.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops listening and drops every connection.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.mu.Lock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// DropConnections closes every connection, the data stays, as after a network failure.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+}
+
+// Get returns a string key, to check what the client stored.
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.str(key)
+	if v == nil {
+		return "", false
+	}
+	return v.val, true
+}
+
+// IsMember reports whether member is in the set key.
+func (s *Server) IsMember(key string, member string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sets[key][member]
+	return ok
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn), authed: s.password == "",
+			subs: make(map[string]bool)}
+		s.mu.Lock()
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c *client) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		for ch := range c.subs {
+			delete(s.subs[ch], c)
+		}
+		s.mu.Unlock()
+		c.conn.Close()
+	}()
+	for {
+		args, err := readCommand(c.r)
+		if err != nil {
+			return
+		}
+		reply := s.exec(c, args)
+		c.wmu.Lock()
+		writeReply(c.w, reply)
+		err = c.w.Flush()
+		c.wmu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// errReply is an error reply.
+type errReply string
+
+// pushes are the replies of SUBSCRIBE, one per channel.
+type pushes [][]any
+
+var queued = "QUEUED"
+
+func (s *Server) exec(c *client, args []string) any {
+	if len(args) == 0 {
+		return errReply("ERR empty command")
+	}
+	name := strings.ToUpper(args[0])
+	if name == "AUTH" {
+		if len(args) != 2 || args[1] != s.password {
+			return errReply("WRONGPASS invalid password")
+		}
+		c.authed = true
+		return "OK"
+	}
+	if !c.authed {
+		return errReply("NOAUTH Authentication required.")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch name {
+	case "MULTI":
+		if c.multi {
+			return errReply("ERR MULTI calls can not be nested")
+		}
+		c.multi, c.queued = true, nil
+		return "OK"
+	case "EXEC":
+		if !c.multi {
+			return errReply("ERR EXEC without MULTI")
+		}
+		queue, watched := c.queued, c.watched
+		c.multi, c.queued, c.watched = false, nil, nil
+		for key, version := range watched {
+			if s.versions[key] != version {
+				return nil
+			}
+		}
+		results := make([]any, 0, len(queue))
+		for _, q := range queue {
+			results = append(results, s.run(c, q))
+		}
+		return results
+	case "DISCARD":
+		if !c.multi {
+			return errReply("ERR DISCARD without MULTI")
+		}
+		c.multi, c.queued, c.watched = false, nil, nil
+		return "OK"
+	case "WATCH":
+		if c.multi {
+			return errReply("ERR WATCH inside MULTI is not allowed")
+		}
+		if c.watched == nil {
+			c.watched = make(map[string]uint64)
+		}
+		for _, key := range args[1:] {
+			s.str(key) // expire it first, so an expiry after WATCH does not count as a change
+			c.watched[key] = s.versions[key]
+		}
+		return "OK"
+	case "UNWATCH":
+		c.watched = nil
+		return "OK"
+	}
+	if c.multi {
+		c.queued = append(c.queued, args)
+		return queued
+	}
+	return s.run(c, args)
+}
+
+// run executes a data command with the lock held.
+func (s *Server) run(c *client, args []string) any {
+	name := strings.ToUpper(args[0])
+	switch name {
+	case "PING":
+		return "PONG"
+	case "SELECT":
+		return "OK"
+	case "GET":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		if v := s.str(args[1]); v != nil {
+			return v.val
+		}
+		return nil
+	case "SET":
+		if len(args) < 3 {
+			return wrongArgs(name)
+		}
+		v := &strValue{val: args[2]}
+		for i := 3; i < len(args); i++ {
+			opt := strings.ToUpper(args[i])
+			if (opt == "PX" || opt == "EX") && i+1 < len(args) {
+				n, err := strconv.ParseInt(args[i+1], 10, 64)
+				if err != nil || n <= 0 {
+					return errReply("ERR invalid expire time in 'set' command")
+				}
+				unit := time.Millisecond
+				if opt == "EX" {
+					unit = time.Second
+				}
+				v.expireAt = time.Now().Add(time.Duration(n) * unit)
+				i++
+				continue
+			}
+			return errReply("ERR syntax error")
+		}
+		delete(s.sets, args[1])
+		s.strs[args[1]] = v
+		s.versions[args[1]]++
+		return "OK"
+	case "MGET":
+		ret := make([]any, 0, len(args)-1)
+		for _, key := range args[1:] {
+			if v := s.str(key); v != nil {
+				ret = append(ret, v.val)
+			} else {
+				ret = append(ret, nil)
+			}
+		}
+		return ret
+	case "DEL":
+		var n int64
+		for _, key := range args[1:] {
+			if s.str(key) != nil || s.sets[key] != nil {
+				n++
+				delete(s.strs, key)
+				delete(s.sets, key)
+				s.versions[key]++
+			}
+		}
+		return n
+	case "EXISTS":
+		var n int64
+		for _, key := range args[1:] {
+			if s.str(key) != nil || s.sets[key] != nil {
+				n++
+			}
+		}
+		return n
+	case "SADD":
+		if len(args) < 3 {
+			return wrongArgs(name)
+		}
+		set := s.sets[args[1]]
+		if set == nil {
+			set = make(map[string]struct{})
+			s.sets[args[1]] = set
+		}
+		var n int64
+		for _, m := range args[2:] {
+			if _, ok := set[m]; !ok {
+				set[m] = struct{}{}
+				n++
+			}
+		}
+		s.versions[args[1]]++
+		return n
+	case "SREM":
+		if len(args) < 3 {
+			return wrongArgs(name)
+		}
+		set := s.sets[args[1]]
+		var n int64
+		for _, m := range args[2:] {
+			if _, ok := set[m]; ok {
+				delete(set, m)
+				n++
+			}
+		}
+		if set != nil && len(set) == 0 {
+			delete(s.sets, args[1])
+		}
+		s.versions[args[1]]++
+		return n
+	case "SMEMBERS":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		ret := make([]any, 0, len(s.sets[args[1]]))
+		for m := range s.sets[args[1]] {
+			ret = append(ret, m)
+		}
+		return ret
+	case "PUBLISH":
+		if len(args) != 3 {
+			return wrongArgs(name)
+		}
+		var n int64
+		for sub := range s.subs[args[1]] {
+			sub.push([]any{"message", args[1], args[2]})
+			n++
+		}
+		return n
+	case "SUBSCRIBE":
+		if len(args) < 2 {
+			return wrongArgs(name)
+		}
+		var ret pushes
+		for _, ch := range args[1:] {
+			if s.subs[ch] == nil {
+				s.subs[ch] = make(map[*client]struct{})
+			}
+			s.subs[ch][c] = struct{}{}
+			c.subs[ch] = true
+			ret = append(ret, []any{"subscribe", ch, int64(len(c.subs))})
+		}
+		return ret
+	}
+	return errReply(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+}
+
+// str returns a live string key, dropping it when it has expired.
+func (s *Server) str(key string) *strValue {
+	v, ok := s.strs[key]
+	if !ok {
+		return nil
+	}
+	if !v.expireAt.IsZero() && !time.Now().Before(v.expireAt) {
+		delete(s.strs, key)
+		s.versions[key]++
+		return nil
+	}
+	return v
+}
+
+func wrongArgs(name string) errReply {
+	return errReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// push writes a published message to a subscriber.
+func (c *client) push(msg []any) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	writeReply(c.w, msg)
+	_ = c.w.Flush()
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return nil, errors.New("expected an array")
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = readLine(r); err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errors.New("expected a bulk string")
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func writeReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case errReply:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		if v == "OK" || v == "PONG" || v == queued {
+			fmt.Fprintf(w, "+%s\r\n", v)
+		} else {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+		}
+	case pushes:
+		for _, p := range v {
+			writeReply(w, p)
+		}
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package gate
 
 import (
+	"context"
 	"net"
 	"reflect"
 	"time"
 
-	"github.com/go-kratos/kratos/v2/log"
 	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/logger"
 	"github.com/yrzs/openimwssdk/network"
+	"github.com/yrzs/openimwssdk/panics"
 )
 
 type Gate struct {
@@ -23,9 +25,12 @@ type Gate struct {
 	CertFile    string
 	KeyFile     string
 
-	// tcp
+	// tcp, the frames of network.MsgParser
 	TCPAddr   string
-	LenMsgLen int
+	LenMsgLen int // 1, 2 or 4 bytes
+
+	// sse, requests POSTed and events streamed; shares HTTPTimeout, CertFile and KeyFile with websocket
+	SSEAddr string
 
 	//add by huanglin
 	FunNewAgent   func(Agent)
@@ -59,76 +64,93 @@ func (gate *Gate) Run(closeSig chan bool) {
 		wsServer.CertFile = gate.CertFile
 		wsServer.KeyFile = gate.KeyFile
 		wsServer.NewAgent = func(conn *network.WSConn) network.Agent {
-			a := &agent{conn: conn, gate: gate}
-			/*if gate.AgentChanRPC != nil {
-				gate.AgentChanRPC.Go("NewAgent", a)
-			}*/
-			/////////////////////////////////////////////////////
-			tagent := common.TAgentUserData{SessionID: conn.SessionId, AppString: conn.AppURL, CookieVal: conn.CookieVal}
-			a.SetUserData(&tagent)
-			gate.FunNewAgent(a)
-			return a
+			return gate.newAgent(conn, &common.TAgentUserData{SessionID: conn.SessionId, AppString: conn.AppURL,
+				CookieVal: conn.CookieVal})
+		}
+	}
+	var tcpServer *network.TCPServer
+	if gate.TCPAddr != "" {
+		tcpServer = new(network.TCPServer)
+		tcpServer.Addr = gate.TCPAddr
+		tcpServer.MaxConnNum = gate.MaxConnNum
+		tcpServer.PendingWriteNum = gate.PendingWriteNum
+		tcpServer.LenMsgLen = gate.LenMsgLen
+		tcpServer.MaxMsgLen = gate.MaxMsgLen
+		tcpServer.HandshakeTimeout = gate.HTTPTimeout
+		tcpServer.NewAgent = func(conn *network.TCPConn) network.Agent {
+			return gate.newAgent(conn, &common.TAgentUserData{SessionID: conn.SessionId, AppString: conn.AppURL})
+		}
+	}
+
+	var sseServer *network.SSEServer
+	if gate.SSEAddr != "" {
+		sseServer = new(network.SSEServer)
+		sseServer.Addr = gate.SSEAddr
+		sseServer.MaxConnNum = gate.MaxConnNum
+		sseServer.PendingWriteNum = gate.PendingWriteNum
+		sseServer.MaxMsgLen = gate.MaxMsgLen
+		sseServer.HTTPTimeout = gate.HTTPTimeout
+		sseServer.CertFile = gate.CertFile
+		sseServer.KeyFile = gate.KeyFile
+		sseServer.NewAgent = func(conn *network.SSEConn) network.Agent {
+			return gate.newAgent(conn, &common.TAgentUserData{SessionID: conn.SessionId, AppString: conn.AppURL,
+				CookieVal: conn.CookieVal})
 		}
 	}
-	/*
-		var tcpServer *network.TCPServer
-		if gate.TCPAddr != "" {
-			tcpServer = new(network.TCPServer)
-			tcpServer.Addr = gate.TCPAddr
-			tcpServer.MaxConnNum = gate.MaxConnNum
-			tcpServer.PendingWriteNum = gate.PendingWriteNum
-			tcpServer.LenMsgLen = gate.LenMsgLen
-			tcpServer.MaxMsgLen = gate.MaxMsgLen
-			tcpServer.UsePacketMode = gate.Processor.UsePacketMode()
-			tcpServer.NewAgent = func(conn *network.TCPConn) network.Agent {
-				a := &agent{conn: conn, gate: gate}
-				if gate.AgentChanRPC != nil {
-					gate.AgentChanRPC.Go("NewAgent", a)
-				}
-				gate.FunNewAgent(a)
-				return a
-			}
-		}*/
 
 	if wsServer != nil {
 		wsServer.Start()
 	}
-	/*if tcpServer != nil {
+	if tcpServer != nil {
 		tcpServer.Start()
-	}*/
+	}
+	if sseServer != nil {
+		sseServer.Start()
+	}
 	<-closeSig
 	if wsServer != nil {
 		wsServer.Close()
 	}
-	/*if tcpServer != nil {
+	if tcpServer != nil {
 		tcpServer.Close()
-	}*/
+	}
+	if sseServer != nil {
+		sseServer.Close()
+	}
 }
 
 func (gate *Gate) OnDestroy() {}
 
+// newAgent creates the agent of a connection of either transport and hands it to FunNewAgent.
+func (gate *Gate) newAgent(conn network.Conn, userData *common.TAgentUserData) *agent {
+	a := &agent{conn: conn, gate: gate, ctx: logger.NewSessionContext(context.Background(), userData.SessionID, "")}
+	a.SetUserData(userData)
+	gate.FunNewAgent(a)
+	return a
+}
+
 type agent struct {
 	conn     network.Conn
 	gate     *Gate
 	userData interface{}
+	ctx      context.Context
 }
 
-// Run processes incoming messages in a loop.
+// Run processes incoming messages in a loop. A panic ends the connection with an internal error close frame, the
+// gateway goes on.
 func (a *agent) Run() {
-	defer common.TryRecoverAndDebugPrint()
+	defer panics.Recover(a.ctx, "agent", func(any) { _ = a.conn.WriteMsg(panics.CloseMessage()) })
 	for {
 		nType, data, err := a.conn.ReadMsg()
 		if err != nil {
-			//log.Debug("read message: %v", err)
-			log.Info("read message error", "error", err)
+			logger.Info(a.ctx, "read message error", "err", err)
 			break
 		}
-		log.Debug("recve one ws msg ", "nType", nType)
+		logger.Debug(a.ctx, "recv one msg", "nType", nType)
 		if a.gate.Processor != nil {
 			msg, err := a.gate.Processor.UnmarshalMul(nType, data)
 			if err != nil {
-				//log.Debug("unmarshal message error: %v", err)
-				log.Error("unmarshal message error", "err", err)
+				logger.Error(a.ctx, "unmarshal message error", "err", err)
 				break
 			}
 			a.gate.FuncMsgRecv(msg, a)
@@ -158,14 +180,12 @@ func (a *agent) WriteMsg(msg interface{}) {
 	if a.gate.Processor != nil {
 		data, err := a.gate.Processor.Marshal(msg)
 		if err != nil {
-			//log.Error("marshal message %v error: %v", reflect.TypeOf(msg), err)
-			log.Error("marshal message", "reflect.TypeOf(msg)", reflect.TypeOf(msg), "error", err)
+			logger.Error(a.ctx, "marshal message error", "msgType", reflect.TypeOf(msg), "err", err)
 			return
 		}
 		err = a.conn.WriteMsg(data)
 		if err != nil {
-			//log.Error("write message %v error: %v", reflect.TypeOf(msg), err)
-			log.Error("write message error", "reflect.TypeOf(msg)", reflect.TypeOf(msg), "error", err)
+			logger.Error(a.ctx, "write message error", "msgType", reflect.TypeOf(msg), "err", err)
 		}
 	}
 }
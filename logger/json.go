@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// jsonLogger writes one json object per line, keeping the key order of the call.
+type jsonLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newJSONLogger(w io.Writer) *jsonLogger {
+	return &jsonLogger{w: w}
+}
+
+func (l *jsonLogger) Log(level log.Level, keyvals ...any) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"level":"`)
+	buf.WriteString(level.String())
+	buf.WriteByte('"')
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "KEYVALS UNPAIRED")
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		buf.WriteByte(',')
+		key, _ := json.Marshal(fmt.Sprint(keyvals[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(jsonValue(keyvals[i+1]))
+	}
+	buf.WriteString("}\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.w.Write(buf.Bytes())
+	return err
+}
+
+// jsonValue encodes errors and stringers as their text, and anything json can not encode with fmt.
+func jsonValue(v any) []byte {
+	switch t := v.(type) {
+	case error:
+		v = t.Error()
+	case fmt.Stringer:
+		v = t.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("%+v", v))
+	}
+	return b
+}
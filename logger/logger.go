@@ -0,0 +1,216 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	KeySessionID   = "sessionId"
+	KeyUserID      = "userID"
+	KeyOperationID = "operationID"
+
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type ctxKey struct{}
+
+// ctxFields are the fields every log line of a session carries.
+type ctxFields struct {
+	sessionId   string
+	userID      string
+	operationID string
+}
+
+var (
+	globalLevel atomic.Int32
+	userLevels  sync.Map     // userID -> log.Level
+	output      atomic.Value // log.Logger
+)
+
+func init() {
+	globalLevel.Store(int32(log.LevelInfo))
+	output.Store(newOutput(FormatText, os.Stdout))
+}
+
+// Init selects the output format and the global level, and routes the kratos global logger through them.
+func Init(format string, level log.Level, w io.Writer) error {
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	output.Store(newOutput(format, w))
+	SetLevel(level)
+	log.SetLogger(&levelLogger{})
+	return nil
+}
+
+// newOutput builds the formatting logger, the caller depth skips the frames of this package and of the kratos global logger.
+func newOutput(format string, w io.Writer) log.Logger {
+	var base log.Logger
+	if format == FormatJSON {
+		base = newJSONLogger(w)
+	} else {
+		base = log.NewStdLogger(w)
+	}
+	return log.With(base, "ts", log.DefaultTimestamp, "caller", log.Caller(5))
+}
+
+// NewSessionContext returns a context carrying the session fields of every log line.
+func NewSessionContext(ctx context.Context, sessionId string, userID string) context.Context {
+	f := fieldsFrom(ctx)
+	f.sessionId = sessionId
+	f.userID = userID
+	return context.WithValue(ctx, ctxKey{}, f)
+}
+
+// WithOperationID returns a context carrying the operationID of the request being served.
+func WithOperationID(ctx context.Context, operationID string) context.Context {
+	f := fieldsFrom(ctx)
+	f.operationID = operationID
+	return context.WithValue(ctx, ctxKey{}, f)
+}
+
+// Fields returns the sessionId, userID and operationID carried by ctx.
+func Fields(ctx context.Context) (sessionId string, userID string, operationID string) {
+	f := fieldsFrom(ctx)
+	return f.sessionId, f.userID, f.operationID
+}
+
+func fieldsFrom(ctx context.Context) ctxFields {
+	if ctx == nil {
+		return ctxFields{}
+	}
+	f, _ := ctx.Value(ctxKey{}).(ctxFields)
+	return f
+}
+
+// Debug logs at debug level with the fields of ctx.
+func Debug(ctx context.Context, msg string, keyvals ...any) {
+	write(ctx, log.LevelDebug, msg, keyvals)
+}
+
+// Info logs at info level with the fields of ctx.
+func Info(ctx context.Context, msg string, keyvals ...any) {
+	write(ctx, log.LevelInfo, msg, keyvals)
+}
+
+// Warn logs at warn level with the fields of ctx.
+func Warn(ctx context.Context, msg string, keyvals ...any) {
+	write(ctx, log.LevelWarn, msg, keyvals)
+}
+
+// Error logs at error level with the fields of ctx.
+func Error(ctx context.Context, msg string, keyvals ...any) {
+	write(ctx, log.LevelError, msg, keyvals)
+}
+
+// Fatal logs at fatal level with the fields of ctx and exits the process.
+func Fatal(ctx context.Context, msg string, keyvals ...any) {
+	write(ctx, log.LevelFatal, msg, keyvals)
+	os.Exit(1)
+}
+
+func write(ctx context.Context, level log.Level, msg string, keyvals []any) {
+	f := fieldsFrom(ctx)
+	if !Enabled(level, f.userID) {
+		return
+	}
+	kvs := make([]any, 0, len(keyvals)+8)
+	kvs = append(kvs, log.DefaultMessageKey, msg, KeySessionID, f.sessionId, KeyUserID, f.userID,
+		KeyOperationID, f.operationID)
+	kvs = append(kvs, keyvals...)
+	_ = output.Load().(log.Logger).Log(level, kvs...)
+}
+
+// levelLogger applies the runtime levels to lines of the kratos global logger.
+type levelLogger struct{}
+
+func (l *levelLogger) Log(level log.Level, keyvals ...any) error {
+	var userID string
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == KeyUserID {
+			userID, _ = keyvals[i+1].(string)
+			break
+		}
+	}
+	if !Enabled(level, userID) {
+		return nil
+	}
+	return output.Load().(log.Logger).Log(level, keyvals...)
+}
+
+// Enabled reports whether a line of the level is written for the user, a user level overrides the global one.
+func Enabled(level log.Level, userID string) bool {
+	if userID != "" {
+		if v, ok := userLevels.Load(userID); ok {
+			return level >= v.(log.Level)
+		}
+	}
+	return level >= log.Level(globalLevel.Load())
+}
+
+// SetLevel changes the global level.
+func SetLevel(level log.Level) {
+	globalLevel.Store(int32(level))
+}
+
+// GetLevel returns the global level.
+func GetLevel() log.Level {
+	return log.Level(globalLevel.Load())
+}
+
+// SetUserLevel overrides the level of one user.
+func SetUserLevel(userID string, level log.Level) {
+	userLevels.Store(userID, level)
+}
+
+// ClearUserLevel removes the override of one user.
+func ClearUserLevel(userID string) {
+	userLevels.Delete(userID)
+}
+
+// UserLevels returns the users with an overridden level.
+func UserLevels() map[string]string {
+	ret := make(map[string]string)
+	userLevels.Range(func(k, v any) bool {
+		ret[k.(string)] = strings.ToLower(v.(log.Level).String())
+		return true
+	})
+	return ret
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (log.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return log.LevelDebug, nil
+	case "info":
+		return log.LevelInfo, nil
+	case "warn":
+		return log.LevelWarn, nil
+	case "error":
+		return log.LevelError, nil
+	}
+	return log.LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// LevelFromSDK maps the numeric level of the openIM sdk (6 and 5 debug, 4 info, 3 warn, lower error).
+func LevelFromSDK(level int) log.Level {
+	switch {
+	case level >= 5:
+		return log.LevelDebug
+	case level == 4:
+		return log.LevelInfo
+	case level == 3:
+		return log.LevelWarn
+	}
+	return log.LevelError
+}
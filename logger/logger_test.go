@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLineCarriesFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, Init(FormatJSON, log.LevelInfo, buf))
+	ctx := WithOperationID(NewSessionContext(context.Background(), "s1", "u1"), "op1")
+	Info(ctx, "receive req", "reqFuncName", "GetFriendList", "err", errors.New("boom"))
+	Debug(ctx, "dropped")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 1)
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &m))
+	assert.Equal(t, "INFO", m["level"])
+	assert.Equal(t, "receive req", m["msg"])
+	assert.Equal(t, "s1", m[KeySessionID])
+	assert.Equal(t, "u1", m[KeyUserID])
+	assert.Equal(t, "op1", m[KeyOperationID])
+	assert.Equal(t, "boom", m["err"])
+	assert.Contains(t, m["caller"], "logger_test.go")
+}
+
+func TestUserLevelOverride(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, Init(FormatText, log.LevelWarn, buf))
+	defer ClearUserLevel("u1")
+	SetUserLevel("u1", log.LevelDebug)
+	Debug(NewSessionContext(context.Background(), "s1", "u1"), "kept")
+	Debug(NewSessionContext(context.Background(), "s2", "u2"), "dropped")
+	log.Infow(KeyUserID, "u1", log.DefaultMessageKey, "global kept")
+	log.Info("global dropped")
+	assert.Contains(t, buf.String(), "logger_test.go")
+	assert.NotContains(t, buf.String(), "logger.go")
+	assert.Contains(t, buf.String(), "kept")
+	assert.Contains(t, buf.String(), "global kept")
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Equal(t, map[string]string{"u1": "debug"}, UserLevels())
+}
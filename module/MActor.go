@@ -6,24 +6,41 @@ import (
 	"errors"
 	"net/url"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/yrzs/openimwssdk/audit"
 	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/record"
 
-	"github.com/go-kratos/kratos/v2/log"
 	"github.com/yrzs/openimwssdk/common"
 	"github.com/yrzs/openimwssdk/gate"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/panics"
+	"github.com/yrzs/openimwssdk/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	WsUserID    = "sendID"
 	OperationID = "operationID"
 	PlatformID  = "platformID"
+	// Events and ConversationIDs are the comma separated url params of the subscription of the session, see
+	// core_func.Subscription.
+	Events          = "events"
+	ConversationIDs = "conversationIDs"
 )
 const ProtocolError = "Protocol Error"
 const DisconnectGCLimit = 100
+const (
+	AuditPendingTimeout = 60 * time.Second
+	AuditNoRespCode     = errcode.Timeout
+)
 
 var disConnectNum atomic.Int64
 
@@ -64,6 +81,34 @@ func (p *ParamStru) GetPlatformID() string {
 	return u.Query().Get(PlatformID)
 }
 
+// GetSubscription parses the URL to get the subscription of the session, nil when the client sent none.
+func (p *ParamStru) GetSubscription() *core_func.Subscription {
+	u, err := url.Parse(p.UrlPath)
+	if err != nil {
+		return nil
+	}
+	q := u.Query()
+	if q.Get(Events) == "" && q.Get(ConversationIDs) == "" {
+		return nil
+	}
+	sub := &core_func.Subscription{ConversationIDs: splitParam(q.Get(ConversationIDs))}
+	for _, c := range splitParam(q.Get(Events)) {
+		sub.Categories = append(sub.Categories, core_func.EventCategory(c))
+	}
+	return sub
+}
+
+// splitParam splits a comma separated url param, skipping the empty items.
+func splitParam(s string) []string {
+	var ret []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
 type ResReleaseStru struct {
 	BackSign chan bool
 }
@@ -83,14 +128,31 @@ type MActorIm struct {
 	heartFlag        bool             //初始为false，收到心跳pack设置为true
 	isclosing        bool
 	isReleasedJscore bool
+	kickChan         chan string //管理端踢下线的通道
+	remoteAddr       string
+	connectTime      time.Time
+	pendingReqs      map[string]*audit.Record //等待响应的请求,用于审计
+	pendingSpans     map[string]trace.Span    //等待响应的请求的trace
+	ctx              context.Context          //携带sessionId和userID的日志上下文
+	recorder         *record.Recorder         //录制中的会话文件
+	slowDown         atomic.Bool              //已通知客户端放慢请求
+	overflows        atomic.Int64             //邮箱满被拒绝的请求数
+	refusedInRow     atomic.Int64             //连续被拒绝的请求数
+	replay           *replayBuffer            //推送事件的序号和重放缓存
 }
 
 // NewMActor creates a new actor instance.
 func NewMActor(a gate.Agent, sessionId string, appParam *ParamStru) (MActor, error) {
-	ret := &MActorIm{param: appParam, a: a, SessionId: sessionId, releaseResChan: make(chan *ResReleaseStru, 1), closeChan: make(chan bool, 1), nChanLen: 10, ReceivMsgChan: make(chan interface{}, 10), isclosing: false,
-		heartTicker: time.NewTicker(100 * time.Second), heartFlag: false, heartTickerSend: time.NewTicker(28 * time.Second), isReleasedJscore: false}
+	ret := &MActorIm{param: appParam, a: a, SessionId: sessionId, releaseResChan: make(chan *ResReleaseStru, 1), closeChan: make(chan bool, 1), nChanLen: MailboxSize, ReceivMsgChan: make(chan interface{}, MailboxSize), isclosing: false,
+		heartTicker: time.NewTicker(100 * time.Second), heartFlag: false, heartTickerSend: time.NewTicker(28 * time.Second), isReleasedJscore: false,
+		kickChan: make(chan string, 1), connectTime: time.Now(), pendingReqs: make(map[string]*audit.Record), pendingSpans: make(map[string]trace.Span),
+		ctx: logger.NewSessionContext(context.Background(), sessionId, appParam.GetUserID()), replay: acquireReplay(appParam.GetUserID())}
+	if addr := a.RemoteAddr(); addr != nil {
+		ret.remoteAddr = addr.String()
+	}
 	///////////////////////////////////////
-	ret.mJsCore = NewJsCore(appParam, sessionId) //todo
+	ret.mJsCore = NewJsCore(ret.ctx, appParam, sessionId, core_func.WithFlowControl(ret.flowControl),
+		core_func.WithEventAck(ret.replay.ack)) //todo
 	///////////////////////////////////////
 	go ret.run()
 	return ret, nil
@@ -99,17 +161,24 @@ func NewMActor(a gate.Agent, sessionId string, appParam *ParamStru) (MActor, err
 // run contains the main loop for the actor, handling various operations.
 func (actor *MActorIm) run() {
 	actor.wg.Add(1)
-	defer common.TryRecoverAndDebugPrint()
 	defer actor.wg.Done()
+	defer actor.replay.release()
+	defer panics.Recover(actor.ctx, "actor", actor.closeOnPanic)
+	actor.replayEvents()
 	for {
 		select {
 		case <-actor.heartTickerSend.C: //send the heart pack
+			actor.flushPendingAudit(AuditPendingTimeout)
+			actor.flushPendingSpans(AuditPendingTimeout)
 			if actor.isclosing == true {
 				continue
 			}
 			actor.sendHeart()
 		case <-actor.closeChan:
-			log.Info("收到退出信号", "sessionId", actor.SessionId)
+			logger.Info(actor.ctx, "收到退出信号")
+			actor.flushPendingAudit(0)
+			actor.flushPendingSpans(0)
+			actor.closeRecorder()
 			if !actor.isReleasedJscore {
 				actor.mJsCore.Destroy()
 			}
@@ -119,7 +188,7 @@ func (actor *MActorIm) run() {
 			}
 			return
 		case resChan := <-actor.releaseResChan:
-			log.Info("收到释放资源通道消息")
+			logger.Info(actor.ctx, "收到释放资源通道消息")
 			actor.mJsCore.Destroy()
 			actor.a.Destroy()
 			actor.isReleasedJscore = true
@@ -130,8 +199,21 @@ func (actor *MActorIm) run() {
 			}
 			data := recvData.(*common.TWSData)
 			_ = actor.doRecvPro(data)
+			actor.resumeFlow()
+		case reason := <-actor.kickChan:
+			if actor.isclosing == true {
+				continue
+			}
+			logger.Info(actor.ctx, "kicked by admin", "reason", reason)
+			actor.sendEventResp(&core_func.EventData{Event: KickedEventName, Data: reason})
+			actor.isclosing = true
+			actor.sendClosingResp()
 		case resp := <-actor.mJsCore.RecvMsg():
-			actor.sendEventResp(resp)
+			if resp.OperationID == "" {
+				actor.replay.push(resp)
+			}
+			actor.auditResp(resp)
+			actor.sendReqResp(resp)
 			if resp.Event == LogoutName {
 				actor.isReleasedJscore = true
 				actor.isclosing = true
@@ -148,17 +230,32 @@ func (actor *MActorIm) run() {
 		}
 	}
 }
+
+// closeOnPanic releases the session whose run loop panicked as on close, and ends the connection with an internal
+// error close frame.
+func (actor *MActorIm) closeOnPanic(any) {
+	actor.isclosing = true
+	actor.flushPendingAudit(0)
+	actor.flushPendingSpans(0)
+	actor.closeRecorder()
+	if !actor.isReleasedJscore {
+		actor.isReleasedJscore = true
+		actor.mJsCore.Destroy()
+	}
+	actor.a.WriteMsg(panics.CloseMessage())
+}
+
 func (actor *MActorIm) ReleaseRes() {
-	log.Info("get ReleaseRes sign")
+	logger.Info(actor.ctx, "get ReleaseRes sign")
 	ind := &ResReleaseStru{BackSign: make(chan bool, 1)}
 	actor.releaseResChan <- ind
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 	select {
 	case <-ctx.Done():
-		log.Info("出现超时返回，actor可能已经被异步destroy")
+		logger.Info(actor.ctx, "出现超时返回，actor可能已经被异步destroy")
 	case <-ind.BackSign:
-		log.Info("通过releaseRes接口回收资源")
+		logger.Info(actor.ctx, "通过releaseRes接口回收资源")
 	}
 
 }
@@ -166,41 +263,173 @@ func (actor *MActorIm) Destroy() {
 	actor.closeChan <- true
 	actor.wg.Wait()
 	actor.a = nil
-	log.Info("退出MQPushActorIm", "sessionId", actor.SessionId)
-}
-
-// ProcessRecvMsg processes received messages and sends them to the ReceivMsgChan.
-func (actor *MActorIm) ProcessRecvMsg(msg interface{}) error {
-	if len(actor.ReceivMsgChan) == actor.nChanLen {
-		log.Error("send channel is full", "sessionId", actor.SessionId)
-		return errors.New("send channel is full")
-	}
-	actor.ReceivMsgChan <- msg
-	return nil
+	logger.Info(actor.ctx, "退出MQPushActorIm")
 }
 
 // doRecvPro processes the message received from the network layer.
 func (actor *MActorIm) doRecvPro(data *common.TWSData) error {
-	log.Info("message come here", "data", data)
+	logger.Debug(actor.ctx, "message come here", "msgType", data.MsgType)
 	if data.MsgType == common.MessageText {
+		actor.recordFrame(data.Msg, true)
+		traceCtx, span := tracing.Start(data.Ctx, "actor.dispatch")
+		defer span.End()
 		req := &Req{}
 		err := json.Unmarshal(data.Msg, req)
 		if err != nil {
-			log.Error("parse protocol err", "err", err, "sessionId", actor.SessionId)
-			actor.sendEventResp(&core_func.EventData{Event: ProtocolError, ErrCode: 20000, ErrMsg: err.Error(),
-				OperationID: req.OperationID})
+			logger.Error(actor.ctx, "parse protocol err", "err", err)
+			tracing.End(trace.SpanFromContext(data.Ctx), err)
+			actor.sendEventResp(errorEvent(ProtocolError, req.OperationID, errcode.New(errcode.BadFrame, err.Error())))
 			return err
 		}
-		log.Info("receive req", "req", req, "sessionId", actor.SessionId)
-		err = actor.mJsCore.SendMsg(req)
+		ctx := logger.WithOperationID(actor.ctx, req.OperationID)
+		logger.Debug(ctx, "receive req", "reqFuncName", req.ReqFuncName)
+		actor.auditReq(req)
+		actor.traceReq(req, trace.SpanFromContext(data.Ctx))
+		span.SetAttributes(tracing.AttrOperationID.String(req.OperationID), tracing.AttrReqFuncName.String(req.ReqFuncName))
+		err = actor.mJsCore.SendMsg(traceCtx, req)
 		if err != nil {
-			actor.sendEventResp(&core_func.EventData{Event: req.ReqFuncName, ErrCode: 20000, ErrMsg: err.Error(),
-				OperationID: req.OperationID})
+			resp := errorEvent(req.ReqFuncName, req.OperationID, errcode.From(err))
+			logger.Warn(ctx, "dispatch req failed", "reqFuncName", req.ReqFuncName, "err", err)
+			actor.auditResp(resp)
+			actor.sendReqResp(resp)
 		}
 	}
 	return nil
 }
 
+// errorEvent returns the failed response of a request.
+func errorEvent(event, operationID string, e *errcode.Error) *core_func.EventData {
+	return &core_func.EventData{Event: event, OperationID: operationID, ErrCode: e.Code, ErrMsg: e.Msg, ErrDetail: e.Detail}
+}
+
+// traceReq keeps the request span open until the response is written.
+func (actor *MActorIm) traceReq(req *Req, span trace.Span) {
+	span.SetAttributes(tracing.AttrOperationID.String(req.OperationID), tracing.AttrReqFuncName.String(req.ReqFuncName))
+	if req.OperationID == "" {
+		span.End()
+		return
+	}
+	if prev, ok := actor.pendingSpans[req.OperationID]; ok {
+		tracing.End(prev, errors.New("operationID reused"))
+	}
+	actor.pendingSpans[req.OperationID] = span
+}
+
+// flushPendingSpans ends the request spans that got no response within timeout, all of them when timeout is 0.
+func (actor *MActorIm) flushPendingSpans(timeout time.Duration) {
+	deadline := time.Now().Add(-timeout)
+	for operationID, span := range actor.pendingSpans {
+		if ro, ok := span.(sdktrace.ReadOnlySpan); timeout == 0 || !ok || ro.StartTime().Before(deadline) {
+			delete(actor.pendingSpans, operationID)
+			tracing.End(span, errors.New("no response"))
+		}
+	}
+}
+
+// sendReqResp writes the response of a request under a span of its trace, then ends the request span.
+func (actor *MActorIm) sendReqResp(resp *core_func.EventData) {
+	span, ok := actor.pendingSpans[resp.OperationID]
+	if !ok || resp.OperationID == "" {
+		actor.sendEventResp(resp)
+		return
+	}
+	delete(actor.pendingSpans, resp.OperationID)
+	_, writeSpan := tracing.Start(trace.ContextWithSpan(context.Background(), span), "ws.response.write")
+	actor.sendEventResp(resp)
+	writeSpan.End()
+	span.SetAttributes(tracing.AttrErrCode.Int(int(resp.ErrCode)))
+	if resp.ErrCode != 0 || resp.ErrMsg != "" {
+		span.SetStatus(codes.Error, resp.ErrMsg)
+	}
+	span.End()
+}
+
+// auditReq remembers the request until its response arrives, so the audit record carries the latency.
+func (actor *MActorIm) auditReq(req *Req) {
+	if !audit.Enabled() || req.OperationID == "" {
+		return
+	}
+	if prev, ok := actor.pendingReqs[req.OperationID]; ok {
+		actor.writeAudit(prev, AuditNoRespCode, "operationID reused")
+	}
+	actor.pendingReqs[req.OperationID] = &audit.Record{Time: time.Now().UnixMilli(), UserID: actor.param.GetUserID(),
+		SessionId: actor.SessionId, PlatformID: actor.param.GetPlatformID(), ReqFuncName: req.ReqFuncName,
+		OperationID: req.OperationID, Args: audit.CaptureArgs(req.Data)}
+}
+
+// auditResp completes the pending record of the request the response answers.
+func (actor *MActorIm) auditResp(resp *core_func.EventData) {
+	if resp.OperationID == "" {
+		return
+	}
+	rec, ok := actor.pendingReqs[resp.OperationID]
+	if !ok {
+		return
+	}
+	delete(actor.pendingReqs, resp.OperationID)
+	actor.writeAudit(rec, resp.ErrCode, resp.ErrMsg)
+}
+
+// flushPendingAudit records the requests that got no response within timeout, all of them when timeout is 0.
+func (actor *MActorIm) flushPendingAudit(timeout time.Duration) {
+	deadline := time.Now().Add(-timeout).UnixMilli()
+	for operationID, rec := range actor.pendingReqs {
+		if timeout == 0 || rec.Time < deadline {
+			delete(actor.pendingReqs, operationID)
+			actor.writeAudit(rec, AuditNoRespCode, "no response")
+		}
+	}
+}
+
+func (actor *MActorIm) writeAudit(rec *audit.Record, errCode int32, errMsg string) {
+	rec.ErrCode = errCode
+	rec.ErrMsg = errMsg
+	rec.LatencyMs = time.Now().UnixMilli() - rec.Time
+	audit.Log(rec)
+}
+
+// recordFrame writes a frame to the recording of the session while its user is recorded.
+func (actor *MActorIm) recordFrame(msg []byte, in bool) {
+	if !record.Enabled() {
+		return
+	}
+	userID := actor.param.GetUserID()
+	if !record.Recording(userID) {
+		actor.closeRecorder()
+		return
+	}
+	if actor.recorder == nil {
+		r, err := record.Open(userID, actor.param.GetPlatformID(), actor.SessionId, actor.param.GetOperationID())
+		if err != nil {
+			logger.Error(actor.ctx, "open recording failed, recording stopped", "err", err)
+			record.Stop(userID)
+			return
+		}
+		logger.Info(actor.ctx, "recording started", "file", r.Name())
+		actor.recorder = r
+	}
+	var err error
+	if in {
+		err = actor.recorder.In(msg)
+	} else {
+		err = actor.recorder.Out(msg)
+	}
+	if err != nil {
+		logger.Warn(actor.ctx, "record frame failed", "err", err)
+	}
+}
+
+func (actor *MActorIm) closeRecorder() {
+	if actor.recorder == nil {
+		return
+	}
+	if err := actor.recorder.Close(); err != nil {
+		logger.Warn(actor.ctx, "close recording failed", "err", err)
+	}
+	logger.Info(actor.ctx, "recording stopped", "file", actor.recorder.Name())
+	actor.recorder = nil
+}
+
 // sendResp sends a response message to the WebSocket client.
 func (actor *MActorIm) sendHeart() {
 	//heart := []byte("ping")
@@ -210,7 +439,11 @@ func (actor *MActorIm) sendHeart() {
 
 // sendEventResp sends an event response to the WebSocket client.
 func (actor *MActorIm) sendEventResp(res *core_func.EventData) {
+	if res.OperationID != "" || res.ErrCode != 0 {
+		GStatusHub.CountResp(res.ErrCode != 0 || res.ErrMsg != "")
+	}
 	resb, _ := json.Marshal(res)
+	actor.recordFrame(resb, false)
 	resSend := &common.TWSData{MsgType: common.MessageText, Msg: resb}
 	actor.a.WriteMsg(resSend)
 }
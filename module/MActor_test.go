@@ -0,0 +1,117 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testAgent records the messages written to the client.
+type testAgent struct {
+	msgs []*common.TWSData
+}
+
+func (a *testAgent) WriteMsg(msg interface{})     { a.msgs = append(a.msgs, msg.(*common.TWSData)) }
+func (a *testAgent) LocalAddr() net.Addr          { return nil }
+func (a *testAgent) RemoteAddr() net.Addr         { return nil }
+func (a *testAgent) Close()                       {}
+func (a *testAgent) Destroy()                     {}
+func (a *testAgent) UserData() interface{}        { return nil }
+func (a *testAgent) SetUserData(data interface{}) {}
+
+func TestRequestSpanEndsOnResponse(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	agent := &testAgent{}
+	actor := &MActorIm{a: agent, pendingSpans: make(map[string]trace.Span)}
+	_, root := tracing.Start(context.Background(), "ws.request")
+	actor.traceReq(&Req{ReqFuncName: "GetSelfUserInfo", OperationID: "op1"}, root)
+	actor.sendReqResp(&core_func.EventData{Event: "Other", OperationID: "op2"})
+	assert.Len(t, recorder.Ended(), 0)
+
+	actor.sendReqResp(&core_func.EventData{Event: "GetSelfUserInfo", OperationID: "op1", ErrCode: 10001, ErrMsg: "failed"})
+	assert.Len(t, agent.msgs, 2)
+	assert.Empty(t, actor.pendingSpans)
+	ended := recorder.Ended()
+	if assert.Len(t, ended, 2) {
+		assert.Equal(t, "ws.response.write", ended[0].Name())
+		assert.Equal(t, ended[1].SpanContext().SpanID(), ended[0].Parent().SpanID())
+		assert.Equal(t, "ws.request", ended[1].Name())
+		assert.Equal(t, codes.Error, ended[1].Status().Code)
+	}
+}
+
+func TestFlushPendingSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	actor := &MActorIm{pendingSpans: make(map[string]trace.Span)}
+	_, root := tracing.Start(context.Background(), "ws.request")
+	actor.traceReq(&Req{OperationID: "op1"}, root)
+	actor.flushPendingSpans(AuditPendingTimeout)
+	assert.Len(t, actor.pendingSpans, 1)
+	actor.flushPendingSpans(0)
+	assert.Empty(t, actor.pendingSpans)
+	if assert.Len(t, recorder.Ended(), 1) {
+		assert.Equal(t, "no response", recorder.Ended()[0].Status().Description)
+	}
+}
+
+func TestMailboxFlowControl(t *testing.T) {
+	prev := MailboxOverflowLimit
+	MailboxOverflowLimit = 2
+	defer func() { MailboxOverflowLimit = prev }()
+	agent := &testAgent{}
+	actor := &MActorIm{a: agent, nChanLen: 4, ReceivMsgChan: make(chan interface{}, 4), ctx: context.Background()}
+	frame := func() *common.TWSData {
+		return &common.TWSData{MsgType: common.MessageText, Msg: []byte(`{"reqFuncName":"GetSelfUserInfo","operationID":"op1","data":"[]"}`)}
+	}
+	event := func(i int) *core_func.EventData {
+		ev := &core_func.EventData{}
+		assert.NoError(t, json.Unmarshal(agent.msgs[i].Msg, ev))
+		return ev
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, actor.ProcessRecvMsg(frame()))
+	}
+	if assert.Len(t, agent.msgs, 1) {
+		assert.Equal(t, FlowControlEventName, event(0).Event)
+		assert.JSONEq(t, `{"size":4,"credit":1,"slowDown":true,"overflows":0}`, event(0).Data)
+	}
+	assert.NoError(t, actor.ProcessRecvMsg(frame()))
+	assert.NoError(t, actor.ProcessRecvMsg(frame()))
+	assert.NoError(t, actor.ProcessRecvMsg(frame()))
+	if assert.Len(t, agent.msgs, 3) {
+		assert.Equal(t, "GetSelfUserInfo", event(1).Event)
+		assert.Equal(t, "op1", event(1).OperationID)
+		assert.Equal(t, errcode.Overloaded, event(1).ErrCode)
+	}
+	assert.ErrorIs(t, actor.ProcessRecvMsg(frame()), errMailboxOverflow)
+
+	<-actor.ReceivMsgChan
+	actor.resumeFlow()
+	assert.Len(t, agent.msgs, 3)
+	<-actor.ReceivMsgChan
+	actor.resumeFlow()
+	if assert.Len(t, agent.msgs, 4) {
+		assert.JSONEq(t, `{"size":4,"credit":2,"slowDown":false,"overflows":3}`, event(3).Data)
+	}
+}
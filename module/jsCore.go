@@ -1,13 +1,17 @@
 package module
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"reflect"
-
-	"github.com/yrzs/openimsdkcore/pkg/utils"
+	"strings"
+	"sync"
 
 	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/tracing"
 )
 
 const (
@@ -18,6 +22,8 @@ const (
 type JsCore struct {
 	RespMessagesChan chan *core_func.EventData
 	funcRouter       *core_func.FuncRouter
+	ctx              context.Context
+	traceCtxs        sync.Map // operationID -> context.Context of the request span, while it is dispatched
 }
 
 type Req struct {
@@ -33,13 +39,53 @@ type JsInterface interface {
 	Destroy()
 }
 
-// NewJsCore creates a new JsCore instance.
-func NewJsCore(para *ParamStru, sessionId string) *JsCore {
-	respChan := make(chan *core_func.EventData, 100)
-	funcRouter := core_func.NewFuncRouter(respChan, sessionId)
-	fmt.Println("NewJsCore", "data=", "sessionId", sessionId)
+var (
+	reqFuncNamesOnce sync.Once
+	reqFuncNames     map[string]bool
+)
+
+// isReqFuncName reports whether a request may call the method: the FuncRouter methods with the request signature
+// but the plumbing ones, see core_func.ReqFuncNames. The others are set up by the gateway only.
+func isReqFuncName(name string) bool {
+	reqFuncNamesOnce.Do(func() {
+		reqFuncNames = make(map[string]bool)
+		for _, n := range core_func.ReqFuncNames() {
+			reqFuncNames[n] = true
+		}
+	})
+	return reqFuncNames[name]
+}
+
+// NewJsCore creates a new JsCore instance, ctx carries the log fields of the session and opts are applied to its
+// FuncRouter after those of the session.
+func NewJsCore(ctx context.Context, para *ParamStru, sessionId string, opts ...core_func.RouterOption) *JsCore {
+	core := &JsCore{RespMessagesChan: make(chan *core_func.EventData, 100), ctx: ctx}
+	routerOpts := []core_func.RouterOption{core_func.WithLogContext(ctx), core_func.WithTraceContext(core.traceContext)}
+	if dir, err := datadir.UserDir(para.GetUserID()); err != nil {
+		logger.Error(ctx, "datadir of user error, the shared one is used", "err", err)
+	} else {
+		routerOpts = append(routerOpts, core_func.WithDataDir(dir))
+	}
+	if sub := para.GetSubscription(); sub != nil {
+		if err := sub.Validate(); err != nil {
+			logger.Warn(ctx, "subscription of the url ignored, every event is sent", "err", err)
+		} else {
+			routerOpts = append(routerOpts, core_func.WithSubscription(sub))
+		}
+	}
+	funcRouter := core_func.NewFuncRouter(core.RespMessagesChan, sessionId, append(routerOpts, opts...)...)
+	core.funcRouter = funcRouter
+	logger.Debug(ctx, "NewJsCore", "platformID", para.GetPlatformID())
 	funcRouter.InitSDK(para.GetOperationID(), para.GetPlatformID())
-	return &JsCore{RespMessagesChan: respChan, funcRouter: funcRouter}
+	return core
+}
+
+// traceContext returns the span context of the request of the operationID, nil once it is dispatched.
+func (core *JsCore) traceContext(operationID string) context.Context {
+	if ctx, ok := core.traceCtxs.Load(operationID); ok {
+		return ctx.(context.Context)
+	}
+	return nil
 }
 
 // RecvMsg returns the channel to receive messages.
@@ -47,28 +93,35 @@ func (core *JsCore) RecvMsg() chan *core_func.EventData {
 	return core.RespMessagesChan
 }
 
-// SendMsg processes the incoming request and calls the corresponding method.
-func (core *JsCore) SendMsg(req *Req) error {
-	fmt.Println("method is valid", "data=", req)
-	methodValue := reflect.ValueOf(core.funcRouter).MethodByName(req.ReqFuncName)
+// SendMsg processes the incoming request and calls the corresponding method, ctx carries the span of the request.
+func (core *JsCore) SendMsg(ctx context.Context, req *Req) (err error) {
+	ctx, span := tracing.Start(ctx, "JsCore.SendMsg")
+	defer func() { tracing.End(span, err) }()
+	var methodValue reflect.Value
+	if isReqFuncName(req.ReqFuncName) {
+		methodValue = reflect.ValueOf(core.funcRouter).MethodByName(req.ReqFuncName)
+	}
 	if !methodValue.IsValid() {
-		//log.ZWarn(context.Background(), "method is valid", errors.New("method is valid"), "data", req)
-		fmt.Println("method is valid", "data=", req)
-		return utils.Wrap(fmt.Errorf("method is valid"), "method is valid")
+		logger.Warn(logger.WithOperationID(core.ctx, req.OperationID), "method is not valid", "reqFuncName", req.ReqFuncName)
+		return errcode.Newf(errcode.UnknownMethod, "method %q is not valid", req.ReqFuncName)
 	}
 	var args []any
-	if err := json.Unmarshal([]byte(req.Data), &args); err != nil {
-		return utils.Wrap(err, "json.Unmarshal failed")
+	dec := json.NewDecoder(strings.NewReader(req.Data))
+	dec.UseNumber() // numbers stay exact until they are converted into the params of the sdk function
+	if err := dec.Decode(&args); err != nil {
+		return errcode.Newf(errcode.BadData, "data is not a JSON array: %s", err)
 	}
 	// Convert args to []reflect.Value
 	args = append([]any{req.OperationID}, args...)
 	argsValue := make([]reflect.Value, len(args))
 	for i, arg := range args {
 		if arg == nil {
-			return utils.Wrap(fmt.Errorf("args[%d] is not nil", i), "args has nil")
+			return errcode.Newf(errcode.BadArg, "args[%d] is null", i-1).WithArg(i-1, "").WithReason(errcode.ReasonNull)
 		}
 		argsValue[i] = reflect.ValueOf(arg)
 	}
+	core.traceCtxs.Store(req.OperationID, ctx)
+	defer core.traceCtxs.Delete(req.OperationID)
 	methodValue.Call(argsValue)
 	return nil
 }
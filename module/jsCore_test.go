@@ -0,0 +1,31 @@
+package module
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/errcode"
+)
+
+func TestSetDataDirRejected(t *testing.T) {
+	ctx := context.Background()
+	core := &JsCore{RespMessagesChan: make(chan *core_func.EventData, 10), ctx: ctx}
+	core.funcRouter = core_func.NewFuncRouter(core.RespMessagesChan, "s1", core_func.WithDataDir(t.TempDir()))
+
+	err := core.SendMsg(ctx, &Req{ReqFuncName: "SetDataDir", OperationID: "op1", Data: `["../../other"]`})
+	assert.Equal(t, errcode.UnknownMethod, errcode.From(err).Code)
+}
+
+func TestPlumbingMethodsRejected(t *testing.T) {
+	ctx := context.Background()
+	core := &JsCore{RespMessagesChan: make(chan *core_func.EventData, 10), ctx: ctx}
+	core.funcRouter = core_func.NewFuncRouter(core.RespMessagesChan, "s1")
+
+	for _, name := range []string{"InitSDK", "UnInitSDK", "GetLoginUserID"} {
+		err := core.SendMsg(ctx, &Req{ReqFuncName: name, OperationID: "op1", Data: `["5"]`})
+		assert.Equal(t, errcode.UnknownMethod, errcode.From(err).Code, name)
+	}
+	assert.Empty(t, core.RespMessagesChan)
+}
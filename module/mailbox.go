@@ -0,0 +1,129 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultMailboxSize          = 64
+	DefaultMailboxOverflowLimit = 32
+	// FlowControlEventName carries a core_func.FlowControl: slowDown true when the mailbox of the session is nearly
+	// full, false once it has drained to half.
+	FlowControlEventName = "OnFlowControl"
+)
+
+var (
+	// MailboxSize is the number of frames a user session queues before it refuses requests with errcode.Overloaded.
+	MailboxSize = DefaultMailboxSize
+	// MailboxOverflowLimit is the number of requests refused in a row before the session is closed.
+	MailboxOverflowLimit = DefaultMailboxOverflowLimit
+)
+
+var errMailboxOverflow = errors.New("mailbox overflow")
+
+// MailboxStats are the mailbox overflows of all the user sessions since the start.
+type MailboxStats struct {
+	Size      int   `json:"size"`
+	SlowDowns int64 `json:"slowDowns"` // slowDown events sent
+	Overflows int64 `json:"overflows"` // requests refused because the mailbox was full
+	Closes    int64 `json:"closes"`    // sessions closed after MailboxOverflowLimit refusals in a row
+}
+
+var mailboxSlowDowns, mailboxOverflows, mailboxCloses atomic.Int64
+
+// ReadMailboxStats returns the mailbox overflows so far.
+func ReadMailboxStats() *MailboxStats {
+	return &MailboxStats{Size: MailboxSize, SlowDowns: mailboxSlowDowns.Load(), Overflows: mailboxOverflows.Load(),
+		Closes: mailboxCloses.Load()}
+}
+
+// mailboxCloseMessage is the close frame of a session that kept overflowing its mailbox.
+var mailboxCloseMessage = &common.TWSData{MsgType: common.CloseMessage, Msg: websocket.FormatCloseMessage(
+	websocket.CloseTryAgainLater, strconv.Itoa(int(errcode.Overloaded))+" mailbox overflow")}
+
+// ProcessRecvMsg queues a frame in the mailbox, called from the read loop of the connection. The client is asked to
+// slow down when a quarter of the mailbox is left; a request arriving with the mailbox full is answered with
+// errcode.Overloaded, and the error closes the session once MailboxOverflowLimit requests were refused in a row.
+func (actor *MActorIm) ProcessRecvMsg(msg interface{}) error {
+	select {
+	case actor.ReceivMsgChan <- msg:
+		actor.refusedInRow.Store(0)
+		if actor.credit() <= actor.nChanLen/4 && actor.slowDown.CompareAndSwap(false, true) {
+			mailboxSlowDowns.Add(1)
+			actor.writeEvent(actor.flowControlEvent())
+		}
+		return nil
+	default:
+	}
+	mailboxOverflows.Add(1)
+	actor.overflows.Add(1)
+	if actor.refusedInRow.Add(1) > int64(MailboxOverflowLimit) {
+		logger.Warn(actor.ctx, "mailbox overflow, closing", "mailboxSize", actor.nChanLen)
+		mailboxCloses.Add(1)
+		return errMailboxOverflow
+	}
+	if actor.slowDown.CompareAndSwap(false, true) {
+		mailboxSlowDowns.Add(1)
+		actor.writeEvent(actor.flowControlEvent())
+	}
+	actor.refuse(msg)
+	return nil
+}
+
+// refuse answers a request that did not fit in the mailbox, other frames are dropped.
+func (actor *MActorIm) refuse(msg interface{}) {
+	data, ok := msg.(*common.TWSData)
+	if !ok || data.MsgType != common.MessageText {
+		return
+	}
+	tracing.End(trace.SpanFromContext(data.Ctx), errMailboxOverflow)
+	req := &Req{}
+	if err := json.Unmarshal(data.Msg, req); err != nil {
+		return
+	}
+	logger.Debug(logger.WithOperationID(actor.ctx, req.OperationID), "mailbox full", "reqFuncName", req.ReqFuncName)
+	GStatusHub.CountResp(true)
+	actor.writeEvent(errorEvent(req.ReqFuncName, req.OperationID,
+		errcode.Newf(errcode.Overloaded, "mailbox of %d requests is full, slow down", actor.nChanLen)))
+}
+
+// resumeFlow tells the client it may speed up again once the mailbox has drained to half, called from the run
+// loop after a frame is served.
+func (actor *MActorIm) resumeFlow() {
+	if actor.credit() >= actor.nChanLen/2 && actor.slowDown.CompareAndSwap(true, false) {
+		actor.sendEventResp(actor.flowControlEvent())
+	}
+}
+
+// credit is the number of frames the mailbox can still take.
+func (actor *MActorIm) credit() int {
+	return actor.nChanLen - len(actor.ReceivMsgChan)
+}
+
+// flowControl reads the mailbox for GetFlowControl.
+func (actor *MActorIm) flowControl() *core_func.FlowControl {
+	return &core_func.FlowControl{Size: actor.nChanLen, Credit: actor.credit(), SlowDown: actor.slowDown.Load(),
+		Overflows: actor.overflows.Load()}
+}
+
+func (actor *MActorIm) flowControlEvent() *core_func.EventData {
+	data, _ := json.Marshal(actor.flowControl())
+	return &core_func.EventData{Event: FlowControlEventName, Data: string(data)}
+}
+
+// writeEvent writes an event from the read loop, the session recording is left to the run loop.
+func (actor *MActorIm) writeEvent(ev *core_func.EventData) {
+	b, _ := json.Marshal(ev)
+	actor.a.WriteMsg(&common.TWSData{MsgType: common.MessageText, Msg: b})
+}
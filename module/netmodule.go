@@ -1,11 +1,16 @@
 package module
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"github.com/go-kratos/kratos/v2/log"
 	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/errcode"
 	"github.com/yrzs/openimwssdk/gate"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"net/url"
 	"sync"
 )
@@ -17,6 +22,10 @@ type JsActorMap struct {
 
 var GJsActors *JsActorMap
 
+// ReservedUser reports whether the gateway itself runs a session as the user, e.g. a bot account; the connections
+// of such a user are refused, their sdk would share the data dir of that session. It is set from main.
+var ReservedUser func(userID string) bool
+
 func init() {
 	GJsActors = &JsActorMap{uActors: make(map[string]MActor)}
 }
@@ -32,27 +41,25 @@ type MActor interface {
 // NewAgent is called when a new WebSocket connection is established. It initializes agent-related data and checks the token validity.
 func NewAgent(a gate.Agent) {
 	aUerData := a.UserData().(*common.TAgentUserData)
-	log.Info("one ws connect", "sessionId", aUerData.SessionID)
-	param, err := checkToken(aUerData)
+	ctx := logger.NewSessionContext(context.Background(), aUerData.SessionID, "")
+	logger.Info(ctx, "one ws connect", "remoteAddr", a.RemoteAddr())
+	param, err := checkToken(ctx, aUerData)
 	if err != nil {
-		log.Error("Token validation failed", "userData", aUerData, "sessionId", aUerData.SessionID)
-
-		res := &ResponseSt{Type: RESP_OP_TYPE, Cmd: CONN_CMD, Success: false, ErrMsg: "check token error"}
-		resb, _ := json.Marshal(res)
-		resSend := &common.TWSData{MsgType: common.MessageText, Msg: resb}
-		a.WriteMsg(resSend)
-		a.Close()
+		logger.Error(ctx, "Token validation failed", "err", err)
+		rejectAgent(a, aUerData, errcode.From(err))
+		return
+	}
+	ctx = logger.NewSessionContext(ctx, aUerData.SessionID, param.GetUserID())
+	if ReservedUser != nil && ReservedUser(param.GetUserID()) {
+		logger.Warn(ctx, "connection of a user the gateway runs a session as")
+		rejectAgent(a, aUerData, errcode.New(errcode.InvalidUserID, "userID is reserved by the gateway"))
 		return
 	}
-	log.Info("checkToken info", "param", param, "err", err)
+	logger.Debug(ctx, "checkToken info", "platformID", param.GetPlatformID())
 	actor, err := NewMActor(a, param.SessionId, param)
 	if err != nil {
-		log.Error("NewMQActor error", "err", err, "sessionId", aUerData.SessionID)
-		res := &ResponseSt{Type: RESP_OP_TYPE, Cmd: CONN_CMD, Success: false, ErrMsg: "NewMQActor error"}
-		resb, _ := json.Marshal(res)
-		resSend := &common.TWSData{MsgType: common.MessageText, Msg: resb}
-		a.WriteMsg(resSend)
-		a.Close()
+		logger.Error(ctx, "NewMQActor error", "err", err)
+		rejectAgent(a, aUerData, errcode.New(errcode.SessionFailed, "NewMQActor error"))
 		return
 	}
 	GJsActors.Lock()
@@ -65,39 +72,75 @@ func NewAgent(a gate.Agent) {
 	aUerData.ProxyBody = actor
 	aUerData.UserId = param.GetUserID()
 	a.SetUserData(aUerData)
-	log.Info("one linked", "param", param, "sessionId", aUerData.SessionID)
+	claimSession(ctx, actor.(UserSession).Info())
+	GStatusHub.PublishConnEvent(CONN_EVENT_CONNECT, param.GetUserID(), aUerData.SessionID, param.GetPlatformID())
+	logger.Info(ctx, "one linked", "platformID", param.GetPlatformID())
+}
+
+// rejectAgent answers the connect with a ConnectRejectedEventName event carrying the operationID of the url, then
+// closes the connection.
+func rejectAgent(a gate.Agent, data *common.TAgentUserData, e *errcode.Error) {
+	var operationID string
+	if u, err := url.Parse(data.AppString); err == nil {
+		operationID = u.Query().Get(OperationID)
+	}
+	resb, _ := json.Marshal(errorEvent(ConnectRejectedEventName, operationID, e))
+	a.WriteMsg(&common.TWSData{MsgType: common.MessageText, Msg: resb})
+	a.Close()
 }
 
 // CloseAgent is called when the WebSocket connection is closed. It performs cleanup actions for the agent.
 func CloseAgent(a gate.Agent) {
 	aUerData := a.UserData().(*common.TAgentUserData)
-	if aUerData.ProxyBody != nil {
-		aUerData.ProxyBody.(MActor).Destroy()
-		aUerData.ProxyBody = nil
+	ctx := logger.NewSessionContext(context.Background(), aUerData.SessionID, aUerData.UserId)
+	if aUerData.ProxyBody == nil {
+		logger.Info(ctx, "one dislinkder")
+		return
 	}
+	actor := aUerData.ProxyBody.(MActor)
+	actor.Destroy()
+	aUerData.ProxyBody = nil
 	GJsActors.Lock()
-	_, ok := GJsActors.uActors[aUerData.UserId]
-	if ok {
+	v, ok := GJsActors.uActors[aUerData.UserId]
+	if ok && v == actor {
 		delete(GJsActors.uActors, aUerData.UserId)
 	}
 	GJsActors.Unlock()
-	log.Info("one dislinkder", "sessionId", a.UserData().(*common.TAgentUserData).SessionID)
+	releaseSession(ctx, aUerData.UserId, aUerData.SessionID)
+	datadir.Touch(aUerData.UserId)
+	GStatusHub.PublishConnEvent(CONN_EVENT_DISCONNECT, aUerData.UserId, aUerData.SessionID, "")
+	logger.Info(ctx, "one dislinkder")
 }
 
 // DataRecv is called when new data is received on the WebSocket connection. It processes the incoming data through the actor.
+// A text frame starts the trace of its request, ended by the actor once the response is written.
 func DataRecv(data interface{}, a gate.Agent) {
 	aUerData := a.UserData().(*common.TAgentUserData)
 	if aUerData.ProxyBody != nil {
+		var frameSpan trace.Span
+		if wsData, ok := data.(*common.TWSData); ok && wsData.MsgType == common.MessageText {
+			wsData.Ctx, _ = tracing.Start(context.Background(), "ws.request", trace.WithAttributes(
+				tracing.AttrSessionID.String(aUerData.SessionID), tracing.AttrUserID.String(aUerData.UserId)))
+			_, frameSpan = tracing.Start(wsData.Ctx, "ws.frame.receive")
+		}
 		err := aUerData.ProxyBody.(MActor).ProcessRecvMsg(data)
-		if err != nil {
-			log.Error("Overflow error", "sessionId", aUerData.SessionID)
+		if frameSpan != nil {
+			tracing.End(frameSpan, err)
+			if err != nil {
+				tracing.End(trace.SpanFromContext(data.(*common.TWSData).Ctx), err)
+			}
+		}
+		if errors.Is(err, errMailboxOverflow) {
+			a.WriteMsg(mailboxCloseMessage) // the connection closes once the frames before are written
+		} else if err != nil {
+			logger.Error(logger.NewSessionContext(context.Background(), aUerData.SessionID, aUerData.UserId), "Overflow error")
 			a.Destroy()
 		}
 	}
 }
 
 // checkToken validates the session token contained in the user data.
-func checkToken(data *common.TAgentUserData) (*ParamStru, error) {
+func checkToken(ctx context.Context, data *common.TAgentUserData) (*ParamStru, error) {
 	ret := new(ParamStru)
 	ret.SessionId = data.SessionID
 	var token string
@@ -107,24 +150,24 @@ func checkToken(data *common.TAgentUserData) (*ParamStru, error) {
 		/////////////////////
 		u, err := url.Parse(data.AppString)
 		if err != nil {
-			log.Error("ws url path not correct", "sessionId", data.SessionID)
-			return nil, errors.New("ws url path not correct")
+			logger.Error(ctx, "ws url path not correct", "err", err)
+			return nil, errcode.New(errcode.BadURL, "ws url path not correct")
 		}
 		q := u.Query()
 		token = q.Get("token")
 		//////////////////////
 	}
 	if token == "" {
-		log.Error("Token retrieval is empty", "sessionId", data.SessionID)
-		return nil, errors.New("Token retrieval is empty")
+		logger.Error(ctx, "Token retrieval is empty")
+		return nil, errcode.New(errcode.TokenMissing, "Token retrieval is empty")
 	}
 	// TODO: Add your token validation logic here to verify the legitimacy of the token
 	//ret.UserId=""
 	ret.UrlPath = data.AppString
 	ret.Token = token
 	if ret.GetUserID() == "" {
-		log.Error("userId is empty!")
-		return nil, errors.New("userId is empty")
+		logger.Error(ctx, "userId is empty!")
+		return nil, errcode.New(errcode.InvalidUserID, "userId is empty")
 	}
 	return ret, nil
 }
@@ -0,0 +1,133 @@
+package module
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/fakeim"
+)
+
+// wsAgent is a client connection whose text responses are decoded into events.
+type wsAgent struct {
+	mu       sync.Mutex
+	userData interface{}
+	events   chan *core_func.EventData
+}
+
+func (a *wsAgent) WriteMsg(msg interface{}) {
+	data := msg.(*common.TWSData)
+	if data.MsgType != common.MessageText {
+		return
+	}
+	ev := &core_func.EventData{}
+	if json.Unmarshal(data.Msg, ev) == nil {
+		a.events <- ev
+	}
+}
+func (a *wsAgent) LocalAddr() net.Addr   { return nil }
+func (a *wsAgent) RemoteAddr() net.Addr  { return nil }
+func (a *wsAgent) Close()                {}
+func (a *wsAgent) Destroy()              {}
+func (a *wsAgent) UserData() interface{} { a.mu.Lock(); defer a.mu.Unlock(); return a.userData }
+func (a *wsAgent) SetUserData(data interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.userData = data
+}
+
+// call sends a request frame the way the js sdk does.
+func (a *wsAgent) call(reqFuncName, operationID, data string) {
+	msg, _ := json.Marshal(&Req{ReqFuncName: reqFuncName, OperationID: operationID, Data: data})
+	DataRecv(&common.TWSData{MsgType: common.MessageText, Msg: msg}, a)
+}
+
+// wait skips the other events until the named one.
+func (a *wsAgent) wait(t *testing.T, event string) *core_func.EventData {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case ev := <-a.events:
+			if ev.Event == event {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("event %s not received", event)
+			return nil
+		}
+	}
+}
+
+func TestSessionAgainstFakeIM(t *testing.T) {
+	fake := fakeim.NewServer()
+	defer fake.Close()
+	token := fake.AddUser("u1", "Alice")
+	fake.AddUser("u2", "Bob")
+	fake.AddFriend("u1", "u2")
+	core_func.Config.ApiAddr = fake.ApiAddr
+	core_func.Config.WsAddr = fake.WsAddr
+	datadir.Init(datadir.Config{Root: t.TempDir(), PerUser: true})
+	defer datadir.Init(datadir.Config{})
+
+	agent := &wsAgent{events: make(chan *core_func.EventData, 1000), userData: &common.TAgentUserData{SessionID: "s1",
+		AppString: "/?sendID=u1&token=" + token + "&platformID=5&operationID=op0"}}
+	NewAgent(agent)
+	assert.True(t, GJsActors.Online("u1"))
+	agent.wait(t, "InitSDK")
+
+	agent.call("Login", "op1", `["u1","`+token+`"]`)
+	ev := agent.wait(t, "Login")
+	assert.Equal(t, int32(0), ev.ErrCode, ev.ErrMsg)
+	assert.Equal(t, "op1", ev.OperationID)
+	agent.wait(t, "OnSyncServerFinish")
+	assert.True(t, fake.WaitIdle(200*time.Millisecond, 5*time.Second))
+
+	agent.call("GetSelfUserInfo", "op2", `[]`)
+	ev = agent.wait(t, "GetSelfUserInfo")
+	assert.Contains(t, ev.Data, `"nickname":"Alice"`)
+	agent.call("GetFriendList", "op3", `[]`)
+	ev = agent.wait(t, "GetFriendList")
+	assert.Contains(t, ev.Data, `"nickname":"Bob"`)
+
+	agent.call("NoSuchMethod", "op4", `[]`)
+	ev = agent.wait(t, "NoSuchMethod")
+	assert.Equal(t, errcode.UnknownMethod, ev.ErrCode)
+	assert.Equal(t, errcode.CategoryProtocol, ev.ErrDetail.Category)
+	agent.call("GetUsersInfo", "op5", `[42]`)
+	ev = agent.wait(t, "GetUsersInfo")
+	assert.Equal(t, errcode.BadArg, ev.ErrCode)
+	assert.Equal(t, errcode.ReasonType, ev.ErrDetail.Reason)
+
+	CloseAgent(agent)
+	assert.False(t, GJsActors.Online("u1"))
+}
+
+func TestAgentRejected(t *testing.T) {
+	agent := &wsAgent{events: make(chan *core_func.EventData, 10), userData: &common.TAgentUserData{SessionID: "s2",
+		AppString: "/?sendID=u1&platformID=5&operationID=op0"}}
+	NewAgent(agent)
+	ev := agent.wait(t, ConnectRejectedEventName)
+	assert.Equal(t, "op0", ev.OperationID)
+	assert.Equal(t, errcode.TokenMissing, ev.ErrCode)
+	assert.Equal(t, errcode.CategoryAuth, ev.ErrDetail.Category)
+	assert.False(t, GJsActors.Online("u1"))
+}
+
+func TestReservedUserRejected(t *testing.T) {
+	ReservedUser = func(userID string) bool { return userID == "bot1" }
+	defer func() { ReservedUser = nil }()
+	agent := &wsAgent{events: make(chan *core_func.EventData, 10), userData: &common.TAgentUserData{SessionID: "s3",
+		AppString: "/?sendID=bot1&token=t&platformID=5&operationID=op0"}}
+	NewAgent(agent)
+	ev := agent.wait(t, ConnectRejectedEventName)
+	assert.Equal(t, errcode.InvalidUserID, ev.ErrCode)
+	assert.False(t, GJsActors.Online("bot1"))
+}
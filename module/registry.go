@@ -0,0 +1,146 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/registry"
+)
+
+// RegistryTimeout bounds one call to GRegistry.
+const RegistryTimeout = 3 * time.Second
+
+// node is the registry of this node and the name of the node in it.
+type node struct {
+	reg registry.Registry
+	id  string
+}
+
+// current is set by UseRegistry and read by the commands, which run on the goroutines of the registry.
+var current atomic.Pointer[node]
+
+func init() {
+	if err := UseRegistry(registry.NewMemory(), DefaultNodeID()); err != nil {
+		panic(err)
+	}
+}
+
+// DefaultNodeID is the host name with a random suffix, a restarted node does not take over the entries of its
+// previous run.
+func DefaultNodeID() string {
+	host, _ := os.Hostname()
+	return host + "-" + common.GenSessionID()[:8]
+}
+
+// GRegistry returns the registry tracking the user sessions of every gateway node, in memory until UseRegistry sets
+// a shared one.
+func GRegistry() registry.Registry {
+	return current.Load().reg
+}
+
+// NodeID returns the name of this node in GRegistry.
+func NodeID() string {
+	return current.Load().id
+}
+
+// UseRegistry makes the node listen to r and use it from now on, the previous registry is closed. It is meant to be
+// called when the gateway is set up, before it serves.
+func UseRegistry(r registry.Registry, id string) error {
+	if err := r.Listen(id, handleCommand); err != nil {
+		return err
+	}
+	prev := current.Swap(&node{reg: r, id: id})
+	if prev != nil {
+		return prev.reg.Close()
+	}
+	return nil
+}
+
+// handleCommand serves a command another node sent about a session of this one.
+func handleCommand(cmd *registry.Command) {
+	ctx := logger.NewSessionContext(context.Background(), cmd.SessionID, cmd.UserID)
+	logger.Debug(ctx, "registry command", "type", cmd.Type, "from", cmd.From)
+	switch cmd.Type {
+	case registry.CmdRelease:
+		GJsActors.releaseLocal(cmd.UserID, cmd.SessionID)
+	case registry.CmdKick:
+		if s, err := GJsActors.Session(cmd.UserID); err == nil {
+			s.Kick(cmd.Data)
+		}
+	case registry.CmdNotice:
+		if cmd.UserID != "" {
+			_ = GJsActors.noticeLocal(cmd.UserID, cmd.Data)
+		} else if cmd.From != NodeID() {
+			GJsActors.noticeAllLocal(cmd.Data)
+		}
+	}
+}
+
+// claimSession registers a new session in GRegistry and closes the session the user had on another node. The
+// registry failing leaves the session served, only unknown to the other nodes.
+func claimSession(ctx context.Context, info *SessionInfo) {
+	rctx, cancel := context.WithTimeout(ctx, RegistryTimeout)
+	defer cancel()
+	prev, err := GRegistry().Claim(rctx, &registry.Entry{UserID: info.UserID, SessionID: info.SessionId,
+		PlatformID: info.PlatformID, Node: NodeID(), RemoteAddr: info.RemoteAddr, ConnectTime: info.ConnectTime})
+	if err != nil {
+		logger.Error(ctx, "registry claim error", "err", err)
+		return
+	}
+	if prev == nil || prev.Node == NodeID() {
+		return
+	}
+	logger.Info(ctx, "session replaced on another node", "node", prev.Node, "prevSessionId", prev.SessionID)
+	err = GRegistry().Send(rctx, prev.Node, &registry.Command{Type: registry.CmdRelease, From: NodeID(),
+		UserID: info.UserID, SessionID: prev.SessionID})
+	if err != nil && !errors.Is(err, registry.ErrNoListener) {
+		logger.Error(ctx, "registry release command error", "node", prev.Node, "err", err)
+	}
+}
+
+// releaseSession drops the entry of a closed session from GRegistry.
+func releaseSession(ctx context.Context, userID string, sessionID string) {
+	rctx, cancel := context.WithTimeout(ctx, RegistryTimeout)
+	defer cancel()
+	if err := GRegistry().Release(rctx, userID, sessionID); err != nil {
+		logger.Error(ctx, "registry release error", "err", err)
+	}
+}
+
+// remoteEntry returns the entry of a user whose session is on another node, ErrSessionNotFound when there is none.
+func remoteEntry(ctx context.Context, userID string) (*registry.Entry, error) {
+	e, err := GRegistry().Lookup(ctx, userID)
+	if errors.Is(err, registry.ErrNotFound) || err == nil && e.Node == NodeID() {
+		return nil, ErrSessionNotFound
+	}
+	return e, err
+}
+
+// sendRemote delivers a command to the node owning the session of the user.
+func sendRemote(userID string, cmd *registry.Command) error {
+	ctx, cancel := context.WithTimeout(context.Background(), RegistryTimeout)
+	defer cancel()
+	e, err := remoteEntry(ctx, userID)
+	if err != nil {
+		return err
+	}
+	cmd.From, cmd.UserID, cmd.SessionID = NodeID(), userID, e.SessionID
+	if err := GRegistry().Send(ctx, e.Node, cmd); err != nil {
+		if errors.Is(err, registry.ErrNoListener) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// entryInfo is the admin view of a registry entry.
+func entryInfo(e *registry.Entry) *SessionInfo {
+	return &SessionInfo{UserID: e.UserID, PlatformID: e.PlatformID, SessionId: e.SessionID, Node: e.Node,
+		RemoteAddr: e.RemoteAddr, ConnectTime: e.ConnectTime}
+}
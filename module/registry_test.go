@@ -0,0 +1,56 @@
+package module
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/registry"
+)
+
+func TestRegistryCommands(t *testing.T) {
+	ctx := context.Background()
+	mem := registry.NewMemory()
+	assert.NoError(t, UseRegistry(mem, "n1"))
+	defer func() { assert.NoError(t, UseRegistry(registry.NewMemory(), DefaultNodeID())) }()
+	remote := make(chan *registry.Command, 4)
+	assert.NoError(t, mem.Listen("n2", func(cmd *registry.Command) { remote <- cmd }))
+	next := func() *registry.Command {
+		select {
+		case cmd := <-remote:
+			return cmd
+		case <-time.After(5 * time.Second):
+			t.Fatal("command not delivered")
+			return nil
+		}
+	}
+	_, err := mem.Claim(ctx, &registry.Entry{UserID: "u1", SessionID: "s1", Node: "n2"})
+	assert.NoError(t, err)
+
+	info, err := GJsActors.Locate("u1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "n2", info.Node)
+	}
+	assert.NoError(t, GJsActors.Kick("u1", "bye"))
+	assert.Equal(t, &registry.Command{Type: registry.CmdKick, From: "n1", UserID: "u1", SessionID: "s1", Data: "bye"}, next())
+	assert.NoError(t, GJsActors.Notice("u1", "hi"))
+	assert.Equal(t, registry.CmdNotice, next().Type)
+	assert.ErrorIs(t, GJsActors.Kick("u2", ""), ErrSessionNotFound)
+
+	// a new session of u1 on this node closes the one on n2
+	claimSession(ctx, &SessionInfo{UserID: "u1", SessionId: "s2"})
+	assert.Equal(t, &registry.Command{Type: registry.CmdRelease, From: "n1", UserID: "u1", SessionID: "s1"}, next())
+	sessions, err := GJsActors.ClusterSessions()
+	if assert.NoError(t, err) && assert.Len(t, sessions, 1) {
+		assert.Equal(t, "n1", sessions[0].Node)
+	}
+
+	GJsActors.NoticeAll("all")
+	cmd := next()
+	assert.Equal(t, registry.CmdNotice, cmd.Type)
+	assert.Empty(t, cmd.UserID)
+	releaseSession(ctx, "u1", "s2")
+	_, err = GJsActors.Locate("u1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
@@ -0,0 +1,177 @@
+package module
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const (
+	DefaultReplayBufferSize = 256
+	DefaultReplayTTL        = 5 * time.Minute
+	// LastSeq is the url param of a reconnecting client: the seq of the last event it handled, the events pushed
+	// after it are sent again.
+	LastSeq = "lastSeq"
+	// EventsLostEventName carries an EventsLost when some of the events after lastSeq can no longer be replayed.
+	EventsLostEventName = "OnEventsLost"
+)
+
+var (
+	// ReplayBufferSize is the number of unacknowledged events kept per user, the oldest ones are dropped beyond it.
+	ReplayBufferSize = DefaultReplayBufferSize
+	// ReplayTTL is how long the buffer of a user outlives their last session, the time they have to reconnect.
+	ReplayTTL = DefaultReplayTTL
+)
+
+// EventsLost tells a reconnecting client that events between after and next are gone, it should pull its state
+// again instead of relying on the events.
+type EventsLost struct {
+	After int64 `json:"after"` // lastSeq of the connection
+	Next  int64 `json:"next"`  // seq of the first event replayed or pushed next
+}
+
+// GetLastSeq parses the URL to get the LastSeq parameter, false when the client did not send one.
+func (p *ParamStru) GetLastSeq() (int64, bool) {
+	u, err := url.Parse(p.UrlPath)
+	if err != nil {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(u.Query().Get(LastSeq), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, false
+	}
+	return seq, true
+}
+
+// replayBuffer numbers the events pushed to a user and keeps the ones not acknowledged yet. It is local to the
+// node: a client reconnecting to another one gets an EventsLost.
+type replayBuffer struct {
+	userID   string
+	sessions int         // sessions using the buffer, guarded by replays
+	expire   *time.Timer // drops the buffer ReplayTTL after its last session, guarded by replays
+
+	mu      sync.Mutex
+	seq     int64 // seq of the last event pushed
+	acked   int64
+	dropped int64 // highest seq dropped before it was acknowledged
+	events  []*core_func.EventData
+}
+
+var replays = struct {
+	sync.Mutex
+	m map[string]*replayBuffer
+}{m: make(map[string]*replayBuffer)}
+
+// acquireReplay returns the buffer of a user for a new session, until release.
+func acquireReplay(userID string) *replayBuffer {
+	replays.Lock()
+	defer replays.Unlock()
+	b, ok := replays.m[userID]
+	if !ok {
+		b = &replayBuffer{userID: userID}
+		replays.m[userID] = b
+	}
+	if b.expire != nil {
+		b.expire.Stop()
+		b.expire = nil
+	}
+	b.sessions++
+	return b
+}
+
+// release ends the use of a session, the buffer is dropped when no session took it again within ReplayTTL.
+func (b *replayBuffer) release() {
+	replays.Lock()
+	defer replays.Unlock()
+	if b.sessions--; b.sessions > 0 {
+		return
+	}
+	b.expire = time.AfterFunc(ReplayTTL, func() {
+		replays.Lock()
+		defer replays.Unlock()
+		if b.sessions == 0 && replays.m[b.userID] == b {
+			delete(replays.m, b.userID)
+		}
+	})
+}
+
+// push numbers an event and keeps it until it is acknowledged.
+func (b *replayBuffer) push(ev *core_func.EventData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	ev.Seq = b.seq
+	b.events = append(b.events, ev)
+	if n := len(b.events) - ReplayBufferSize; n > 0 {
+		b.dropped = b.events[n-1].Seq
+		b.events = append(b.events[:0:0], b.events[n:]...)
+	}
+}
+
+// ack drops the events up to seq.
+func (b *replayBuffer) ack(seq int64) *core_func.EventAck {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ackLocked(seq)
+	return &core_func.EventAck{Acked: b.acked, LastSeq: b.seq, Pending: len(b.events)}
+}
+
+func (b *replayBuffer) ackLocked(seq int64) {
+	if seq > b.seq {
+		seq = b.seq
+	}
+	if seq <= b.acked {
+		return
+	}
+	b.acked = seq
+	i := 0
+	for i < len(b.events) && b.events[i].Seq <= seq {
+		i++
+	}
+	b.events = b.events[i:]
+}
+
+// resume acknowledges the events up to the lastSeq of a reconnecting client and returns the ones after it, with an
+// EventsLost when some of them are gone. A lastSeq beyond the buffer, whose numbering was lost with an expired
+// buffer or comes from another node, makes the buffer go on numbering from it.
+func (b *replayBuffer) resume(after int64) ([]*core_func.EventData, *EventsLost) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if after > b.seq {
+		b.seq, b.acked, b.dropped, b.events = after, after, after, nil
+		return nil, &EventsLost{After: after, Next: after + 1}
+	}
+	var lost *EventsLost
+	if after < b.dropped || after < b.acked {
+		lost = &EventsLost{After: after, Next: b.seq + 1}
+		if len(b.events) > 0 {
+			lost.Next = b.events[0].Seq
+		}
+	}
+	b.ackLocked(after)
+	return append([]*core_func.EventData(nil), b.events...), lost
+}
+
+// replayEvents sends the events the client missed when it reconnects with a lastSeq, called before the run loop
+// serves anything else.
+func (actor *MActorIm) replayEvents() {
+	after, ok := actor.param.GetLastSeq()
+	if !ok {
+		return
+	}
+	events, lost := actor.replay.resume(after)
+	if lost != nil {
+		logger.Warn(actor.ctx, "events lost before reconnect", "lastSeq", after, "next", lost.Next)
+		data, _ := json.Marshal(lost)
+		actor.sendEventResp(&core_func.EventData{Event: EventsLostEventName, Data: string(data)})
+	}
+	logger.Info(actor.ctx, "replay events", "lastSeq", after, "count", len(events))
+	for _, ev := range events {
+		actor.sendEventResp(ev)
+	}
+}
@@ -0,0 +1,57 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/core_func"
+)
+
+func TestReplayEvents(t *testing.T) {
+	prev := ReplayBufferSize
+	ReplayBufferSize = 3
+	defer func() { ReplayBufferSize = prev }()
+	b := acquireReplay("replay-user")
+	defer b.release()
+	for i := 0; i < 4; i++ {
+		b.push(&core_func.EventData{Event: "OnRecvNewMessage"})
+	}
+	assert.Equal(t, &core_func.EventAck{Acked: 2, LastSeq: 4, Pending: 2}, b.ack(2))
+	assert.Same(t, b, acquireReplay("replay-user"))
+	b.release()
+
+	replay := func(lastSeq string) []*core_func.EventData {
+		agent := &testAgent{}
+		actor := &MActorIm{a: agent, replay: b, ctx: context.Background(), param: &ParamStru{UrlPath: "/?lastSeq=" + lastSeq}}
+		actor.replayEvents()
+		var events []*core_func.EventData
+		for _, msg := range agent.msgs {
+			ev := &core_func.EventData{}
+			assert.NoError(t, json.Unmarshal(msg.Msg, ev))
+			events = append(events, ev)
+		}
+		return events
+	}
+	// the reconnecting client gets what it missed and acknowledges what it had
+	events := replay("3")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, int64(4), events[0].Seq)
+	}
+	// the acknowledged events are gone, the pending ones are sent again
+	events = replay("1")
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, EventsLostEventName, events[0].Event)
+		assert.JSONEq(t, `{"after":1,"next":4}`, events[0].Data)
+		assert.Equal(t, int64(4), events[1].Seq)
+	}
+	// a lastSeq the buffer does not know goes on numbering from it
+	events = replay("10")
+	if assert.Len(t, events, 1) {
+		assert.JSONEq(t, `{"after":10,"next":11}`, events[0].Data)
+	}
+	ev := &core_func.EventData{}
+	b.push(ev)
+	assert.Equal(t, int64(11), ev.Seq)
+}
@@ -0,0 +1,206 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/registry"
+)
+
+const (
+	KickedEventName = "OnKickedByAdmin"
+	NoticeEventName = "OnSystemNotice"
+	// ConnectRejectedEventName is sent instead of the InitSDK response when a connection is refused.
+	ConnectRejectedEventName = "OnConnectRejected"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionInfo is the admin view of one connected user session.
+type SessionInfo struct {
+	UserID      string `json:"userID"`
+	PlatformID  string `json:"platformID"`
+	SessionId   string `json:"sessionId"`
+	Node        string `json:"node"` // gateway node serving the session
+	RemoteAddr  string `json:"remoteAddr"`
+	ConnectTime int64  `json:"connectTime"` // unix milliseconds
+}
+
+// UserSession is implemented by the actors stored in GJsActors.
+type UserSession interface {
+	Info() *SessionInfo
+	Kick(reason string)
+	Notice(data string) error
+}
+
+// Sessions returns every connected user session ordered by user id.
+func (m *JsActorMap) Sessions() []*SessionInfo {
+	m.Lock()
+	ret := make([]*SessionInfo, 0, len(m.uActors))
+	for _, actor := range m.uActors {
+		if s, ok := actor.(UserSession); ok {
+			ret = append(ret, s.Info())
+		}
+	}
+	m.Unlock()
+	sort.Slice(ret, func(i, j int) bool { return ret[i].UserID < ret[j].UserID })
+	return ret
+}
+
+// Count returns the number of connected user sessions.
+func (m *JsActorMap) Count() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.uActors)
+}
+
+// Online reports whether the user has a session.
+func (m *JsActorMap) Online(userID string) bool {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.uActors[userID]
+	return ok
+}
+
+// Session returns the session of one user.
+func (m *JsActorMap) Session(userID string) (UserSession, error) {
+	m.Lock()
+	defer m.Unlock()
+	actor, ok := m.uActors[userID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	s, ok := actor.(UserSession)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return s, nil
+}
+
+// Locate returns the session of one user on this node or, through GRegistry, on another one.
+func (m *JsActorMap) Locate(userID string) (*SessionInfo, error) {
+	if s, err := m.Session(userID); err == nil {
+		return s.Info(), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), RegistryTimeout)
+	defer cancel()
+	e, err := remoteEntry(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return entryInfo(e), nil
+}
+
+// ClusterSessions returns the sessions of every node in GRegistry ordered by user id.
+func (m *JsActorMap) ClusterSessions() ([]*SessionInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RegistryTimeout)
+	defer cancel()
+	entries, err := GRegistry().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*SessionInfo, 0, len(entries))
+	for _, e := range entries {
+		ret = append(ret, entryInfo(e))
+	}
+	return ret, nil
+}
+
+// Kick force-disconnects one user, the client receives the reason before the socket closes. A session on another
+// node is kicked by that node.
+func (m *JsActorMap) Kick(userID string, reason string) error {
+	s, err := m.Session(userID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return sendRemote(userID, &registry.Command{Type: registry.CmdKick, Data: reason})
+	}
+	if err != nil {
+		return err
+	}
+	s.Kick(reason)
+	return nil
+}
+
+// Notice pushes a system notice to one user, on this node or another one.
+func (m *JsActorMap) Notice(userID string, data string) error {
+	err := m.noticeLocal(userID, data)
+	if errors.Is(err, ErrSessionNotFound) {
+		return sendRemote(userID, &registry.Command{Type: registry.CmdNotice, Data: data})
+	}
+	return err
+}
+
+func (m *JsActorMap) noticeLocal(userID string, data string) error {
+	s, err := m.Session(userID)
+	if err != nil {
+		return err
+	}
+	return s.Notice(data)
+}
+
+// NoticeAll pushes a system notice to every connected user of every node and returns how many of this node
+// received it.
+func (m *JsActorMap) NoticeAll(data string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), RegistryTimeout)
+	defer cancel()
+	err := GRegistry().Send(ctx, "", &registry.Command{Type: registry.CmdNotice, From: NodeID(), Data: data})
+	if err != nil && !errors.Is(err, registry.ErrNoListener) {
+		logger.Error(ctx, "registry notice error", "err", err)
+	}
+	return m.noticeAllLocal(data)
+}
+
+// noticeAllLocal pushes a system notice to the users of this node.
+func (m *JsActorMap) noticeAllLocal(data string) int {
+	m.Lock()
+	sessions := make([]UserSession, 0, len(m.uActors))
+	for _, actor := range m.uActors {
+		if s, ok := actor.(UserSession); ok {
+			sessions = append(sessions, s)
+		}
+	}
+	m.Unlock()
+	var n int
+	for _, s := range sessions {
+		if s.Notice(data) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// releaseLocal closes the session of a user connected again on another node, if it is still the one of sessionID.
+func (m *JsActorMap) releaseLocal(userID string, sessionID string) {
+	m.Lock()
+	actor, ok := m.uActors[userID]
+	m.Unlock()
+	if s, isSession := actor.(UserSession); ok && isSession && s.Info().SessionId == sessionID {
+		actor.ReleaseRes()
+	}
+}
+
+// Info returns the admin view of the session.
+func (actor *MActorIm) Info() *SessionInfo {
+	return &SessionInfo{UserID: actor.param.GetUserID(), PlatformID: actor.param.GetPlatformID(),
+		SessionId: actor.SessionId, Node: NodeID(), RemoteAddr: actor.remoteAddr, ConnectTime: actor.connectTime.UnixMilli()}
+}
+
+// Kick asks the actor loop to send the reason and close the socket.
+func (actor *MActorIm) Kick(reason string) {
+	select {
+	case actor.kickChan <- reason:
+	default:
+	}
+}
+
+// Notice queues a system notice event for the client.
+func (actor *MActorIm) Notice(data string) error {
+	select {
+	case actor.mJsCore.RecvMsg() <- &core_func.EventData{Event: NoticeEventName, Data: data}:
+		return nil
+	default:
+		return errors.New("response channel is full")
+	}
+}
@@ -1,14 +1,23 @@
 package module
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/go-kratos/kratos/v2/log"
+	"errors"
 	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/errcode"
 	"github.com/yrzs/openimwssdk/gate"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/panics"
 	"sync"
 	"time"
 )
 
+const (
+	StatusHeartInterval = 100 //seconds
+	StatusPushChanLen   = 100
+)
+
 type StatusActorIm struct {
 	nChanLen        int          //接收数据网络缓存
 	heartTickerSend *time.Ticker //用于心跳send
@@ -17,20 +26,28 @@ type StatusActorIm struct {
 	SessionId       string
 	closeChan       chan bool        //主动关闭协程的通道
 	ReceivMsgChan   chan interface{} //接收网络层数据通道
+	pushChan        chan *ResponseSt //订阅主题的推送通道
 	isclosing       bool
+	ctx             context.Context
 }
 
 func NewStatusActor(a gate.Agent, sessionId string, appParam *ParamStru) (MActor, error) {
 
 	ret := &StatusActorIm{a: a, SessionId: sessionId, closeChan: make(chan bool, 1), nChanLen: 10, ReceivMsgChan: make(chan interface{}, 10), isclosing: false,
-		heartTickerSend: time.NewTicker(100 * time.Second)}
+		heartTickerSend: time.NewTicker(StatusHeartInterval * time.Second), pushChan: make(chan *ResponseSt, StatusPushChanLen),
+		ctx: logger.NewSessionContext(context.Background(), sessionId, "")}
 	go ret.run()
+	ret.sendResp(&ResponseSt{Type: HEART_CONFIG_TYPE, Success: true, Rate: StatusHeartInterval})
 	return ret, nil
 }
 func (actor *StatusActorIm) run() {
 	actor.wg.Add(1)
-	defer common.TryRecoverAndDebugPrint()
 	defer actor.wg.Done()
+	defer panics.Recover(actor.ctx, "status actor", func(any) {
+		actor.isclosing = true
+		actor.heartTickerSend.Stop()
+		actor.a.WriteMsg(panics.CloseMessage())
+	})
 	for {
 		select {
 		case <-actor.heartTickerSend.C: //send the heart pack
@@ -39,7 +56,8 @@ func (actor *StatusActorIm) run() {
 			}
 			actor.sendHeart()
 		case <-actor.closeChan:
-			log.Info("收到退出信号", "sessionId", actor.SessionId)
+			logger.Info(actor.ctx, "收到退出信号")
+			actor.heartTickerSend.Stop()
 			return
 		case recvData := <-actor.ReceivMsgChan:
 			if actor.isclosing == true {
@@ -47,42 +65,94 @@ func (actor *StatusActorIm) run() {
 			}
 			data := recvData.(*common.TWSData)
 			_ = actor.doRecvPro(data)
+		case msg := <-actor.pushChan:
+			if actor.isclosing == true {
+				continue
+			}
+			actor.sendResp(msg)
 		}
 	}
 }
-func (actor *StatusActorIm) ProcessRecvMsg(interface{}) error {
 
+// ProcessRecvMsg processes received messages and sends them to the ReceivMsgChan.
+func (actor *StatusActorIm) ProcessRecvMsg(msg interface{}) error {
+	if len(actor.ReceivMsgChan) == actor.nChanLen {
+		logger.Error(actor.ctx, "status channel is full")
+		return errors.New("status channel is full")
+	}
+	actor.ReceivMsgChan <- msg
 	return nil
 }
 func (actor *StatusActorIm) Destroy() {
+	GStatusHub.UnsubscribeAll(actor)
 	actor.closeChan <- true
 	actor.wg.Wait()
 	actor.a = nil
-	log.Info("退出MQPushActorIm", "sessionId", actor.SessionId)
+	logger.Info(actor.ctx, "退出StatusActorIm")
 }
 func (actor *StatusActorIm) ReleaseRes() {
 
 }
+
+// push queues a topic message for the operator, dropping it when the operator can not keep up.
+func (actor *StatusActorIm) push(msg *ResponseSt) {
+	select {
+	case actor.pushChan <- msg:
+	default:
+		logger.Error(actor.ctx, "status push channel is full", "topic", msg.Topic)
+	}
+}
 func (actor *StatusActorIm) sendHeart() {
 	//heart := []byte("ping")
 	resSend := &common.TWSData{MsgType: common.PingMessage, Msg: nil}
 	actor.a.WriteMsg(resSend)
 }
+func (actor *StatusActorIm) sendResp(res *ResponseSt) {
+	resb, _ := json.Marshal(res)
+	resSend := &common.TWSData{MsgType: common.MessageText, Msg: resb}
+	actor.a.WriteMsg(resSend)
+}
 func (actor *StatusActorIm) doRecvPro(data *common.TWSData) error {
-	log.Info("message come here", "data.type", data.MsgType)
+	logger.Debug(actor.ctx, "message come here", "msgType", data.MsgType)
 	if data.MsgType == common.MessageText {
 		req := &RequestSt{}
 		err := json.Unmarshal(data.Msg, req)
 		if err != nil {
-			log.Error("解析前端协议出错", "err", err, "sessionId", actor.SessionId)
+			logger.Error(actor.ctx, "解析前端协议出错", "err", err)
+			actor.sendResp(&ResponseSt{Type: RESP_OP_TYPE, Success: false, ErrCode: errcode.BadFrame, ErrMsg: ProtocolError})
 			return err
 		}
-		log.Info("收到命令", "req", req, "sessionId", actor.SessionId)
+		logger.Info(actor.ctx, "收到命令", "cmd", req.Cmd, "topic", req.Topic, "requestId", req.RequestId)
 		////////////////////////////////////////////////
-		res := ResponseSt{Type: RESP_OP_TYPE, Success: true, UserId: genGroupUserIds(), Duration: time.Now().Unix() - ProgressStartTime}
-		resb, _ := json.Marshal(res)
-		resSend := &common.TWSData{MsgType: common.MessageText, Msg: resb}
-		actor.a.WriteMsg(resSend)
+		res := &ResponseSt{Type: RESP_OP_TYPE, Cmd: req.Cmd, Success: true, RequestId: req.RequestId, Topic: req.Topic,
+			Extra: req.Extra, Duration: time.Now().Unix() - ProgressStartTime}
+		switch req.Cmd {
+		case SUB_CMD:
+			if !GStatusHub.IsValidTopic(req.Topic) {
+				res.Success = false
+				res.ErrCode = errcode.BadData
+				res.ErrMsg = "unknown topic"
+				break
+			}
+			missed := GStatusHub.Subscribe(actor, req)
+			if req.Topic == TOPIC_ONLINE_USERS {
+				res.UserId = genGroupUserIds()
+			}
+			actor.sendResp(res)
+			for _, msg := range missed {
+				actor.sendResp(msg)
+			}
+			return nil
+		case UNSUB_CMD:
+			GStatusHub.Unsubscribe(actor, req.Topic)
+		case HEART_CMD:
+			res.Rate = StatusHeartInterval
+		default:
+			res.Success = false
+			res.ErrCode = errcode.UnknownMethod
+			res.ErrMsg = "unknown cmd"
+		}
+		actor.sendResp(res)
 	}
 	return nil
 }
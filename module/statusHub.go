@@ -0,0 +1,197 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yrzs/openimwssdk/datadir"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const (
+	StatusReportInterval = 10 * time.Second
+	StatusHistoryLen     = 128
+)
+
+// ConnEvent is the payload of a connEvent topic message.
+type ConnEvent struct {
+	Event      string `json:"event"` //"connect" or "disconnect"
+	UserID     string `json:"userID"`
+	SessionId  string `json:"sessionId"`
+	PlatformID string `json:"platformID"`
+}
+
+// ErrorRateStat is the payload of an errorRate topic message.
+type ErrorRateStat struct {
+	Responses int64 `json:"responses"`
+	Errors    int64 `json:"errors"`
+	Interval  int64 `json:"interval"` // seconds
+}
+
+// StatusHub fans the operator topics out to the subscribed status actors.
+type StatusHub struct {
+	sync.Mutex
+	subs      map[string]map[*StatusActorIm]struct{}
+	seqs      map[string]int64
+	history   map[string][]*ResponseSt
+	respNum   atomic.Int64
+	errNum    atomic.Int64
+	closeChan chan bool
+}
+
+var GStatusHub *StatusHub
+
+func init() {
+	GStatusHub = NewStatusHub()
+}
+
+// NewStatusHub creates an empty hub with every known topic registered.
+func NewStatusHub() *StatusHub {
+	hub := &StatusHub{subs: make(map[string]map[*StatusActorIm]struct{}), seqs: make(map[string]int64),
+		history: make(map[string][]*ResponseSt)}
+	for _, topic := range []string{TOPIC_ONLINE_USERS, TOPIC_CONN_EVENT, TOPIC_ERROR_RATE, TOPIC_DISK_QUOTA} {
+		hub.subs[topic] = make(map[*StatusActorIm]struct{})
+	}
+	return hub
+}
+
+// IsValidTopic reports whether the topic can be subscribed to.
+func (hub *StatusHub) IsValidTopic(topic string) bool {
+	hub.Lock()
+	defer hub.Unlock()
+	_, ok := hub.subs[topic]
+	return ok
+}
+
+// Subscribe adds the actor to the topic and returns the buffered messages it asked to resume from.
+func (hub *StatusHub) Subscribe(actor *StatusActorIm, req *RequestSt) []*ResponseSt {
+	hub.Lock()
+	defer hub.Unlock()
+	hub.subs[req.Topic][actor] = struct{}{}
+	var ret []*ResponseSt
+	if req.MsgSeqId <= 0 && req.MsgTimeStamp <= 0 {
+		return ret
+	}
+	for _, msg := range hub.history[req.Topic] {
+		if req.MsgSeqId > 0 && msg.MsgSeqId > req.MsgSeqId {
+			ret = append(ret, msg)
+		} else if req.MsgSeqId <= 0 && msg.MsgTimeStamp >= req.MsgTimeStamp {
+			ret = append(ret, msg)
+		}
+	}
+	return ret
+}
+
+// Unsubscribe removes the actor from the topic.
+func (hub *StatusHub) Unsubscribe(actor *StatusActorIm, topic string) {
+	hub.Lock()
+	defer hub.Unlock()
+	if subs, ok := hub.subs[topic]; ok {
+		delete(subs, actor)
+	}
+}
+
+// UnsubscribeAll removes the actor from every topic, used when the operator disconnects.
+func (hub *StatusHub) UnsubscribeAll(actor *StatusActorIm) {
+	hub.Lock()
+	defer hub.Unlock()
+	for _, subs := range hub.subs {
+		delete(subs, actor)
+	}
+}
+
+// hasSubscriber reports whether anyone listens to the topic, so that idle topics cost nothing.
+func (hub *StatusHub) hasSubscriber(topic string) bool {
+	hub.Lock()
+	defer hub.Unlock()
+	return len(hub.subs[topic]) > 0
+}
+
+// Publish stamps the message with the next sequence id of its topic and pushes it to every subscriber.
+func (hub *StatusHub) Publish(topic string, msg *ResponseSt) {
+	hub.Lock()
+	defer hub.Unlock()
+	hub.seqs[topic]++
+	msg.Type = MQ_MSG_TYPE
+	msg.Topic = topic
+	msg.Success = true
+	msg.MsgSeqId = hub.seqs[topic]
+	msg.MsgTimeStamp = time.Now().UnixMilli()
+	msg.Duration = time.Now().Unix() - ProgressStartTime
+	history := append(hub.history[topic], msg)
+	if len(history) > StatusHistoryLen {
+		history = history[len(history)-StatusHistoryLen:]
+	}
+	hub.history[topic] = history
+	for actor := range hub.subs[topic] {
+		actor.push(msg)
+	}
+}
+
+// PublishConnEvent publishes a connect or disconnect of a user session, kept in the history without subscribers.
+func (hub *StatusHub) PublishConnEvent(event string, userID string, sessionId string, platformID string) {
+	data, _ := json.Marshal(&ConnEvent{Event: event, UserID: userID, SessionId: sessionId, PlatformID: platformID})
+	hub.Publish(TOPIC_CONN_EVENT, &ResponseSt{Cmd: event, Data: string(data)})
+}
+
+// PublishDiskAlert publishes a datadir quota exceeded.
+func (hub *StatusHub) PublishDiskAlert(alert *datadir.Alert) {
+	data, _ := json.Marshal(alert)
+	hub.Publish(TOPIC_DISK_QUOTA, &ResponseSt{Data: string(data)})
+}
+
+// CountResp records one response sent to a client, used to compute the error rate.
+func (hub *StatusHub) CountResp(isErr bool) {
+	hub.respNum.Add(1)
+	if isErr {
+		hub.errNum.Add(1)
+	}
+}
+
+// Start runs the periodic report of the onlineUsers and errorRate topics until Stop is called.
+func (hub *StatusHub) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = StatusReportInterval
+	}
+	hub.closeChan = make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				hub.report(interval)
+			case <-hub.closeChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic report.
+func (hub *StatusHub) Stop() {
+	if hub.closeChan != nil {
+		hub.closeChan <- true
+	}
+}
+
+// report publishes one snapshot of the periodic topics.
+func (hub *StatusHub) report(interval time.Duration) {
+	resps, errNum := hub.respNum.Swap(0), hub.errNum.Swap(0)
+	if hub.hasSubscriber(TOPIC_ONLINE_USERS) {
+		hub.Publish(TOPIC_ONLINE_USERS, &ResponseSt{UserId: genGroupUserIds()})
+	}
+	if hub.hasSubscriber(TOPIC_ERROR_RATE) {
+		stat := &ErrorRateStat{Responses: resps, Errors: errNum, Interval: int64(interval / time.Second)}
+		data, _ := json.Marshal(stat)
+		var rate int64
+		if resps > 0 {
+			rate = errNum * 1000 / resps
+		}
+		hub.Publish(TOPIC_ERROR_RATE, &ResponseSt{Data: string(data), Rate: rate})
+	}
+	logger.Debug(context.Background(), "status report", "responses", resps, "errors", errNum)
+}
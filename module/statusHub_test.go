@@ -0,0 +1,43 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStatusActor() *StatusActorIm {
+	return &StatusActorIm{pushChan: make(chan *ResponseSt, StatusPushChanLen)}
+}
+
+func TestStatusHubPublish(t *testing.T) {
+	hub := NewStatusHub()
+	actor := newTestStatusActor()
+	assert.False(t, hub.IsValidTopic("unknown"))
+	assert.Empty(t, hub.Subscribe(actor, &RequestSt{Cmd: SUB_CMD, Topic: TOPIC_CONN_EVENT}))
+
+	hub.PublishConnEvent(CONN_EVENT_CONNECT, "u1", "s1", "5")
+	hub.PublishConnEvent(CONN_EVENT_DISCONNECT, "u1", "s1", "")
+	first, second := <-actor.pushChan, <-actor.pushChan
+	assert.Equal(t, MQ_MSG_TYPE, first.Type)
+	assert.Equal(t, TOPIC_CONN_EVENT, first.Topic)
+	assert.Equal(t, int64(1), first.MsgSeqId)
+	assert.Equal(t, int64(2), second.MsgSeqId)
+	assert.JSONEq(t, `{"event":"disconnect","userID":"u1","sessionId":"s1","platformID":""}`, second.Data)
+
+	hub.Unsubscribe(actor, TOPIC_CONN_EVENT)
+	hub.PublishConnEvent(CONN_EVENT_CONNECT, "u2", "s2", "5")
+	assert.Len(t, actor.pushChan, 0)
+}
+
+func TestStatusHubResume(t *testing.T) {
+	hub := NewStatusHub()
+	for i := 0; i < 3; i++ {
+		hub.PublishConnEvent(CONN_EVENT_CONNECT, "u1", "s1", "5")
+	}
+	missed := hub.Subscribe(newTestStatusActor(), &RequestSt{Topic: TOPIC_CONN_EVENT, MsgSeqId: 1})
+	if assert.Len(t, missed, 2) {
+		assert.Equal(t, int64(2), missed[0].MsgSeqId)
+		assert.Equal(t, int64(3), missed[1].MsgSeqId)
+	}
+}
@@ -1,21 +1,39 @@
 package module
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
-	"github.com/go-kratos/kratos/v2/log"
 	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/errcode"
 	"github.com/yrzs/openimwssdk/gate"
+	"github.com/yrzs/openimwssdk/logger"
+	"net/url"
 )
 
 var ProgressStartTime int64
 
+// StatusToken is the shared secret operators must present, an empty value disables the check.
+var StatusToken string
+
 func NewStatusAgent(a gate.Agent) {
 	aUerData := a.UserData().(*common.TAgentUserData)
-	log.Info("one status ws connect", "sessionId", aUerData.SessionID)
+	ctx := logger.NewSessionContext(context.Background(), aUerData.SessionID, "")
+	logger.Info(ctx, "one status ws connect", "remoteAddr", a.RemoteAddr())
+	if err := checkStatusToken(aUerData); err != nil {
+		logger.Error(ctx, "status token validation failed", "err", err)
+		res := &ResponseSt{Type: RESP_OP_TYPE, Cmd: CONN_CMD, Success: false, ErrCode: errcode.From(err).Code,
+			ErrMsg: "check token error"}
+		resb, _ := json.Marshal(res)
+		resSend := &common.TWSData{MsgType: common.MessageText, Msg: resb}
+		a.WriteMsg(resSend)
+		a.Close()
+		return
+	}
 	actor, err := NewStatusActor(a, aUerData.SessionID, nil)
 	if err != nil {
-		log.Error("NewStatusActor error", "err", err, "sessionId", aUerData.SessionID)
-		res := &ResponseSt{Type: RESP_OP_TYPE, Success: false, ErrMsg: "NewMQActor error"}
+		logger.Error(ctx, "NewStatusActor error", "err", err)
+		res := &ResponseSt{Type: RESP_OP_TYPE, Success: false, ErrCode: errcode.SessionFailed, ErrMsg: "NewMQActor error"}
 		resb, _ := json.Marshal(res)
 		resSend := &common.TWSData{MsgType: common.MessageText, Msg: resb}
 		a.WriteMsg(resSend)
@@ -24,7 +42,7 @@ func NewStatusAgent(a gate.Agent) {
 	}
 	aUerData.ProxyBody = actor
 	a.SetUserData(aUerData)
-	log.Info("one status linked", "sessionId", aUerData.SessionID)
+	logger.Info(ctx, "one status linked")
 
 }
 func CloseStatusAgent(a gate.Agent) {
@@ -33,15 +51,34 @@ func CloseStatusAgent(a gate.Agent) {
 		aUerData.ProxyBody.(MActor).Destroy()
 		aUerData.ProxyBody = nil
 	}
-	log.Info("one  status dislinkder", "sessionId", a.UserData().(*common.TAgentUserData).SessionID)
+	logger.Info(logger.NewSessionContext(context.Background(), aUerData.SessionID, ""), "one status dislinkder")
 }
 func DataRecvStatus(data interface{}, a gate.Agent) {
 	aUerData := a.UserData().(*common.TAgentUserData)
 	if aUerData.ProxyBody != nil {
 		err := aUerData.ProxyBody.(MActor).ProcessRecvMsg(data)
 		if err != nil {
-			log.Error("溢出错误", "sessionId", aUerData.SessionID)
+			logger.Error(logger.NewSessionContext(context.Background(), aUerData.SessionID, ""), "溢出错误")
 			a.Destroy()
 		}
 	}
 }
+
+// checkStatusToken compares the operator token, taken from the Authorization header or the token query, with StatusToken.
+func checkStatusToken(data *common.TAgentUserData) error {
+	if StatusToken == "" {
+		return nil
+	}
+	token := data.CookieVal
+	if token == "" {
+		u, err := url.Parse(data.AppString)
+		if err != nil {
+			return errcode.New(errcode.BadURL, "ws url path not correct")
+		}
+		token = u.Query().Get("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(StatusToken)) != 1 {
+		return errcode.New(errcode.TokenInvalid, "status token mismatch")
+	}
+	return nil
+}
@@ -10,10 +10,21 @@ const (
 	HEART_CMD         = "heart"
 )
 
+const (
+	TOPIC_ONLINE_USERS = "onlineUsers" // periodic snapshot of the connected user ids
+	TOPIC_CONN_EVENT   = "connEvent"   // one message per user connect or disconnect
+	TOPIC_ERROR_RATE   = "errorRate"   // periodic count of error responses, rate in permille
+	TOPIC_DISK_QUOTA   = "diskQuota"   // one message per datadir quota exceeded
+
+	CONN_EVENT_CONNECT    = "connect"
+	CONN_EVENT_DISCONNECT = "disconnect"
+)
+
 type ResponseSt struct {
 	Type         string   `json:"type"`    //"response" or "mqMessage" or "heartConfig"
 	Cmd          string   `json:"cmd"`     //"connect" "subscribe" "unsubscribe"
 	Success      bool     `json:"success"` //
+	ErrCode      int32    `json:"errCode"` // see package errcode, 0 on success
 	ErrMsg       string   `json:"errMsg"`
 	UserId       []string `json:"userIds"`
 	Duration     int64    `json:"duration"` // progress run time ,seconds
@@ -23,7 +34,7 @@ type ResponseSt struct {
 	MsgTimeStamp int64    `json:"msgTimeStamp"`
 	MsgSeqId     int64    `json:"msgSeqId"`
 	Data         string   `json:"data"`
-	Rate         int64    `json:"rate"`
+	Rate         int64    `json:"rate"` // heart interval seconds for "heartConfig", error permille for "errorRate"
 }
 
 type RequestSt struct {
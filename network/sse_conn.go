@@ -0,0 +1,216 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+var (
+	errSSEClosed   = errors.New("session closed")
+	errSSEFull     = errors.New("too many requests pending")
+	errSSETooLarge = errors.New("message too long")
+)
+
+// SSEConn is a session of the SSE transport: the frames for the client are events of the stream, those of the
+// client are the bodies of its POSTs.
+type SSEConn struct {
+	sync.Mutex
+	writeChan  chan *common.TWSData
+	recvChan   chan []byte
+	maxMsgLen  uint32
+	closeFlag  bool
+	recvClosed bool
+	cancel     context.CancelFunc // ends the stream at once
+	token      string             // a POST must carry the token of the stream
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	ctx        context.Context
+	SessionId  string
+	AppURL     string
+	CookieVal  string
+}
+
+// newSSEConn initializes the session of a stream request, cancel ends the stream.
+func newSSEConn(r *http.Request, cancel context.CancelFunc, pendingWriteNum int, maxMsgLen uint32, token string,
+	cookieVal string) *SSEConn {
+	sseConn := new(SSEConn)
+	sseConn.cancel = cancel
+	sseConn.writeChan = make(chan *common.TWSData, pendingWriteNum)
+	sseConn.recvChan = make(chan []byte, pendingWriteNum)
+	sseConn.maxMsgLen = maxMsgLen
+	sseConn.token = token
+	sseConn.SessionId = common.GenSessionID()
+	sseConn.AppURL = r.URL.String()
+	sseConn.CookieVal = cookieVal
+	sseConn.remoteAddr = addrOf(r.RemoteAddr)
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		sseConn.localAddr = addr
+	}
+	sseConn.ctx = logger.NewSessionContext(context.Background(), sseConn.SessionId, "")
+	return sseConn
+}
+
+// addrOf parses the host:port of the remote end of a request.
+func addrOf(hostPort string) net.Addr {
+	addr, err := net.ResolveTCPAddr("tcp", hostPort)
+	if err != nil {
+		return nil
+	}
+	return addr
+}
+
+// writeLoop writes the queued frames to the stream until the connection is closed or the client goes away.
+func (sseConn *SSEConn) writeLoop(ctx context.Context, w io.Writer, flusher http.Flusher) {
+	for {
+		var b *common.TWSData
+		select {
+		case <-ctx.Done():
+			return
+		case b = <-sseConn.writeChan:
+		}
+		if b == nil {
+			return
+		}
+		if err := writeSSEFrame(w, b); err != nil {
+			logger.Error(sseConn.ctx, "send message err", "err", err)
+			return
+		}
+		flusher.Flush()
+		if b.MsgType == common.CloseMessage {
+			logger.Debug(sseConn.ctx, "close message")
+			return
+		}
+	}
+}
+
+// writeSSEFrame writes a frame as an event: a text or binary frame as its data, a ping as a comment and a close
+// frame as a close event whose data is the close code and the reason.
+func writeSSEFrame(w io.Writer, b *common.TWSData) error {
+	var err error
+	switch b.MsgType {
+	case common.PingMessage:
+		_, err = io.WriteString(w, ": ping\n\n")
+	case common.CloseMessage:
+		data := ""
+		if len(b.Msg) >= 2 {
+			data = strconv.Itoa(int(binary.BigEndian.Uint16(b.Msg))) + " " + string(b.Msg[2:])
+		}
+		_, err = fmt.Fprintf(w, "event: close\ndata: %s\n\n", data)
+	default:
+		var buf bytes.Buffer
+		for _, line := range bytes.Split(b.Msg, []byte("\n")) {
+			buf.WriteString("data: ")
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		buf.WriteByte('\n')
+		_, err = w.Write(buf.Bytes())
+	}
+	return err
+}
+
+// post queues the body of a POST for ReadMsg.
+func (sseConn *SSEConn) post(b []byte) error {
+	sseConn.Lock()
+	defer sseConn.Unlock()
+	if sseConn.recvClosed {
+		return errSSEClosed
+	}
+	select {
+	case sseConn.recvChan <- b:
+		return nil
+	default:
+		return errSSEFull
+	}
+}
+
+// closeRecv ends ReadMsg, once the stream is over.
+func (sseConn *SSEConn) closeRecv() {
+	sseConn.Lock()
+	defer sseConn.Unlock()
+	sseConn.closeFlag = true
+	if !sseConn.recvClosed {
+		close(sseConn.recvChan)
+		sseConn.recvClosed = true
+	}
+}
+
+// Destroy ends the stream at once, without writing the pending frames.
+func (sseConn *SSEConn) Destroy() {
+	sseConn.Lock()
+	defer sseConn.Unlock()
+	sseConn.closeFlag = true
+	sseConn.cancel()
+}
+
+// Close ends the stream once the pending frames are written.
+func (sseConn *SSEConn) Close() {
+	sseConn.Lock()
+	defer sseConn.Unlock()
+	if sseConn.closeFlag {
+		return
+	}
+
+	sseConn.doWrite(nil)
+	sseConn.closeFlag = true
+}
+
+// doWrite enqueues a frame for the stream.
+func (sseConn *SSEConn) doWrite(b *common.TWSData) {
+	if len(sseConn.writeChan) == cap(sseConn.writeChan) {
+		logger.Error(sseConn.ctx, "close conn: channel full")
+		sseConn.closeFlag = true
+		sseConn.cancel()
+		return
+	}
+
+	sseConn.writeChan <- b
+}
+
+// LocalAddr returns the local network address.
+func (sseConn *SSEConn) LocalAddr() net.Addr {
+	return sseConn.localAddr
+}
+
+// RemoteAddr returns the remote network address.
+func (sseConn *SSEConn) RemoteAddr() net.Addr {
+	return sseConn.remoteAddr
+}
+
+// ReadMsg returns the body of the next POST, as a text frame.
+// goroutine not safe.
+func (sseConn *SSEConn) ReadMsg() (int, []byte, error) {
+	b, ok := <-sseConn.recvChan
+	if !ok {
+		return 0, nil, io.EOF
+	}
+	return common.MessageText, b, nil
+}
+
+// WriteMsg queues a frame for the stream.
+// args must not be modified by the others goroutines.
+func (sseConn *SSEConn) WriteMsg(args *common.TWSData) error {
+	sseConn.Lock()
+	defer sseConn.Unlock()
+	if sseConn.closeFlag {
+		return nil
+	}
+
+	if uint32(len(args.Msg)) > sseConn.maxMsgLen {
+		return errSSETooLarge
+	}
+
+	sseConn.doWrite(args)
+	return nil
+}
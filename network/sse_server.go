@@ -0,0 +1,254 @@
+package network
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/panics"
+)
+
+const (
+	// SSEPath streams the events of a new session: GET with the query of the websocket url.
+	SSEPath = "/sse"
+	// SSESessionEvent is the first event of a stream, its data is the session id the POSTs go to.
+	SSESessionEvent = "session"
+)
+
+// SSEServer is the transport for the clients behind proxies that strip websocket upgrades. A GET on SSEPath opens
+// the event stream of a session; the request frames are POSTed to SSEPath/{sessionId}, with the token of the
+// stream, and answered on the stream.
+type SSEServer struct {
+	Addr            string
+	MaxConnNum      int
+	PendingWriteNum int
+	MaxMsgLen       uint32
+	HTTPTimeout     time.Duration
+	CertFile        string
+	KeyFile         string
+	NewAgent        func(*SSEConn) Agent
+	ln              net.Listener
+	handler         *SSEHandler
+	httpServer      *http.Server
+}
+
+type SSEHandler struct {
+	maxConnNum      int
+	pendingWriteNum int
+	maxMsgLen       uint32
+	newAgent        func(*SSEConn) Agent
+	mux             *http.ServeMux
+	conns           map[string]*SSEConn // by session id
+	mutexConns      sync.Mutex
+	wg              sync.WaitGroup
+}
+
+// ServeHTTP allows the requests of any origin, like the websocket upgrader does, and routes them.
+func (handler *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer panics.Recover(r.Context(), "sse handler", nil)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	handler.mux.ServeHTTP(w, r)
+}
+
+// serveStream opens the session of a client and streams its events until either end closes it.
+func (handler *SSEHandler) serveStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	cookieVal := bearerToken(r)
+	token := cookieVal
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	logger.Debug(ctx, "sse connect", "path", r.URL.Path, "remoteAddr", r.RemoteAddr)
+	handler.wg.Add(1)
+	defer handler.wg.Done()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sseConn := newSSEConn(r, cancel, handler.pendingWriteNum, handler.maxMsgLen, token, cookieVal)
+	handler.mutexConns.Lock()
+	if handler.conns == nil {
+		handler.mutexConns.Unlock()
+		http.Error(w, "server closed", http.StatusServiceUnavailable)
+		return
+	}
+	if len(handler.conns) >= handler.maxConnNum {
+		handler.mutexConns.Unlock()
+		// the body carries the errCode, there is no session to answer with an event yet
+		http.Error(w, strconv.Itoa(int(errcode.Overloaded))+" too many connections", http.StatusServiceUnavailable)
+		logger.Warn(ctx, "too many connections", "maxConnNum", handler.maxConnNum)
+		return
+	}
+	handler.conns[sseConn.SessionId] = sseConn
+	handler.mutexConns.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", SSESessionEvent, sseConn.SessionId); err == nil {
+		flusher.Flush()
+	}
+
+	agent := handler.newAgent(sseConn)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.Run()
+	}()
+	sseConn.writeLoop(streamCtx, w, flusher)
+
+	// cleanup
+	sseConn.closeRecv()
+	<-done
+	handler.mutexConns.Lock()
+	delete(handler.conns, sseConn.SessionId)
+	handler.mutexConns.Unlock()
+	agent.OnClose()
+}
+
+// servePost hands the body of a POST to the session it is sent to.
+func (handler *SSEHandler) servePost(w http.ResponseWriter, r *http.Request) {
+	handler.mutexConns.Lock()
+	sseConn, ok := handler.conns[r.PathValue("sessionId")]
+	handler.mutexConns.Unlock()
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	token := bearerToken(r)
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(sseConn.token)) != 1 {
+		http.Error(w, "token does not match the session", http.StatusForbidden)
+		return
+	}
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(handler.maxMsgLen)))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, errSSETooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch err := sseConn.post(b); {
+	case errors.Is(err, errSSEClosed):
+		http.Error(w, "session not found", http.StatusNotFound)
+	case errors.Is(err, errSSEFull):
+		http.Error(w, strconv.Itoa(int(errcode.Overloaded))+" "+err.Error(), http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// bearerToken returns the token of the Authorization header, empty without a "Bearer " one.
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
+
+// Start initializes and starts the SSE server.
+func (server *SSEServer) Start() {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Fatal(context.Background(), "net.listen err", "addr", server.Addr, "err", err)
+	}
+
+	if server.MaxConnNum <= 0 {
+		server.MaxConnNum = 100
+		logger.Info(context.Background(), "invalid MaxConnNum, reset", "maxConnNum", server.MaxConnNum)
+	}
+	if server.PendingWriteNum <= 0 {
+		server.PendingWriteNum = 100
+		logger.Info(context.Background(), "invalid PendingWriteNum, reset", "pendingWriteNum", server.PendingWriteNum)
+	}
+	if server.MaxMsgLen <= 0 {
+		server.MaxMsgLen = 4096
+		logger.Info(context.Background(), "invalid MaxMsgLen, reset", "maxMsgLen", server.MaxMsgLen)
+	}
+	if server.HTTPTimeout <= 0 {
+		server.HTTPTimeout = 10 * time.Second
+		logger.Info(context.Background(), "invalid HTTPTimeout, reset", "httpTimeout", server.HTTPTimeout)
+	}
+	if server.NewAgent == nil {
+		logger.Fatal(context.Background(), "NewAgent must not be nil")
+	}
+
+	if server.CertFile != "" || server.KeyFile != "" {
+		config := &tls.Config{}
+		config.NextProtos = []string{"http/1.1"}
+
+		var err error
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(server.CertFile, server.KeyFile)
+		if err != nil {
+			logger.Fatal(context.Background(), "certificate file error", "err", err)
+		}
+
+		ln = tls.NewListener(ln, config)
+	}
+
+	server.ln = ln
+	server.handler = &SSEHandler{
+		maxConnNum:      server.MaxConnNum,
+		pendingWriteNum: server.PendingWriteNum,
+		maxMsgLen:       server.MaxMsgLen,
+		newAgent:        server.NewAgent,
+		mux:             http.NewServeMux(),
+		conns:           make(map[string]*SSEConn),
+	}
+	server.handler.mux.HandleFunc("GET "+SSEPath, server.handler.serveStream)
+	server.handler.mux.HandleFunc("POST "+SSEPath+"/{sessionId}", server.handler.servePost)
+
+	// no WriteTimeout, the streams stay open
+	server.httpServer = &http.Server{
+		Addr:              server.Addr,
+		Handler:           server.handler,
+		ReadHeaderTimeout: server.HTTPTimeout,
+		ReadTimeout:       server.HTTPTimeout,
+		MaxHeaderBytes:    1 << 12,
+	}
+
+	go server.httpServer.Serve(ln)
+}
+
+// Close shuts down the SSE server and ends all the streams.
+func (server *SSEServer) Close() {
+	server.ln.Close()
+
+	server.handler.mutexConns.Lock()
+	for _, conn := range server.handler.conns {
+		conn.Destroy()
+	}
+	server.handler.conns = nil
+	server.handler.mutexConns.Unlock()
+
+	server.handler.wg.Wait()
+}
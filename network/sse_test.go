@@ -0,0 +1,98 @@
+package network
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/common"
+)
+
+// readSSEEvent reads the next event of a stream, skipping comments.
+func readSSEEvent(r *bufio.Reader) (event string, data string, err error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if event != "" || lines != nil {
+				return event, strings.Join(lines, "\n"), nil
+			}
+		case strings.HasPrefix(line, "event: "):
+			event = line[len("event: "):]
+		case strings.HasPrefix(line, "data: "):
+			lines = append(lines, line[len("data: "):])
+		}
+	}
+}
+
+func TestSSEServer(t *testing.T) {
+	agents := make(chan *echoAgent, 1)
+	server := &SSEServer{Addr: "127.0.0.1:0", MaxMsgLen: 64, NewAgent: func(conn *SSEConn) Agent {
+		a := &echoAgent{conn: conn, closed: make(chan struct{})}
+		agents <- a
+		return a
+	}}
+	server.Start()
+	defer server.Close()
+	base := "http://" + server.ln.Addr().String() + SSEPath
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	resp, err := client.Get(base + "?sendID=u1&token=t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+	event, sessionID, err := readSSEEvent(r)
+	assert.NoError(t, err)
+	assert.Equal(t, SSESessionEvent, event)
+	a := <-agents
+	assert.Equal(t, "/sse?sendID=u1&token=t", a.conn.(*SSEConn).AppURL)
+
+	post := func(url string, body string) int {
+		resp, err := client.Post(url, "application/json", strings.NewReader(body))
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusAccepted, post(base+"/"+sessionID+"?token=t", `{"reqFuncName":"GetSelfUserInfo"}`))
+	_, data, err := readSSEEvent(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"reqFuncName":"GetSelfUserInfo"}`, data)
+
+	assert.Equal(t, http.StatusForbidden, post(base+"/"+sessionID+"?token=other", "{}"))
+	assert.Equal(t, http.StatusNotFound, post(base+"/unknown?token=t", "{}"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(base+"/"+sessionID+"?token=t", strings.Repeat("x", 65)))
+
+	// a close frame is the last event of the stream
+	assert.NoError(t, a.conn.WriteMsg(&common.TWSData{MsgType: common.CloseMessage,
+		Msg: []byte{0x03, 0xe8, 'b', 'y', 'e'}}))
+	event, data, err = readSSEEvent(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "close", event)
+	assert.Equal(t, "1000 bye", data)
+	select {
+	case <-a.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent not closed")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for header, want := range map[string]string{"Bearer abc": "abc", "Basic dXNlcjpwdw==": "", "abcdefgh": "", "": ""} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", header)
+		assert.Equal(t, want, bearerToken(r), header)
+	}
+}
@@ -0,0 +1,158 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+type ConnSet map[net.Conn]struct{}
+
+// TCPConn is a connection of the TCP transport, its frames are those of MsgParser. It answers the pings of the
+// client itself, like the websocket library does.
+type TCPConn struct {
+	sync.Mutex
+	conn        net.Conn
+	writeChan   chan *common.TWSData
+	closeFlag   bool
+	msgParser   *MsgParser
+	readTimeout time.Duration
+	ctx         context.Context
+	SessionId   string
+	AppURL      string // the connect url the client sent in its first frame
+}
+
+// newTCPConn initializes a new TCPConn object and starts its write loop.
+func newTCPConn(conn net.Conn, pendingWriteNum int, msgParser *MsgParser, readTimeout time.Duration, appURL string) *TCPConn {
+	tcpConn := new(TCPConn)
+	tcpConn.conn = conn
+	tcpConn.writeChan = make(chan *common.TWSData, pendingWriteNum)
+	tcpConn.msgParser = msgParser
+	tcpConn.readTimeout = readTimeout
+	tcpConn.SessionId = common.GenSessionID()
+	tcpConn.AppURL = appURL
+	tcpConn.ctx = logger.NewSessionContext(context.Background(), tcpConn.SessionId, "")
+	go func() {
+		for b := range tcpConn.writeChan {
+			if b == nil {
+				break
+			}
+			if err := msgParser.Write(conn, b.MsgType, b.Msg); err != nil {
+				logger.Error(tcpConn.ctx, "send message err", "err", err)
+				break
+			}
+			if b.MsgType == common.CloseMessage {
+				logger.Debug(tcpConn.ctx, "close message")
+				break
+			}
+		}
+
+		conn.Close()
+		tcpConn.Lock()
+		tcpConn.closeFlag = true
+		tcpConn.Unlock()
+	}()
+	return tcpConn
+}
+
+// doDestroy forcefully closes the connection without waiting for pending writes.
+func (tcpConn *TCPConn) doDestroy() {
+	if c, ok := tcpConn.conn.(*net.TCPConn); ok {
+		_ = c.SetLinger(0)
+	}
+	tcpConn.conn.Close()
+
+	if !tcpConn.closeFlag {
+		close(tcpConn.writeChan)
+		tcpConn.closeFlag = true
+	}
+}
+
+// Destroy closes the connection at once.
+func (tcpConn *TCPConn) Destroy() {
+	tcpConn.Lock()
+	defer tcpConn.Unlock()
+
+	tcpConn.doDestroy()
+}
+
+// Close closes the connection once the pending frames are written.
+func (tcpConn *TCPConn) Close() {
+	tcpConn.Lock()
+	defer tcpConn.Unlock()
+	if tcpConn.closeFlag {
+		return
+	}
+
+	tcpConn.doWrite(nil)
+	tcpConn.closeFlag = true
+}
+
+// doWrite enqueues a frame for the write loop.
+func (tcpConn *TCPConn) doWrite(b *common.TWSData) {
+	if len(tcpConn.writeChan) == cap(tcpConn.writeChan) {
+		logger.Error(tcpConn.ctx, "close conn: channel full")
+		tcpConn.doDestroy()
+		return
+	}
+
+	tcpConn.writeChan <- b
+}
+
+// LocalAddr returns the local network address.
+func (tcpConn *TCPConn) LocalAddr() net.Addr {
+	return tcpConn.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (tcpConn *TCPConn) RemoteAddr() net.Addr {
+	return tcpConn.conn.RemoteAddr()
+}
+
+// ReadMsg reads the next text or binary frame, a client silent for the read timeout is disconnected. Pings are
+// answered and pongs skipped, a close frame ends the connection with io.EOF.
+// goroutine not safe.
+func (tcpConn *TCPConn) ReadMsg() (int, []byte, error) {
+	for {
+		_ = tcpConn.conn.SetReadDeadline(time.Now().Add(tcpConn.readTimeout))
+		msgType, b, err := tcpConn.msgParser.Read(tcpConn.conn)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch msgType {
+		case common.MessageText, common.MessageBinary:
+			return msgType, b, nil
+		case common.PingMessage:
+			_ = tcpConn.WriteMsg(&common.TWSData{MsgType: common.PongMessage, Msg: b})
+		case common.PongMessage:
+			logger.Debug(tcpConn.ctx, "tcp client replying with a pong frame")
+		case common.CloseMessage:
+			return 0, nil, io.EOF
+		default:
+			return 0, nil, errors.New("unknown frame type")
+		}
+	}
+}
+
+// WriteMsg queues a frame for the client.
+// args must not be modified by the others goroutines.
+func (tcpConn *TCPConn) WriteMsg(args *common.TWSData) error {
+	tcpConn.Lock()
+	defer tcpConn.Unlock()
+	if tcpConn.closeFlag {
+		return nil
+	}
+
+	if uint32(len(args.Msg)) > tcpConn.msgParser.maxMsgLen {
+		return errors.New("message too long")
+	}
+
+	tcpConn.doWrite(args)
+	return nil
+}
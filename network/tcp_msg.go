@@ -0,0 +1,129 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// MsgParser reads and writes the frames of the TCP transport:
+//
+//	frame  = length type payload
+//	length = lenMsgLen bytes (1, 2 or 4) counting type and payload, big endian unless LittleEndian
+//	type   = 1 byte, the websocket opcode of the frame: text, binary, close, ping or pong (see common)
+//
+// A close payload is a websocket close payload: a 2 byte big endian code and the reason.
+type MsgParser struct {
+	lenMsgLen    int
+	maxMsgLen    uint32
+	littleEndian bool
+}
+
+// NewMsgParser returns a parser of 2 byte lengths and frames up to 4096 bytes.
+func NewMsgParser() *MsgParser {
+	p := new(MsgParser)
+	p.lenMsgLen = 2
+	p.maxMsgLen = 4096
+	p.littleEndian = LittleEndian
+	return p
+}
+
+// SetMsgLen sets the size of the length and the max size of a payload, a zero keeps the current value. The max is
+// lowered to what the length can hold.
+func (p *MsgParser) SetMsgLen(lenMsgLen int, maxMsgLen uint32) {
+	if lenMsgLen == 1 || lenMsgLen == 2 || lenMsgLen == 4 {
+		p.lenMsgLen = lenMsgLen
+	}
+	if maxMsgLen != 0 {
+		p.maxMsgLen = maxMsgLen
+	}
+
+	var max uint32
+	switch p.lenMsgLen {
+	case 1:
+		max = math.MaxUint8
+	case 2:
+		max = math.MaxUint16
+	case 4:
+		max = math.MaxUint32
+	}
+	// the length counts the type byte
+	if p.maxMsgLen > max-1 {
+		p.maxMsgLen = max - 1
+	}
+}
+
+// SetByteOrder sets the byte order of the length.
+func (p *MsgParser) SetByteOrder(littleEndian bool) {
+	p.littleEndian = littleEndian
+}
+
+// Read reads one frame and returns its type and payload.
+func (p *MsgParser) Read(r io.Reader) (int, []byte, error) {
+	bufMsgLen := make([]byte, 4)[:p.lenMsgLen]
+	if _, err := io.ReadFull(r, bufMsgLen); err != nil {
+		return 0, nil, err
+	}
+
+	var msgLen uint32
+	switch p.lenMsgLen {
+	case 1:
+		msgLen = uint32(bufMsgLen[0])
+	case 2:
+		if p.littleEndian {
+			msgLen = uint32(binary.LittleEndian.Uint16(bufMsgLen))
+		} else {
+			msgLen = uint32(binary.BigEndian.Uint16(bufMsgLen))
+		}
+	case 4:
+		if p.littleEndian {
+			msgLen = binary.LittleEndian.Uint32(bufMsgLen)
+		} else {
+			msgLen = binary.BigEndian.Uint32(bufMsgLen)
+		}
+	}
+
+	if msgLen == 0 {
+		return 0, nil, errors.New("frame without a type")
+	}
+	if msgLen-1 > p.maxMsgLen {
+		return 0, nil, errors.New("message too long")
+	}
+
+	msgData := make([]byte, msgLen)
+	if _, err := io.ReadFull(r, msgData); err != nil {
+		return 0, nil, err
+	}
+	return int(msgData[0]), msgData[1:], nil
+}
+
+// Write writes one frame of a type.
+func (p *MsgParser) Write(w io.Writer, msgType int, msg []byte) error {
+	msgLen := uint32(len(msg))
+	if msgLen > p.maxMsgLen {
+		return errors.New("message too long")
+	}
+
+	frame := make([]byte, p.lenMsgLen+1+int(msgLen))
+	switch p.lenMsgLen {
+	case 1:
+		frame[0] = byte(msgLen + 1)
+	case 2:
+		if p.littleEndian {
+			binary.LittleEndian.PutUint16(frame, uint16(msgLen+1))
+		} else {
+			binary.BigEndian.PutUint16(frame, uint16(msgLen+1))
+		}
+	case 4:
+		if p.littleEndian {
+			binary.LittleEndian.PutUint32(frame, msgLen+1)
+		} else {
+			binary.BigEndian.PutUint32(frame, msgLen+1)
+		}
+	}
+	frame[p.lenMsgLen] = byte(msgType)
+	copy(frame[p.lenMsgLen+1:], msg)
+	_, err := w.Write(frame)
+	return err
+}
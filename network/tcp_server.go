@@ -0,0 +1,164 @@
+package network
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/panics"
+)
+
+// TCPServer serves the length prefixed frames of MsgParser, for the clients that do without websocket framing.
+// The first frame of a connection is a text frame holding the url a websocket client would connect to, e.g.
+// /?sendID=u1&token=t&platformID=5&operationID=op0; the frames after it are those of a websocket session.
+type TCPServer struct {
+	Addr             string
+	MaxConnNum       int
+	PendingWriteNum  int
+	LenMsgLen        int
+	MaxMsgLen        uint32
+	HandshakeTimeout time.Duration // for the first frame
+	ReadTimeout      time.Duration // a client sending nothing, not even a pong, for so long is disconnected
+	NewAgent         func(*TCPConn) Agent
+	ln               net.Listener
+	conns            ConnSet
+	mutexConns       sync.Mutex
+	wgLn             sync.WaitGroup
+	wgConns          sync.WaitGroup
+	msgParser        *MsgParser
+}
+
+// Start listens on Addr and serves the connections until Close.
+func (server *TCPServer) Start() {
+	server.init()
+	server.wgLn.Add(1)
+	go server.run()
+}
+
+func (server *TCPServer) init() {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Fatal(context.Background(), "net.listen err", "addr", server.Addr, "err", err)
+	}
+
+	if server.MaxConnNum <= 0 {
+		server.MaxConnNum = 100
+		logger.Info(context.Background(), "invalid MaxConnNum, reset", "maxConnNum", server.MaxConnNum)
+	}
+	if server.PendingWriteNum <= 0 {
+		server.PendingWriteNum = 100
+		logger.Info(context.Background(), "invalid PendingWriteNum, reset", "pendingWriteNum", server.PendingWriteNum)
+	}
+	if server.HandshakeTimeout <= 0 {
+		server.HandshakeTimeout = 10 * time.Second
+		logger.Info(context.Background(), "invalid HandshakeTimeout, reset", "handshakeTimeout", server.HandshakeTimeout)
+	}
+	if server.ReadTimeout <= 0 {
+		server.ReadTimeout = 30 * time.Second
+		logger.Info(context.Background(), "invalid ReadTimeout, reset", "readTimeout", server.ReadTimeout)
+	}
+	if server.NewAgent == nil {
+		logger.Fatal(context.Background(), "NewAgent must not be nil")
+	}
+
+	server.ln = ln
+	server.conns = make(ConnSet)
+
+	msgParser := NewMsgParser()
+	msgParser.SetMsgLen(server.LenMsgLen, server.MaxMsgLen)
+	msgParser.SetByteOrder(LittleEndian)
+	server.msgParser = msgParser
+}
+
+func (server *TCPServer) run() {
+	defer server.wgLn.Done()
+
+	var tempDelay time.Duration
+	for {
+		conn, err := server.ln.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if max := 1 * time.Second; tempDelay > max {
+					tempDelay = max
+				}
+				logger.Warn(context.Background(), "accept error, retrying", "err", err, "delay", tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			return
+		}
+		tempDelay = 0
+
+		server.mutexConns.Lock()
+		if len(server.conns) >= server.MaxConnNum {
+			server.mutexConns.Unlock()
+			// the close frame carries the errCode, there is no session to answer with an event yet
+			_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+			_ = server.msgParser.Write(conn, common.CloseMessage, websocket.FormatCloseMessage(
+				websocket.CloseTryAgainLater, strconv.Itoa(int(errcode.Overloaded))+" too many connections"))
+			conn.Close()
+			logger.Warn(context.Background(), "too many connections", "maxConnNum", server.MaxConnNum)
+			continue
+		}
+		server.conns[conn] = struct{}{}
+		server.mutexConns.Unlock()
+
+		server.wgConns.Add(1)
+		go server.serve(conn)
+	}
+}
+
+// serve reads the connect url of a connection, then runs its agent.
+func (server *TCPServer) serve(conn net.Conn) {
+	defer server.wgConns.Done()
+	defer func() {
+		server.mutexConns.Lock()
+		delete(server.conns, conn)
+		server.mutexConns.Unlock()
+	}()
+	ctx := context.Background()
+	defer panics.Recover(ctx, "tcp handler", nil)
+
+	_ = conn.SetReadDeadline(time.Now().Add(server.HandshakeTimeout))
+	msgType, appURL, err := server.msgParser.Read(conn)
+	if err != nil || msgType != common.MessageText {
+		logger.Error(ctx, "tcp handshake error", "err", err, "msgType", msgType, "remoteAddr", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+	logger.Debug(ctx, "tcp connect", "remoteAddr", conn.RemoteAddr())
+
+	tcpConn := newTCPConn(conn, server.PendingWriteNum, server.msgParser, server.ReadTimeout, string(appURL))
+	agent := server.NewAgent(tcpConn)
+	agent.Run()
+
+	// cleanup
+	tcpConn.Close()
+	agent.OnClose()
+}
+
+// Close stops listening and closes all the connections.
+func (server *TCPServer) Close() {
+	server.ln.Close()
+	server.wgLn.Wait()
+
+	server.mutexConns.Lock()
+	for conn := range server.conns {
+		conn.Close()
+	}
+	server.conns = nil
+	server.mutexConns.Unlock()
+
+	server.wgConns.Wait()
+}
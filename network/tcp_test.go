@@ -0,0 +1,86 @@
+package network
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/common"
+)
+
+// echoAgent writes back the frames it reads.
+type echoAgent struct {
+	conn   Conn
+	closed chan struct{}
+}
+
+func (a *echoAgent) Run() {
+	for {
+		msgType, b, err := a.conn.ReadMsg()
+		if err != nil {
+			return
+		}
+		_ = a.conn.WriteMsg(&common.TWSData{MsgType: msgType, Msg: b})
+	}
+}
+
+func (a *echoAgent) OnClose() { close(a.closed) }
+
+func TestMsgParser(t *testing.T) {
+	p := NewMsgParser()
+	p.SetMsgLen(1, 1024)
+	assert.Equal(t, uint32(254), p.maxMsgLen)
+	var buf bytes.Buffer
+	assert.NoError(t, p.Write(&buf, common.MessageText, []byte("hi")))
+	assert.Equal(t, []byte{3, common.MessageText, 'h', 'i'}, buf.Bytes())
+	msgType, b, err := p.Read(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, common.MessageText, msgType)
+	assert.Equal(t, "hi", string(b))
+	assert.Error(t, p.Write(&buf, common.MessageText, make([]byte, 255)))
+}
+
+func TestTCPServer(t *testing.T) {
+	agents := make(chan *echoAgent, 1)
+	server := &TCPServer{Addr: "127.0.0.1:0", LenMsgLen: 4, MaxMsgLen: 4096, NewAgent: func(conn *TCPConn) Agent {
+		a := &echoAgent{conn: conn, closed: make(chan struct{})}
+		agents <- a
+		return a
+	}}
+	server.Start()
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.ln.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	p := NewMsgParser()
+	p.SetMsgLen(4, 4096)
+	assert.NoError(t, p.Write(conn, common.MessageText, []byte("/?sendID=u1&token=t")))
+	a := <-agents
+	assert.Equal(t, "/?sendID=u1&token=t", a.conn.(*TCPConn).AppURL)
+
+	assert.NoError(t, p.Write(conn, common.MessageText, []byte(`{"reqFuncName":"GetSelfUserInfo"}`)))
+	msgType, b, err := p.Read(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, common.MessageText, msgType)
+	assert.Equal(t, `{"reqFuncName":"GetSelfUserInfo"}`, string(b))
+
+	// pings are answered by the connection, not handed to the agent
+	assert.NoError(t, p.Write(conn, common.PingMessage, []byte("p")))
+	msgType, b, err = p.Read(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, common.PongMessage, msgType)
+	assert.Equal(t, "p", string(b))
+
+	assert.NoError(t, p.Write(conn, common.CloseMessage, nil))
+	select {
+	case <-a.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent not closed")
+	}
+}
@@ -1,10 +1,11 @@
 package network
 
 import (
+	"context"
 	"sync"
 	"time"
 
-	"github.com/go-kratos/kratos/v2/log"
+	"github.com/yrzs/openimwssdk/logger"
 
 	"github.com/gorilla/websocket"
 )
@@ -47,36 +48,29 @@ func (client *WSClient) init() {
 
 	if client.ConnNum <= 0 {
 		client.ConnNum = 1
-		//log.Release("invalid ConnNum, reset to %v", client.ConnNum)
-		log.Info("invalid ConnNum, reset", "client.ConnNum", client.ConnNum)
+		logger.Info(context.Background(), "invalid ConnNum, reset", "connNum", client.ConnNum)
 	}
 	if client.ConnectInterval <= 0 {
 		client.ConnectInterval = 3 * time.Second
-		//log.Release("invalid ConnectInterval, reset to %v", client.ConnectInterval)
-		log.Info("invalid ConnectInterval, reset", "client.ConnectInterval", client.ConnectInterval)
+		logger.Info(context.Background(), "invalid ConnectInterval, reset", "connectInterval", client.ConnectInterval)
 	}
 	if client.PendingWriteNum <= 0 {
 		client.PendingWriteNum = 100
-		//log.Release("invalid PendingWriteNum, reset to %v", client.PendingWriteNum)
-		log.Info("invalid PendingWriteNum, reset", "client.PendingWriteNum", client.PendingWriteNum)
+		logger.Info(context.Background(), "invalid PendingWriteNum, reset", "pendingWriteNum", client.PendingWriteNum)
 	}
 	if client.MaxMsgLen <= 0 {
 		client.MaxMsgLen = MaxMsgLen
-		//log.Release("invalid MaxMsgLen, reset to %v", client.MaxMsgLen)
-		log.Info("invalid MaxMsgLen, reset", "client.MaxMsgLen", client.MaxMsgLen)
+		logger.Info(context.Background(), "invalid MaxMsgLen, reset", "maxMsgLen", client.MaxMsgLen)
 	}
 	if client.HandshakeTimeout <= 0 {
 		client.HandshakeTimeout = 10 * time.Second
-		//log.Release("invalid HandshakeTimeout, reset to %v", client.HandshakeTimeout)
-		log.Info("invalid HandshakeTimeout, reset", "client.HandshakeTimeout", client.HandshakeTimeout)
+		logger.Info(context.Background(), "invalid HandshakeTimeout, reset", "handshakeTimeout", client.HandshakeTimeout)
 	}
 	if client.NewAgent == nil {
-		//log.Fatal("NewAgent must not be nil")
-		log.Fatal("NewAgent must not be nil")
+		logger.Fatal(context.Background(), "NewAgent must not be nil")
 	}
 	if client.conns != nil {
-		//log.Fatal("client is running")
-		log.Fatal("client is running")
+		logger.Fatal(context.Background(), "client is running")
 	}
 
 	client.conns = make(WebsocketConnSet)
@@ -94,8 +88,7 @@ func (client *WSClient) dial() *websocket.Conn {
 			return conn
 		}
 
-		//log.Release("connect to %v error: %v", client.Addr, err)
-		log.Info("connect error", "client.Addr", client.Addr, "err", err)
+		logger.Info(context.Background(), "connect error", "addr", client.Addr, "err", err)
 		time.Sleep(client.ConnectInterval)
 		continue
 	}
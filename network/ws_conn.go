@@ -1,13 +1,14 @@
 package network
 
 import (
+	"context"
 	"errors"
 	"net"
 	"sync"
 
-	"github.com/go-kratos/kratos/v2/log"
 	"github.com/gorilla/websocket"
 	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/logger"
 )
 
 type WebsocketConnSet map[*websocket.Conn]struct{}
@@ -27,22 +28,15 @@ type WSConn struct {
 
 // newWSConn initializes a new WSConn object.
 func newWSConn(conn *websocket.Conn, pendingWriteNum int, maxMsgLen uint32, appurl string, cookieVal string) *WSConn {
-	//log.Error("test4.1", pendingWriteNum)
 	wsConn := new(WSConn)
 	wsConn.conn = conn
 	wsConn.writeChan = make(chan *common.TWSData, pendingWriteNum)
-	//log.Error("test4.1.1", pendingWriteNum)
 	wsConn.maxMsgLen = maxMsgLen
 	//生成唯一session id
-	var sessionID string
-	//log.Error("test4.1.2", pendingWriteNum)
-	//sessionID = common.GetRandomSessionId()
-
-	//log.Error("test4.1.3", pendingWriteNum)
-	wsConn.SessionId = sessionID
+	wsConn.SessionId = common.GenSessionID()
 	wsConn.AppURL = appurl
 	wsConn.CookieVal = cookieVal
-	//log.Error("test4.2")
+	ctx := logger.NewSessionContext(context.Background(), wsConn.SessionId, "")
 	go func() {
 		for b := range wsConn.writeChan {
 			if b == nil {
@@ -54,15 +48,15 @@ func newWSConn(conn *websocket.Conn, pendingWriteNum int, maxMsgLen uint32, appu
 			} else if b.MsgType == common.MessageText {
 				err = conn.WriteMessage(websocket.TextMessage, b.Msg)
 			} else if b.MsgType == common.PingMessage {
-				log.Info("ping message", "b", b)
+				logger.Debug(ctx, "ping message")
 				err = conn.WriteMessage(websocket.PingMessage, b.Msg)
 			} else if b.MsgType == common.CloseMessage {
-				log.Info("close message", "b", b)
+				logger.Debug(ctx, "close message")
 				err = conn.WriteMessage(websocket.CloseMessage, b.Msg)
 				break
 			}
 			if err != nil {
-				log.Error("send message err", "err", err.Error())
+				logger.Error(ctx, "send message err", "err", err)
 				break
 			}
 			//fmt.Println("send msg is :", b)
@@ -73,7 +67,6 @@ func newWSConn(conn *websocket.Conn, pendingWriteNum int, maxMsgLen uint32, appu
 		wsConn.closeFlag = true
 		wsConn.Unlock()
 	}()
-	log.Error("test4.3")
 	return wsConn
 }
 
@@ -111,8 +104,7 @@ func (wsConn *WSConn) Close() {
 // doWrite enqueues a message for writing to the websocket connection.
 func (wsConn *WSConn) doWrite(b *common.TWSData) {
 	if len(wsConn.writeChan) == cap(wsConn.writeChan) {
-		//log.Debug("close conn: channel full")
-		log.Error("close conn: channel full")
+		logger.Error(logger.NewSessionContext(context.Background(), wsConn.SessionId, ""), "close conn: channel full")
 		wsConn.doDestroy()
 		return
 	}
@@ -1,11 +1,14 @@
 package network
 
 import (
+	"context"
 	"crypto/tls"
-	"github.com/go-kratos/kratos/v2/log"
-	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+	"github.com/yrzs/openimwssdk/panics"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -38,7 +41,7 @@ type WSHandler struct {
 
 // ServeHTTP handles HTTP requests and upgrades them to WebSocket if the request is valid.
 func (handler *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	defer common.TryRecoverAndDebugPrint()
+	defer panics.Recover(r.Context(), "ws handler", nil)
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", 405)
 		return
@@ -50,18 +53,17 @@ func (handler *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	//} else {
 	//	cookieVal = cookieToken.Value
 	//}
+	ctx := r.Context()
 	token := r.Header.Get("Authorization")
 	if token != "" && len(token) > 7 {
 		cookieVal = token[7:]
 	} else {
-		log.Info("http的headers Authorization中没有对应token")
+		logger.Debug(ctx, "no token in the Authorization header", "remoteAddr", r.RemoteAddr)
 	}
-	log.Info("token info", "token", cookieVal)
-
-	log.Info("ws url is:", r.URL.Path)
+	logger.Debug(ctx, "ws upgrade", "path", r.URL.Path, "remoteAddr", r.RemoteAddr)
 	conn, err := handler.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Error("upgrade error", "err", err, "remoteIp", r.Host)
+		logger.Error(ctx, "upgrade error", "err", err, "remoteAddr", r.RemoteAddr)
 		return
 	}
 	conn.SetReadLimit(int64(handler.maxMsgLen))
@@ -69,12 +71,11 @@ func (handler *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	conn.SetPongHandler(func(appData string) error {
 		err := conn.SetReadDeadline(time.Now().Add(30 * time.Second))
 		if err != nil {
-			log.Error("set read deadline error", "err", err)
+			logger.Error(ctx, "set read deadline error", "err", err)
 		}
-		log.Info("js replying with a pong packet.")
+		logger.Debug(ctx, "js replying with a pong packet")
 		return nil
 	})
-	log.Error("test1")
 	handler.wg.Add(1)
 	defer handler.wg.Done()
 
@@ -84,20 +85,19 @@ func (handler *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		conn.Close()
 		return
 	}
-	log.Error("test2")
 	if len(handler.conns) >= handler.maxConnNum {
 		handler.mutexConns.Unlock()
+		// the close reason carries the errCode, there is no session to answer with an event yet
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater,
+			strconv.Itoa(int(errcode.Overloaded))+" too many connections"), time.Now().Add(time.Second))
 		conn.Close()
-		log.Error("too many connections")
+		logger.Warn(ctx, "too many connections", "maxConnNum", handler.maxConnNum)
 		return
 	}
-	log.Error("test3")
 	handler.conns[conn] = struct{}{}
 	handler.mutexConns.Unlock()
 
-	log.Error("test4")
 	wsConn := newWSConn(conn, handler.pendingWriteNum, handler.maxMsgLen, r.URL.String(), cookieVal)
-	log.Error("tes5")
 	agent := handler.newAgent(wsConn)
 	agent.Run()
 
@@ -113,33 +113,27 @@ func (handler *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (server *WSServer) Start() {
 	ln, err := net.Listen("tcp", server.Addr)
 	if err != nil {
-		//log.Fatal("%v", err)
-		log.Fatal("net.listen err", "err", err)
+		logger.Fatal(context.Background(), "net.listen err", "addr", server.Addr, "err", err)
 	}
 
 	if server.MaxConnNum <= 0 {
 		server.MaxConnNum = 100
-		//log.Release("invalid MaxConnNum, reset to %v", server.MaxConnNum)
-		log.Info("invalid MaxConnNum,reset", "server.MaxConnNum", server.MaxConnNum)
+		logger.Info(context.Background(), "invalid MaxConnNum, reset", "maxConnNum", server.MaxConnNum)
 	}
 	if server.PendingWriteNum <= 0 {
 		server.PendingWriteNum = 100
-		//log.Release("invalid PendingWriteNum, reset to %v", server.PendingWriteNum)
-		log.Info("invalid PendingWriteNum,reset", "server.PendingWriteNum", server.PendingWriteNum)
+		logger.Info(context.Background(), "invalid PendingWriteNum, reset", "pendingWriteNum", server.PendingWriteNum)
 	}
 	if server.MaxMsgLen <= 0 {
 		server.MaxMsgLen = 4096
-		//log.Release("invalid MaxMsgLen, reset to %v", server.MaxMsgLen)
-		log.Info("invalid MaxMsgLen,reset", "server.MaxMsgLen", server.MaxMsgLen)
+		logger.Info(context.Background(), "invalid MaxMsgLen, reset", "maxMsgLen", server.MaxMsgLen)
 	}
 	if server.HTTPTimeout <= 0 {
 		server.HTTPTimeout = 10 * time.Second
-		//log.Release("invalid HTTPTimeout, reset to %v", server.HTTPTimeout)
-		log.Info("invalid HTTPTimeout,reset", "server.HTTPTimeout", server.HTTPTimeout)
+		logger.Info(context.Background(), "invalid HTTPTimeout, reset", "httpTimeout", server.HTTPTimeout)
 	}
 	if server.NewAgent == nil {
-		//log.Fatal("NewAgent must not be nil")
-		log.Fatal("NewAgent must not be nil")
+		logger.Fatal(context.Background(), "NewAgent must not be nil")
 	}
 
 	if server.CertFile != "" || server.KeyFile != "" {
@@ -150,8 +144,7 @@ func (server *WSServer) Start() {
 		config.Certificates = make([]tls.Certificate, 1)
 		config.Certificates[0], err = tls.LoadX509KeyPair(server.CertFile, server.KeyFile)
 		if err != nil {
-			//log.Fatal("%v", err)
-			log.Fatal("cerfiti file error", "err", err)
+			logger.Fatal(context.Background(), "certificate file error", "err", err)
 		}
 
 		ln = tls.NewListener(ln, config)
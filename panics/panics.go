@@ -0,0 +1,173 @@
+// Package panics contains the panics of the gateway to the session they happen in: Recover logs and counts them
+// instead of letting the process die, and a method that keeps panicking is disabled for a while.
+package panics
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yrzs/openimwssdk/common"
+	"github.com/yrzs/openimwssdk/errcode"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+// Config is the crash-loop guard of the methods. A method is disabled for every client, so the panics must come
+// from Clients distinct clients: one client sending the same bad request again and again cannot disable a method
+// the others use.
+type Config struct {
+	Limit    int           // panics of a method within Window disabling it, 0 never disables
+	Clients  int           // distinct clients among those panics, at most 1 counts the panics of any client
+	Window   time.Duration // span the panics are counted over
+	Cooldown time.Duration // how long a method stays disabled
+}
+
+// DefaultConfig disables a method panicking 5 times for 3 clients within a minute for 10 minutes.
+var DefaultConfig = Config{Limit: 5, Clients: 3, Window: time.Minute, Cooldown: 10 * time.Minute}
+
+// Stats are the panics recovered since the start.
+type Stats struct {
+	Total    int64                `json:"total"`
+	ByWhere  map[string]int64     `json:"byWhere"`  // e.g. "actor", "method GetUsersInfo"
+	Disabled map[string]time.Time `json:"disabled"` // method -> end of its cooldown
+	Last     *Panic               `json:"last,omitempty"`
+}
+
+// Panic is one recovered panic.
+type Panic struct {
+	Time  time.Time `json:"time"`
+	Where string    `json:"where"`
+	Value string    `json:"value"`
+	Stack string    `json:"stack"`
+}
+
+var (
+	mu       sync.Mutex
+	cfg      = DefaultConfig
+	total    int64
+	byWhere  = make(map[string]int64)
+	methods  = make(map[string]map[string][]time.Time) // method -> client -> times of its panics within the window
+	disabled = make(map[string]time.Time)
+	last     *Panic
+)
+
+// Init sets the crash-loop guard and forgets the state of the methods.
+func Init(c Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = c
+	methods = make(map[string]map[string][]time.Time)
+	disabled = make(map[string]time.Time)
+}
+
+// Recover recovers a panic of the goroutine and reports it, it must be deferred directly. onPanic, when not nil,
+// is called after the report, to close the session the goroutine serves.
+func Recover(ctx context.Context, where string, onPanic func(r any)) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	Report(ctx, where, r, debug.Stack())
+	if onPanic != nil {
+		defer Recover(ctx, where+" cleanup", nil)
+		onPanic(r)
+	}
+}
+
+// Report logs and counts a recovered panic.
+func Report(ctx context.Context, where string, r any, stack []byte) {
+	logger.Error(ctx, "panic recovered", "where", where, "panic", r, "stack", string(stack))
+	mu.Lock()
+	defer mu.Unlock()
+	total++
+	byWhere[where]++
+	last = &Panic{Time: time.Now(), Where: where, Value: fmt.Sprint(r), Stack: string(stack)}
+}
+
+// MethodPanicked reports a panic of a method called by a client, the user or the session before login, to the
+// crash-loop guard. It returns true when the method gets disabled.
+func MethodPanicked(method, client string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if cfg.Limit <= 0 {
+		return false
+	}
+	now := time.Now()
+	clients := methods[method]
+	if clients == nil {
+		clients = make(map[string][]time.Time)
+		methods[method] = clients
+	}
+	clients[client] = append(clients[client], now)
+	n := 0
+	for c, times := range clients {
+		for len(times) > 0 && now.Sub(times[0]) > cfg.Window {
+			times = times[1:]
+		}
+		if len(times) > cfg.Limit { // more panics of one client never count
+			times = times[len(times)-cfg.Limit:]
+		}
+		if len(times) == 0 {
+			delete(clients, c)
+			continue
+		}
+		clients[c] = times
+		n += len(times)
+	}
+	if n < cfg.Limit || len(clients) < cfg.Clients {
+		return false
+	}
+	delete(methods, method)
+	disabled[method] = now.Add(cfg.Cooldown)
+	return true
+}
+
+// Disabled returns the end of the cooldown of a disabled method.
+func Disabled(method string) (time.Time, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	until, ok := disabled[method]
+	if ok && !time.Now().Before(until) {
+		delete(disabled, method)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// Enable ends the cooldown of a method, it returns false when the method was not disabled.
+func Enable(method string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := disabled[method]
+	delete(disabled, method)
+	delete(methods, method)
+	return ok
+}
+
+// ReadStats returns the panics recovered so far and the disabled methods.
+func ReadStats() *Stats {
+	mu.Lock()
+	defer mu.Unlock()
+	s := &Stats{Total: total, ByWhere: make(map[string]int64, len(byWhere)), Disabled: make(map[string]time.Time),
+		Last: last}
+	for k, v := range byWhere {
+		s.ByWhere[k] = v
+	}
+	now := time.Now()
+	for k, until := range disabled {
+		if now.Before(until) {
+			s.Disabled[k] = until
+		}
+	}
+	return s
+}
+
+// CloseMessage is the close frame ending a session after a panic, with the internal error close code.
+func CloseMessage() *common.TWSData {
+	return &common.TWSData{MsgType: common.CloseMessage, Msg: websocket.FormatCloseMessage(websocket.CloseInternalServerErr,
+		strconv.Itoa(int(errcode.Panic))+" internal error")}
+}
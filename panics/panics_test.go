@@ -0,0 +1,64 @@
+package panics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodPanicked(t *testing.T) {
+	Init(Config{Limit: 3, Window: time.Minute, Cooldown: time.Minute})
+	t.Cleanup(func() { Init(DefaultConfig) })
+	assert.False(t, MethodPanicked("A", "u1"))
+	assert.False(t, MethodPanicked("A", "u1"))
+	_, ok := Disabled("A")
+	assert.False(t, ok)
+	assert.True(t, MethodPanicked("A", "u1"))
+	until, ok := Disabled("A")
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), until, time.Second)
+	assert.Contains(t, ReadStats().Disabled, "A")
+
+	assert.True(t, Enable("A"))
+	assert.False(t, Enable("A"))
+	_, ok = Disabled("A")
+	assert.False(t, ok)
+
+	Init(Config{Limit: 1, Window: time.Minute, Cooldown: time.Millisecond})
+	assert.True(t, MethodPanicked("B", "u1"))
+	time.Sleep(5 * time.Millisecond)
+	_, ok = Disabled("B")
+	assert.False(t, ok)
+}
+
+func TestMethodPanickedClients(t *testing.T) {
+	Init(Config{Limit: 3, Clients: 2, Window: time.Minute, Cooldown: time.Minute})
+	t.Cleanup(func() { Init(DefaultConfig) })
+	for i := 0; i < 10; i++ {
+		assert.False(t, MethodPanicked("A", "u1"))
+	}
+	_, ok := Disabled("A")
+	assert.False(t, ok)
+	assert.True(t, MethodPanicked("A", "u2"))
+	_, ok = Disabled("A")
+	assert.True(t, ok)
+}
+
+func TestRecover(t *testing.T) {
+	before := ReadStats().ByWhere["test"]
+	var got any
+	func() {
+		defer Recover(context.Background(), "test", func(r any) {
+			got = r
+			panic("again")
+		})
+		panic("boom")
+	}()
+	assert.Equal(t, "boom", got)
+	stats := ReadStats()
+	assert.Equal(t, before+1, stats.ByWhere["test"])
+	assert.Equal(t, int64(1), stats.ByWhere["test cleanup"])
+	assert.Equal(t, "again", stats.Last.Value)
+}
@@ -0,0 +1,254 @@
+// Package record writes the frames of the sessions of chosen users to files, so a client bug report can be
+// replayed against a gateway with cmd/replay. Every file is one session: a DirOpen line, then a line per
+// inbound Req and outbound EventData in the order the gateway handled them.
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DirOpen = "open" // first line of a file, it describes the session
+	DirIn   = "in"   // a frame received from the client
+	DirOut  = "out"  // a frame sent to the client
+
+	// RedactedToken replaces the token of a recorded Login request, cmd/replay -token sends a valid one instead.
+	RedactedToken = "<redacted>"
+
+	DefaultMaxSize = 100 * 1024 * 1024
+	fileSuffix     = ".jsonl"
+	fileTimeFmt    = "20060102T150405.000"
+)
+
+var (
+	ErrInvalidUserID = errors.New("invalid userID")
+	ErrDisabled      = errors.New("recording is disabled")
+)
+
+// Entry is one line of a recording.
+type Entry struct {
+	Time        int64  `json:"time"` // unix milliseconds
+	Dir         string `json:"dir"`
+	Msg         string `json:"msg,omitempty"` // text of the frame
+	UserID      string `json:"userID,omitempty"`
+	PlatformID  string `json:"platformID,omitempty"`
+	SessionId   string `json:"sessionId,omitempty"`
+	OperationID string `json:"operationID,omitempty"` // of the connect url
+}
+
+// Config of the recordings.
+type Config struct {
+	Dir     string   // recordings go to Dir/<userID>/
+	Users   []string // recorded from the start, more can be added with Start
+	MaxSize int64    // bytes of one recording, the frames after it are dropped
+}
+
+var (
+	mu    sync.RWMutex
+	conf  Config
+	users = make(map[string]bool)
+)
+
+// Init sets the recording directory, an empty dir leaves recording disabled.
+func Init(c Config) error {
+	if c.Dir != "" {
+		if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+			return err
+		}
+	} else {
+		c.Users = nil
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = DefaultMaxSize
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	conf = c
+	users = make(map[string]bool)
+	for _, userID := range c.Users {
+		if validUserID(userID) {
+			users[userID] = true
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether Init set a recording directory.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return conf.Dir != ""
+}
+
+// Start records the sessions of a user, the current one from its next frame on.
+func Start(userID string) error {
+	if !validUserID(userID) {
+		return ErrInvalidUserID
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conf.Dir == "" {
+		return ErrDisabled
+	}
+	users[userID] = true
+	return nil
+}
+
+// Stop ends the recording of a user.
+func Stop(userID string) {
+	mu.Lock()
+	delete(users, userID)
+	mu.Unlock()
+}
+
+// Recording reports whether the sessions of a user are recorded.
+func Recording(userID string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return users[userID]
+}
+
+// Users returns the recorded users, sorted.
+func Users() []string {
+	mu.RLock()
+	ret := make([]string, 0, len(users))
+	for userID := range users {
+		ret = append(ret, userID)
+	}
+	mu.RUnlock()
+	sort.Strings(ret)
+	return ret
+}
+
+func validUserID(userID string) bool {
+	return userID != "" && userID != "." && userID != ".." && !strings.ContainsAny(userID, "/\\\x00")
+}
+
+// Recorder writes the frames of one session.
+type Recorder struct {
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	maxSize int64
+	full    bool
+}
+
+// Open creates the recording of a session in Dir/<userID>/, named after its start time and sessionId.
+func Open(userID, platformID, sessionId, operationID string) (*Recorder, error) {
+	if !validUserID(userID) {
+		return nil, ErrInvalidUserID
+	}
+	mu.RLock()
+	c := conf
+	mu.RUnlock()
+	if c.Dir == "" {
+		return nil, ErrDisabled
+	}
+	dir := filepath.Join(c.Dir, userID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s%s", now.Format(fileTimeFmt), sanitize(sessionId), fileSuffix)
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{f: f, maxSize: c.MaxSize}
+	if err := r.write(&Entry{Time: now.UnixMilli(), Dir: DirOpen, UserID: userID, PlatformID: platformID,
+		SessionId: sessionId, OperationID: operationID}); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Name returns the path of the recording.
+func (r *Recorder) Name() string {
+	return r.f.Name()
+}
+
+// In records a frame received from the client, the token of a Login request is redacted.
+func (r *Recorder) In(msg []byte) error {
+	return r.write(&Entry{Time: time.Now().UnixMilli(), Dir: DirIn, Msg: string(redactLogin(msg))})
+}
+
+// redactLogin returns a Login request with RedactedToken as the token of its args, other frames as they are.
+func redactLogin(msg []byte) []byte {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return msg
+	}
+	var name, data string
+	if json.Unmarshal(req["reqFuncName"], &name) != nil || name != "Login" || json.Unmarshal(req["data"], &data) != nil {
+		return msg
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &args); err != nil || len(args) < 2 {
+		return msg
+	}
+	args[1] = marshal(RedactedToken)
+	req["data"] = marshal(string(marshal(args)))
+	return marshal(req)
+}
+
+// marshal encodes v without escaping <, > and &, v is known to be valid.
+func marshal(v any) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+// Out records a frame sent to the client.
+func (r *Recorder) Out(msg []byte) error {
+	return r.write(&Entry{Time: time.Now().UnixMilli(), Dir: DirOut, Msg: string(msg)})
+}
+
+// Close closes the file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// write appends one line, once MaxSize is reached the recording stops growing.
+func (r *Recorder) write(e *Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full {
+		return nil
+	}
+	if r.maxSize > 0 && r.size+int64(len(line)) > r.maxSize {
+		r.full = true
+		return fmt.Errorf("recording %s reached %d bytes, the next frames are dropped", r.f.Name(), r.maxSize)
+	}
+	n, err := r.f.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// sanitize keeps a session id usable in a file name.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, s)
+}
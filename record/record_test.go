@@ -0,0 +1,44 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	assert.ErrorIs(t, Start("u1"), ErrDisabled)
+	assert.Nil(t, Init(Config{Dir: t.TempDir(), Users: []string{"u1", "../x"}, MaxSize: 400}))
+	t.Cleanup(func() { _ = Init(Config{}) })
+	assert.True(t, Enabled())
+	assert.Equal(t, []string{"u1"}, Users())
+	assert.ErrorIs(t, Start("a/b"), ErrInvalidUserID)
+	assert.Nil(t, Start("u2"))
+	Stop("u1")
+	assert.False(t, Recording("u1"))
+	assert.True(t, Recording("u2"))
+
+	r, err := Open("u2", "5", "s1", "op")
+	assert.Nil(t, err)
+	assert.Nil(t, r.In([]byte(`{"reqFuncName":"Login"}`)))
+	assert.Nil(t, r.Out([]byte(`{"event":"Login"}`)))
+	assert.NotNil(t, r.Out(make([]byte, 400))) // over MaxSize
+	assert.Nil(t, r.Out([]byte(`{}`)))         // dropped quietly
+	assert.Nil(t, r.Close())
+
+	entries, err := ReadFile(r.Name())
+	assert.Nil(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, &Entry{Time: entries[0].Time, Dir: DirOpen, UserID: "u2", PlatformID: "5", SessionId: "s1",
+		OperationID: "op"}, entries[0])
+	assert.Equal(t, DirIn, entries[1].Dir)
+	assert.Equal(t, `{"event":"Login"}`, entries[2].Msg)
+}
+
+func TestRedactLogin(t *testing.T) {
+	assert.JSONEq(t, `{"reqFuncName":"Login","operationID":"op1","data":"[\"u1\",\"<redacted>\"]"}`,
+		string(redactLogin([]byte(`{"reqFuncName":"Login","operationID":"op1","data":"[\"u1\",\"secret\"]"}`))))
+	other := `{"reqFuncName":"GetUsersInfo","data":"[[\"u1\",\"secret\"]]"}`
+	assert.Equal(t, other, string(redactLogin([]byte(other))))
+	assert.Equal(t, "not json", string(redactLogin([]byte("not json"))))
+}
@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Memory is a Registry within one process, the default of a single node. Nodes sharing one Memory see each other,
+// which is how the cross-node commands are tested.
+type Memory struct {
+	mu        sync.Mutex
+	entries   map[string]*Entry
+	listeners map[string]func(cmd *Command)
+	handling  sync.WaitGroup // commands being handled
+}
+
+// NewMemory returns an empty registry.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]*Entry), listeners: make(map[string]func(cmd *Command))}
+}
+
+func (m *Memory) Claim(_ context.Context, e *Entry) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.entries[e.UserID]
+	entry := *e
+	m.entries[e.UserID] = &entry
+	return prev, nil
+}
+
+func (m *Memory) Release(_ context.Context, userID string, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[userID]; ok && e.SessionID == sessionID {
+		delete(m.entries, userID)
+	}
+	return nil
+}
+
+func (m *Memory) Lookup(_ context.Context, userID string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	entry := *e
+	return &entry, nil
+}
+
+func (m *Memory) List(_ context.Context) ([]*Entry, error) {
+	m.mu.Lock()
+	ret := make([]*Entry, 0, len(m.entries))
+	for _, e := range m.entries {
+		entry := *e
+		ret = append(ret, &entry)
+	}
+	m.mu.Unlock()
+	sort.Slice(ret, func(i, j int) bool { return ret[i].UserID < ret[j].UserID })
+	return ret, nil
+}
+
+// Send hands the command to the listeners in their own goroutines, as a message broker would.
+func (m *Memory) Send(_ context.Context, node string, cmd *Command) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sent := false
+	for n, handle := range m.listeners {
+		if node == "" || n == node {
+			c := *cmd
+			m.handling.Add(1)
+			go func() {
+				defer m.handling.Done()
+				handle(&c)
+			}()
+			sent = true
+		}
+	}
+	if !sent {
+		return ErrNoListener
+	}
+	return nil
+}
+
+func (m *Memory) Listen(node string, handle func(cmd *Command)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners[node] = handle
+	return nil
+}
+
+// Close forgets the listeners of every node and waits for the commands being handled, the entries stay.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	m.listeners = make(map[string]func(cmd *Command))
+	m.mu.Unlock()
+	m.handling.Wait()
+	return nil
+}
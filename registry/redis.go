@@ -0,0 +1,480 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const (
+	DefaultRedisPrefix = "oimws:"
+	DefaultNodeTTL     = 15 * time.Second
+	redisIdleConns     = 4
+	releaseRetries     = 8
+)
+
+// RedisConfig is the server a Redis registry connects to.
+type RedisConfig struct {
+	Addr        string
+	Password    string
+	DB          int
+	Prefix      string        // of the keys and channels, DefaultRedisPrefix when empty
+	NodeTTL     time.Duration // a node not refreshing its key within NodeTTL is gone, Lookup and List drop its entries
+	DialTimeout time.Duration
+}
+
+// Redis is a Registry kept in a Redis server, or anything speaking its protocol. The entry of a user is the JSON
+// string at <prefix>session:<userID>, the users with an entry are the set <prefix>users, a live node refreshes
+// <prefix>node:<node> and the commands are published on <prefix>cmd:<node> and <prefix>cmd.
+type Redis struct {
+	cfg    RedisConfig
+	idle   chan *respConn
+	ctx    context.Context // of the listen goroutines, done on Close
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	node   string
+	sub    *respConn
+}
+
+// NewRedis returns a registry on the server of cfg, it connects on the first use.
+func NewRedis(cfg RedisConfig) *Redis {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultRedisPrefix
+	}
+	if cfg.NodeTTL <= 0 {
+		cfg.NodeTTL = DefaultNodeTTL
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Redis{cfg: cfg, idle: make(chan *respConn, redisIdleConns), ctx: ctx, cancel: cancel}
+}
+
+func (r *Redis) sessionKey(userID string) string { return r.cfg.Prefix + "session:" + userID }
+func (r *Redis) usersKey() string                { return r.cfg.Prefix + "users" }
+func (r *Redis) nodeKey(node string) string      { return r.cfg.Prefix + "node:" + node }
+
+func (r *Redis) channel(node string) string {
+	if node == "" {
+		return r.cfg.Prefix + "cmd"
+	}
+	return r.cfg.Prefix + "cmd:" + node
+}
+
+// with runs fn on an idle connection or a new one. A connection fn fails on is dropped, it may be left within a
+// transaction.
+func (r *Redis) with(ctx context.Context, fn func(c *respConn) error) error {
+	if r.ctx.Err() != nil {
+		return errClosed
+	}
+	var c *respConn
+	select {
+	case c = <-r.idle:
+	default:
+		var err error
+		if c, err = dialResp(ctx, &r.cfg); err != nil {
+			return err
+		}
+	}
+	if err := fn(c); err != nil {
+		c.close()
+		return err
+	}
+	select {
+	case r.idle <- c:
+	default:
+		c.close()
+	}
+	return nil
+}
+
+func (r *Redis) Claim(ctx context.Context, e *Entry) (*Entry, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var prev *Entry
+	err = r.with(ctx, func(c *respConn) error {
+		if _, err := c.do(ctx, "MULTI"); err != nil {
+			return err
+		}
+		for _, cmd := range [][]string{{"GET", r.sessionKey(e.UserID)}, {"SET", r.sessionKey(e.UserID), string(data)},
+			{"SADD", r.usersKey(), e.UserID}} {
+			if _, err := c.do(ctx, cmd...); err != nil {
+				_, _ = c.do(ctx, "DISCARD")
+				return err
+			}
+		}
+		reply, err := c.do(ctx, "EXEC")
+		if err != nil {
+			return err
+		}
+		results, ok := reply.([]any)
+		if !ok || len(results) == 0 {
+			return errors.New("claim: transaction aborted")
+		}
+		prev = decodeEntry(results[0])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prev, nil
+}
+
+// Release deletes the entry in a transaction watching it, so that a claim of another node in between is kept.
+func (r *Redis) Release(ctx context.Context, userID string, sessionID string) error {
+	return r.with(ctx, func(c *respConn) error {
+		return r.drop(ctx, c, userID, func(e *Entry, _ bool) bool { return e != nil && e.SessionID == sessionID })
+	})
+}
+
+// stale holds for a member of the users set without entry and for the entry of a node that is gone.
+func stale(e *Entry, live bool) bool {
+	return e == nil || !live
+}
+
+// drop deletes the entry of userID and its member of the users set when match holds for the entry, nil when there
+// is none, and whether its node is live. It runs in a transaction watching the entry and the key of its node, so a
+// claim or a node coming back in between is kept.
+func (r *Redis) drop(ctx context.Context, c *respConn, userID string, match func(e *Entry, live bool) bool) error {
+	key := r.sessionKey(userID)
+	for i := 0; i < releaseRetries; i++ {
+		if _, err := c.do(ctx, "WATCH", key); err != nil {
+			return err
+		}
+		reply, err := c.do(ctx, "GET", key)
+		if err != nil {
+			return err
+		}
+		e, live := decodeEntry(reply), false
+		if e != nil {
+			if _, err := c.do(ctx, "WATCH", r.nodeKey(e.Node)); err != nil {
+				return err
+			}
+			if reply, err = c.do(ctx, "EXISTS", r.nodeKey(e.Node)); err != nil {
+				return err
+			}
+			live = reply == int64(1)
+		}
+		if !match(e, live) {
+			_, err = c.do(ctx, "UNWATCH")
+			return err
+		}
+		if _, err := c.do(ctx, "MULTI"); err != nil {
+			return err
+		}
+		if _, err := c.do(ctx, "DEL", key); err != nil {
+			_, _ = c.do(ctx, "DISCARD")
+			return err
+		}
+		if _, err := c.do(ctx, "SREM", r.usersKey(), userID); err != nil {
+			_, _ = c.do(ctx, "DISCARD")
+			return err
+		}
+		if reply, err = c.do(ctx, "EXEC"); err != nil || reply != nil {
+			return err
+		}
+		// the entry or its node changed in between, look again
+	}
+	return errors.New("drop: too many concurrent changes")
+}
+
+func (r *Redis) Lookup(ctx context.Context, userID string) (*Entry, error) {
+	var e *Entry
+	err := r.with(ctx, func(c *respConn) error {
+		reply, err := c.do(ctx, "GET", r.sessionKey(userID))
+		if err != nil {
+			return err
+		}
+		if e = decodeEntry(reply); e == nil {
+			return nil
+		}
+		live, err := c.do(ctx, "EXISTS", r.nodeKey(e.Node))
+		if err != nil {
+			return err
+		}
+		if live != int64(1) {
+			e = nil
+			return r.drop(ctx, c, userID, stale)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	return e, nil
+}
+
+func (r *Redis) List(ctx context.Context) ([]*Entry, error) {
+	var ret []*Entry
+	err := r.with(ctx, func(c *respConn) error {
+		reply, err := c.do(ctx, "SMEMBERS", r.usersKey())
+		if err != nil {
+			return err
+		}
+		users, _ := reply.([]any)
+		if len(users) == 0 {
+			return nil
+		}
+		keys := []string{"MGET"}
+		for _, u := range users {
+			keys = append(keys, r.sessionKey(toString(u)))
+		}
+		if reply, err = c.do(ctx, keys...); err != nil {
+			return err
+		}
+		values, _ := reply.([]any)
+		var nodes, orphans []string
+		seen := make(map[string]bool)
+		for i, v := range values {
+			if e := decodeEntry(v); e != nil {
+				ret = append(ret, e)
+				if !seen[e.Node] {
+					seen[e.Node] = true
+					nodes = append(nodes, e.Node)
+				}
+			} else if i < len(users) {
+				orphans = append(orphans, toString(users[i]))
+			}
+		}
+		if len(nodes) == 0 {
+			return r.dropAll(ctx, c, orphans)
+		}
+		keys = []string{"MGET"}
+		for _, node := range nodes {
+			keys = append(keys, r.nodeKey(node))
+		}
+		if reply, err = c.do(ctx, keys...); err != nil {
+			return err
+		}
+		alive, _ := reply.([]any)
+		live := make(map[string]bool)
+		for i, v := range alive {
+			live[nodes[i]] = v != nil
+		}
+		entries := ret[:0]
+		for _, e := range ret {
+			if live[e.Node] {
+				entries = append(entries, e)
+			} else {
+				orphans = append(orphans, e.UserID)
+			}
+		}
+		ret = entries
+		return r.dropAll(ctx, c, orphans)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].UserID < ret[j].UserID })
+	return ret, nil
+}
+
+// dropAll drops the stale entries of users, which List found without entry or with a node that is gone.
+func (r *Redis) dropAll(ctx context.Context, c *respConn, users []string) error {
+	for _, userID := range users {
+		if err := r.drop(ctx, c, userID, stale); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Redis) Send(ctx context.Context, node string, cmd *Command) error {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	return r.with(ctx, func(c *respConn) error {
+		reply, err := c.do(ctx, "PUBLISH", r.channel(node), string(data))
+		if err != nil {
+			return err
+		}
+		if reply == int64(0) {
+			return ErrNoListener
+		}
+		return nil
+	})
+}
+
+// Listen sets the key of the node and subscribes to its channels, then keeps both up in the background.
+func (r *Redis) Listen(node string, handle func(cmd *Command)) error {
+	ctx, cancel := context.WithTimeout(r.ctx, r.cfg.DialTimeout)
+	defer cancel()
+	if err := r.refresh(ctx, node); err != nil {
+		return err
+	}
+	sub, err := r.subscribe(ctx, node)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.node, r.sub = node, sub
+	r.mu.Unlock()
+	r.wg.Add(2)
+	go r.keepAlive(node)
+	go r.receive(node, sub, handle)
+	return nil
+}
+
+func (r *Redis) refresh(ctx context.Context, node string) error {
+	return r.with(ctx, func(c *respConn) error {
+		_, err := c.do(ctx, "SET", r.nodeKey(node), strconv.FormatInt(time.Now().UnixMilli(), 10),
+			"PX", strconv.FormatInt(r.cfg.NodeTTL.Milliseconds(), 10))
+		return err
+	})
+}
+
+// keepAlive refreshes the key of the node three times per NodeTTL.
+func (r *Redis) keepAlive(node string) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.cfg.NodeTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(r.ctx, r.cfg.NodeTTL/3)
+			if err := r.refresh(ctx, node); err != nil && r.ctx.Err() == nil {
+				logger.Warn(ctx, "registry node refresh error", "node", node, "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (r *Redis) subscribe(ctx context.Context, node string) (*respConn, error) {
+	c, err := dialResp(ctx, &r.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.write("SUBSCRIBE", r.channel(node), r.channel("")); err != nil {
+		c.close()
+		return nil, err
+	}
+	for i := 0; i < 2; i++ { // the confirmations of both channels
+		if _, err := c.read(); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	_ = c.conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// receive hands the published commands to handle, subscribing again when the connection breaks.
+func (r *Redis) receive(node string, sub *respConn, handle func(cmd *Command)) {
+	defer r.wg.Done()
+	for {
+		reply, err := sub.read()
+		if err != nil {
+			sub.close()
+			if r.ctx.Err() != nil {
+				return
+			}
+			logger.Warn(r.ctx, "registry subscription broken", "node", node, "err", err)
+			if sub = r.resubscribe(node); sub == nil {
+				return
+			}
+			continue
+		}
+		msg, ok := reply.([]any)
+		if !ok || len(msg) != 3 || msg[0] != "message" {
+			continue
+		}
+		cmd := &Command{}
+		if err := json.Unmarshal([]byte(toString(msg[2])), cmd); err != nil {
+			logger.Warn(r.ctx, "registry command malformed", "err", err)
+			continue
+		}
+		handle(cmd)
+	}
+}
+
+// resubscribe retries every second until it subscribes or the registry is closed, then it returns nil.
+func (r *Redis) resubscribe(node string) *respConn {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(r.ctx, r.cfg.DialTimeout)
+		sub, err := r.subscribe(ctx, node)
+		cancel()
+		if err == nil {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.ctx.Err() != nil { // closed meanwhile
+				sub.close()
+				return nil
+			}
+			r.sub = sub
+			return sub
+		}
+	}
+}
+
+// Close deletes the key of the node, so its entries are ignored at once, and closes the connections.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	node := r.node
+	r.mu.Unlock()
+	var err error
+	if node != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), r.cfg.DialTimeout)
+		err = r.with(ctx, func(c *respConn) error {
+			_, err := c.do(ctx, "DEL", r.nodeKey(node))
+			return err
+		})
+		cancel()
+	}
+	r.mu.Lock()
+	r.cancel()
+	if r.sub != nil {
+		r.sub.close()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	for {
+		select {
+		case c := <-r.idle:
+			c.close()
+		default:
+			return err
+		}
+	}
+}
+
+// decodeEntry decodes a bulk string reply, nil for a null or a malformed entry.
+func decodeEntry(reply any) *Entry {
+	s, ok := reply.(string)
+	if !ok {
+		return nil
+	}
+	e := &Entry{}
+	if json.Unmarshal([]byte(s), e) != nil {
+		return nil
+	}
+	return e
+}
+
+func toString(reply any) string {
+	s, _ := reply.(string)
+	return s
+}
@@ -0,0 +1,59 @@
+// Package registry tracks which gateway node owns the session of each user, so that several nodes behind a load
+// balancer keep one session per user, and carries the kick and notice commands to the node owning a session.
+package registry
+
+import (
+	"context"
+	"errors"
+)
+
+// Command types.
+const (
+	CmdRelease = "release" // the user connected to another node, close the session SessionID
+	CmdKick    = "kick"    // Data is the reason
+	CmdNotice  = "notice"  // Data is the notice, to every session of the node when UserID is empty
+)
+
+var (
+	ErrNotFound   = errors.New("session not registered")
+	ErrNoListener = errors.New("no node listens")
+	errClosed     = errors.New("registry closed")
+)
+
+// Entry is the session of a user as registered by the node owning it.
+type Entry struct {
+	UserID      string `json:"userID"`
+	SessionID   string `json:"sessionId"`
+	PlatformID  string `json:"platformID"`
+	Node        string `json:"node"`
+	RemoteAddr  string `json:"remoteAddr"`
+	ConnectTime int64  `json:"connectTime"` // unix milliseconds
+}
+
+// Command is sent by a node to the node owning a session, or to every node.
+type Command struct {
+	Type      string `json:"type"`
+	From      string `json:"from"` // node sending it
+	UserID    string `json:"userID"`
+	SessionID string `json:"sessionId,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// Registry is shared by the gateway nodes.
+type Registry interface {
+	// Claim registers the session as the one of its user and returns the entry it replaces, nil when there was none.
+	Claim(ctx context.Context, e *Entry) (*Entry, error)
+	// Release drops the entry of the user if it is still the one of the session.
+	Release(ctx context.Context, userID string, sessionID string) error
+	// Lookup returns the entry of a user, ErrNotFound when the user has no session on a live node.
+	Lookup(ctx context.Context, userID string) (*Entry, error)
+	// List returns the entries of the sessions on the live nodes.
+	List(ctx context.Context) ([]*Entry, error)
+	// Send delivers a command to a node, to every node when node is empty. It returns ErrNoListener when no node
+	// got it.
+	Send(ctx context.Context, node string, cmd *Command) error
+	// Listen marks the node live and hands it the commands sent to it or to every node, until Close.
+	Listen(node string, handle func(cmd *Command)) error
+	// Close stops listening and marks the node gone.
+	Close() error
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/fakeredis"
+)
+
+// testRegistry runs the same checks on two nodes sharing a backend.
+func testRegistry(t *testing.T, a Registry, b Registry) {
+	ctx := context.Background()
+	cmdsA, cmdsB := make(chan *Command, 4), make(chan *Command, 4)
+	assert.NoError(t, a.Listen("a", func(cmd *Command) { deliver(cmdsA, cmd) }))
+	assert.NoError(t, b.Listen("b", func(cmd *Command) { deliver(cmdsB, cmd) }))
+
+	prev, err := a.Claim(ctx, &Entry{UserID: "u1", SessionID: "s1", Node: "a"})
+	assert.NoError(t, err)
+	assert.Nil(t, prev)
+	prev, err = b.Claim(ctx, &Entry{UserID: "u1", SessionID: "s2", Node: "b"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, prev) {
+		assert.Equal(t, "a", prev.Node)
+		assert.Equal(t, "s1", prev.SessionID)
+	}
+	_, err = a.Claim(ctx, &Entry{UserID: "u2", SessionID: "s3", Node: "a"})
+	assert.NoError(t, err)
+
+	// the session replaced on a closes late, the claim of b stays
+	assert.NoError(t, a.Release(ctx, "u1", "s1"))
+	e, err := a.Lookup(ctx, "u1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "b", e.Node)
+	}
+	entries, err := b.List(ctx)
+	if assert.NoError(t, err) && assert.Len(t, entries, 2) {
+		assert.Equal(t, "u1", entries[0].UserID)
+		assert.Equal(t, "u2", entries[1].UserID)
+	}
+
+	assert.NoError(t, a.Send(ctx, "b", &Command{Type: CmdKick, From: "a", UserID: "u1", Data: "bye"}))
+	select {
+	case cmd := <-cmdsB:
+		assert.Equal(t, &Command{Type: CmdKick, From: "a", UserID: "u1", Data: "bye"}, cmd)
+	case <-time.After(5 * time.Second):
+		t.Fatal("kick not delivered")
+	}
+	assert.NoError(t, b.Send(ctx, "", &Command{Type: CmdNotice, From: "b", Data: "hi"}))
+	for _, ch := range []chan *Command{cmdsA, cmdsB} {
+		select {
+		case cmd := <-ch:
+			assert.Equal(t, CmdNotice, cmd.Type)
+		case <-time.After(5 * time.Second):
+			t.Fatal("notice not delivered")
+		}
+	}
+	assert.ErrorIs(t, a.Send(ctx, "c", &Command{Type: CmdKick}), ErrNoListener)
+
+	assert.NoError(t, b.Release(ctx, "u1", "s2"))
+	_, err = a.Lookup(ctx, "u1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func deliver(ch chan *Command, cmd *Command) {
+	select {
+	case ch <- cmd:
+	default:
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	testRegistry(t, m, m)
+}
+
+func TestMemoryCloseWaits(t *testing.T) {
+	m := NewMemory()
+	started, handled := make(chan struct{}), make(chan struct{})
+	assert.NoError(t, m.Listen("a", func(cmd *Command) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		close(handled)
+	}))
+	assert.NoError(t, m.Send(context.Background(), "a", &Command{Type: CmdKick}))
+	<-started
+	assert.NoError(t, m.Close())
+	select {
+	case <-handled:
+	default:
+		t.Fatal("Close returned before the command was handled")
+	}
+}
+
+func TestRedis(t *testing.T) {
+	server, err := fakeredis.NewServer("secret")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer server.Close()
+	cfg := RedisConfig{Addr: server.Addr(), Password: "secret", Prefix: "test:"}
+	a, b := NewRedis(cfg), NewRedis(cfg)
+	testRegistry(t, a, b)
+
+	// the entries of a node that is gone are ignored and dropped
+	ctx := context.Background()
+	for _, user := range []string{"u3", "u4"} {
+		_, err = a.Claim(ctx, &Entry{UserID: user, SessionID: "s-" + user, Node: "a"})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, a.Close())
+	_, err = b.Lookup(ctx, "u3")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, ok := server.Get("test:session:u3")
+	assert.False(t, ok)
+	assert.False(t, server.IsMember("test:users", "u3"))
+	entries, err := b.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	_, ok = server.Get("test:session:u4")
+	assert.False(t, ok)
+	assert.False(t, server.IsMember("test:users", "u4"))
+
+	// a claim of a live node is kept
+	_, err = b.Claim(ctx, &Entry{UserID: "u5", SessionID: "s5", Node: "b"})
+	assert.NoError(t, err)
+	entries, err = b.List(ctx)
+	if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+		assert.Equal(t, "u5", entries[0].UserID)
+	}
+
+	// the subscription comes back after the connections break
+	server.DropConnections()
+	assert.Eventually(t, func() bool {
+		return b.Send(ctx, "b", &Command{Type: CmdKick}) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.NoError(t, b.Close())
+}
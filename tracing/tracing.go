@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracerName         = "github.com/yrzs/openimwssdk"
+	DefaultServiceName = "openimwssdk"
+)
+
+// Span attributes correlating the spans of one request.
+const (
+	AttrSessionID   = attribute.Key("openim.session_id")
+	AttrUserID      = attribute.Key("openim.user_id")
+	AttrOperationID = attribute.Key("openim.operation_id")
+	AttrReqFuncName = attribute.Key("openim.req_func_name")
+	AttrErrCode     = attribute.Key("openim.err_code")
+)
+
+// Config selects the exporters, tracing stays a no-op when both Endpoint and File are empty.
+type Config struct {
+	Endpoint    string // OTLP/HTTP collector, host:port
+	Insecure    bool   // plain http to the collector
+	File        string // spans are also written as JSON to this file, for local debugging
+	ServiceName string
+	SampleRatio float64 // 1 records every request
+}
+
+var (
+	provider *sdktrace.TracerProvider
+	file     *os.File
+)
+
+// Init installs the global tracer provider.
+func Init(cfg Config) error {
+	if cfg.Endpoint == "" && cfg.File == "" {
+		return nil
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	}
+	if cfg.Endpoint != "" {
+		clientOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(context.Background(), clientOpts...)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	if cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		file = f
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	provider = sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+// Shutdown flushes the pending spans and closes the exporters.
+func Shutdown(ctx context.Context) error {
+	var err error
+	if provider != nil {
+		err = provider.Shutdown(ctx)
+		provider = nil
+	}
+	if file != nil {
+		err = errors.Join(err, file.Close())
+		file = nil
+	}
+	return err
+}
+
+// Tracer returns the tracer of the gateway, a no-op one until Init installs a provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Start starts a span as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End ends the span, marking it failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
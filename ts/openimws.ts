@@ -0,0 +1,1411 @@
+// Code generated by cmd/tsgen from core_func.FuncRouter and ws_listener.go. DO NOT EDIT.
+
+/** A JSON string holding a T: the gateway takes struct, slice and map args as JSON strings. */
+export type JSONString<T> = string & { readonly __json?: T };
+
+/** The request frame, data is the JSON array of ReqArgs[K]. */
+export interface Req<K extends ReqFuncName = ReqFuncName> {
+  reqFuncName: K;
+  operationID: string;
+  data: string;
+}
+
+/** The response and event frame, data of a response is the JSON of RespData[event]. */
+export type Resp = EventData;
+
+export type ReqFuncName =
+  | "AcceptFriendApplication"
+  | "AcceptGroupApplication"
+  | "AckEvents"
+  | "AddBlack"
+  | "AddFriend"
+  | "ChangeGroupMemberMute"
+  | "ChangeGroupMute"
+  | "CheckFriend"
+  | "ClearConversationAndDeleteAllMsg"
+  | "CreateAdvancedQuoteMessage"
+  | "CreateAdvancedTextMessage"
+  | "CreateCardMessage"
+  | "CreateCustomMessage"
+  | "CreateFaceMessage"
+  | "CreateFileMessage"
+  | "CreateFileMessageByURL"
+  | "CreateFileMessageFromFullPath"
+  | "CreateForwardMessage"
+  | "CreateGroup"
+  | "CreateImageMessage"
+  | "CreateImageMessageByURL"
+  | "CreateImageMessageFromFullPath"
+  | "CreateLocationMessage"
+  | "CreateMergerMessage"
+  | "CreateQuoteMessage"
+  | "CreateSoundMessage"
+  | "CreateSoundMessageByURL"
+  | "CreateSoundMessageFromFullPath"
+  | "CreateTextAtMessage"
+  | "CreateTextMessage"
+  | "CreateVideoMessage"
+  | "CreateVideoMessageByURL"
+  | "CreateVideoMessageFromFullPath"
+  | "DeleteAllMsgFromLocal"
+  | "DeleteAllMsgFromLocalAndSvr"
+  | "DeleteConversationAndDeleteAllMsg"
+  | "DeleteFriend"
+  | "DeleteMessage"
+  | "DeleteMessageFromLocalStorage"
+  | "DismissGroup"
+  | "FindMessageList"
+  | "GetAdvancedHistoryMessageList"
+  | "GetAdvancedHistoryMessageListReverse"
+  | "GetAllConversationList"
+  | "GetAtAllTag"
+  | "GetBlackList"
+  | "GetConversationIDBySessionType"
+  | "GetConversationListSplit"
+  | "GetConversationRecvMessageOpt"
+  | "GetFlowControl"
+  | "GetFriendApplicationListAsApplicant"
+  | "GetFriendApplicationListAsRecipient"
+  | "GetFriendList"
+  | "GetFriendListPage"
+  | "GetGroupApplicationListAsApplicant"
+  | "GetGroupApplicationListAsRecipient"
+  | "GetGroupMemberList"
+  | "GetGroupMemberListByJoinTimeFilter"
+  | "GetGroupMemberOwnerAndAdmin"
+  | "GetJoinedGroupList"
+  | "GetLoginStatus"
+  | "GetMethods"
+  | "GetMultipleConversation"
+  | "GetOneConversation"
+  | "GetSelfUserInfo"
+  | "GetSpecifiedFriendsInfo"
+  | "GetSpecifiedGroupMembersInfo"
+  | "GetSpecifiedGroupsInfo"
+  | "GetSubscribeUsersStatus"
+  | "GetTotalUnreadMsgCount"
+  | "GetUserStatus"
+  | "GetUsersInfo"
+  | "GetUsersInfoFromSrv"
+  | "GetUsersInfoWithCache"
+  | "HideAllConversations"
+  | "HideConversation"
+  | "InsertGroupMessageToLocalStorage"
+  | "InsertSingleMessageToLocalStorage"
+  | "InviteUserToGroup"
+  | "IsJoinGroup"
+  | "JoinGroup"
+  | "KickGroupMember"
+  | "Login"
+  | "Logout"
+  | "MarkConversationMessageAsRead"
+  | "MarkMessagesAsReadByMsgID"
+  | "NetworkStatusChanged"
+  | "PinConversation"
+  | "PinFriends"
+  | "QuitGroup"
+  | "RefuseFriendApplication"
+  | "RefuseGroupApplication"
+  | "RemoveBlack"
+  | "ResetConversationGroupAtType"
+  | "RevokeMessage"
+  | "SearchConversation"
+  | "SearchFriends"
+  | "SearchGroupMembers"
+  | "SearchGroups"
+  | "SearchLocalMessages"
+  | "SendMessage"
+  | "SendMessageNotOss"
+  | "SetAppBackgroundStatus"
+  | "SetAppBadge"
+  | "SetConversationBurnDuration"
+  | "SetConversationDraft"
+  | "SetConversationIsMsgDestruct"
+  | "SetConversationMsgDestructTime"
+  | "SetConversationPrivateChat"
+  | "SetConversationRecvMessageOpt"
+  | "SetFriendRemark"
+  | "SetFriendsEx"
+  | "SetGlobalRecvMessageOpt"
+  | "SetGroupApplyMemberFriend"
+  | "SetGroupInfo"
+  | "SetGroupLookMemberInfo"
+  | "SetGroupMemberInfo"
+  | "SetGroupMemberNickname"
+  | "SetGroupMemberRoleLevel"
+  | "SetGroupVerification"
+  | "SetMessageLocalEx"
+  | "SetOneConversationEx"
+  | "SetSelfInfo"
+  | "SetSelfInfoEx"
+  | "SubscribeEvents"
+  | "SubscribeUsersStatus"
+  | "TransferGroupOwner"
+  | "TypingStatusUpdate"
+  | "UnsubscribeUsersStatus"
+  | "UpdateFcmToken"
+  | "UpdateMsgSenderInfo"
+  | "UploadFile"
+  | "UploadLogs"
+  ;
+
+/** The args of every reqFuncName, after the operationID. */
+export interface ReqArgs {
+  /** Friend.AcceptFriendApplication */
+  AcceptFriendApplication: [userIDHandleMsg: JSONString<ProcessFriendApplicationParams>];
+  /** Group.AcceptGroupApplication */
+  AcceptGroupApplication: [groupID: string, fromUserID: string, handleMsg: string];
+  /** gatewayFuncs.AckEvents */
+  AckEvents: [seq: number | string];
+  /** Friend.AddBlack */
+  AddBlack: [blackUserID: string, ex: string];
+  /** Friend.AddFriend */
+  AddFriend: [userIDReqMsg: JSONString<ApplyToAddFriendReq>];
+  /** Group.ChangeGroupMemberMute */
+  ChangeGroupMemberMute: [groupID: string, userID: string, mutedSeconds: number | string];
+  /** Group.ChangeGroupMute */
+  ChangeGroupMute: [groupID: string, isMute: boolean];
+  /** Friend.CheckFriend */
+  CheckFriend: [friendUserIDList: JSONString<string[]>];
+  /** Conversation.ClearConversationAndDeleteAllMsg */
+  ClearConversationAndDeleteAllMsg: [conversationID: string];
+  /** Conversation.CreateAdvancedQuoteMessage */
+  CreateAdvancedQuoteMessage: [text: string, qs: JSONString<MsgStruct>, messageEntities: JSONString<MessageEntity[]>];
+  /** Conversation.CreateAdvancedTextMessage */
+  CreateAdvancedTextMessage: [text: string, messageEntities: JSONString<MessageEntity[]>];
+  /** Conversation.CreateCardMessage */
+  CreateCardMessage: [card: JSONString<CardElem>];
+  /** Conversation.CreateCustomMessage */
+  CreateCustomMessage: [data: string, extension: string, description: string];
+  /** Conversation.CreateFaceMessage */
+  CreateFaceMessage: [index: number | string, data: string];
+  /** Conversation.CreateFileMessage */
+  CreateFileMessage: [filePath: string, fileName: string];
+  /** Conversation.CreateFileMessageByURL */
+  CreateFileMessageByURL: [fileElem: JSONString<FileBaseInfo>];
+  /** Conversation.CreateFileMessageFromFullPath */
+  CreateFileMessageFromFullPath: [fileFullPath: string, fileName: string];
+  /** Conversation.CreateForwardMessage */
+  CreateForwardMessage: [s: JSONString<MsgStruct>];
+  /** Group.CreateGroup */
+  CreateGroup: [req: JSONString<CreateGroupReq>];
+  /** Conversation.CreateImageMessage */
+  CreateImageMessage: [imagePath: string];
+  /** Conversation.CreateImageMessageByURL */
+  CreateImageMessageByURL: [sourcePath: string, sourcePicture: JSONString<PictureBaseInfo>, bigPicture: JSONString<PictureBaseInfo>, snapshotPicture: JSONString<PictureBaseInfo>];
+  /** Conversation.CreateImageMessageFromFullPath */
+  CreateImageMessageFromFullPath: [imageFullPath: string];
+  /** Conversation.CreateLocationMessage */
+  CreateLocationMessage: [description: string, longitude: number, latitude: number];
+  /** Conversation.CreateMergerMessage */
+  CreateMergerMessage: [messages: JSONString<MsgStruct[]>, title: string, summaries: JSONString<string[]>];
+  /** Conversation.CreateQuoteMessage */
+  CreateQuoteMessage: [text: string, qs: JSONString<MsgStruct>];
+  /** Conversation.CreateSoundMessage */
+  CreateSoundMessage: [soundPath: string, duration: number | string];
+  /** Conversation.CreateSoundMessageByURL */
+  CreateSoundMessageByURL: [soundElem: JSONString<SoundBaseInfo>];
+  /** Conversation.CreateSoundMessageFromFullPath */
+  CreateSoundMessageFromFullPath: [soundPath: string, duration: number | string];
+  /** Conversation.CreateTextAtMessage */
+  CreateTextAtMessage: [text: string, userIDList: JSONString<string[]>, usersInfo: JSONString<AtInfo[]>, qs: JSONString<MsgStruct>];
+  /** Conversation.CreateTextMessage */
+  CreateTextMessage: [text: string];
+  /** Conversation.CreateVideoMessage */
+  CreateVideoMessage: [videoPath: string, videoType: string, duration: number | string, snapshotPath: string];
+  /** Conversation.CreateVideoMessageByURL */
+  CreateVideoMessageByURL: [videoElem: JSONString<VideoBaseInfo>];
+  /** Conversation.CreateVideoMessageFromFullPath */
+  CreateVideoMessageFromFullPath: [videoFullPath: string, videoType: string, duration: number | string, snapshotFullPath: string];
+  /** Conversation.DeleteAllMessageFromLocalStorage */
+  DeleteAllMsgFromLocal: [];
+  /** Conversation.DeleteAllMsgFromLocalAndSvr */
+  DeleteAllMsgFromLocalAndSvr: [];
+  /** Conversation.DeleteConversationAndDeleteAllMsg */
+  DeleteConversationAndDeleteAllMsg: [conversationID: string];
+  /** Friend.DeleteFriend */
+  DeleteFriend: [friendUserID: string];
+  /** Conversation.DeleteMessage */
+  DeleteMessage: [conversationID: string, clientMsgID: string];
+  /** Conversation.DeleteMessageFromLocalStorage */
+  DeleteMessageFromLocalStorage: [conversationID: string, clientMsgID: string];
+  /** Group.DismissGroup */
+  DismissGroup: [groupID: string];
+  /** Conversation.FindMessageList */
+  FindMessageList: [req: JSONString<ConversationArgs[]>];
+  /** Conversation.GetAdvancedHistoryMessageList */
+  GetAdvancedHistoryMessageList: [req: JSONString<GetAdvancedHistoryMessageListParams>];
+  /** Conversation.GetAdvancedHistoryMessageListReverse */
+  GetAdvancedHistoryMessageListReverse: [req: JSONString<GetAdvancedHistoryMessageListParams>];
+  /** Conversation.GetAllConversationList */
+  GetAllConversationList: [];
+  /** Conversation.GetAtAllTag */
+  GetAtAllTag: [];
+  /** Friend.GetBlackList */
+  GetBlackList: [];
+  /** Conversation.GetConversationIDBySessionType */
+  GetConversationIDBySessionType: [sourceID: string, sessionType: number | string];
+  /** Conversation.GetConversationListSplit */
+  GetConversationListSplit: [offset: number | string, count: number | string];
+  /** Conversation.GetConversationRecvMessageOpt @deprecated */
+  GetConversationRecvMessageOpt: [conversationIDs: JSONString<string[]>];
+  /** gatewayFuncs.GetFlowControl */
+  GetFlowControl: [];
+  /** Friend.GetFriendApplicationListAsApplicant */
+  GetFriendApplicationListAsApplicant: [];
+  /** Friend.GetFriendApplicationListAsRecipient */
+  GetFriendApplicationListAsRecipient: [];
+  /** Friend.GetFriendList */
+  GetFriendList: [];
+  /** Friend.GetFriendListPage */
+  GetFriendListPage: [offset: number, count: number];
+  /** Group.GetGroupApplicationListAsApplicant */
+  GetGroupApplicationListAsApplicant: [];
+  /** Group.GetGroupApplicationListAsRecipient */
+  GetGroupApplicationListAsRecipient: [];
+  /** Group.GetGroupMemberList */
+  GetGroupMemberList: [groupID: string, filter: number, offset: number, count: number];
+  /** Group.GetGroupMemberListByJoinTimeFilter */
+  GetGroupMemberListByJoinTimeFilter: [groupID: string, offset: number, count: number, joinTimeBegin: number | string, joinTimeEnd: number | string, userIDs: JSONString<string[]>];
+  /** Group.GetGroupMemberOwnerAndAdmin */
+  GetGroupMemberOwnerAndAdmin: [groupID: string];
+  /** Group.GetJoinedGroupList */
+  GetJoinedGroupList: [];
+  /** LoginMgr.GetLoginStatus */
+  GetLoginStatus: [];
+  /** gatewayFuncs.GetMethods */
+  GetMethods: [];
+  /** Conversation.GetMultipleConversation */
+  GetMultipleConversation: [conversationIDList: JSONString<string[]>];
+  /** Conversation.GetOneConversation */
+  GetOneConversation: [sessionType: number, sourceID: string];
+  /** User.GetSelfUserInfo */
+  GetSelfUserInfo: [];
+  /** Friend.GetSpecifiedFriendsInfo */
+  GetSpecifiedFriendsInfo: [friendUserIDList: JSONString<string[]>];
+  /** Group.GetSpecifiedGroupMembersInfo */
+  GetSpecifiedGroupMembersInfo: [groupID: string, userIDList: JSONString<string[]>];
+  /** Group.GetSpecifiedGroupsInfo */
+  GetSpecifiedGroupsInfo: [groupIDs: JSONString<string[]>];
+  /** User.GetSubscribeUsersStatus */
+  GetSubscribeUsersStatus: [];
+  /** Conversation.GetTotalUnreadMsgCount */
+  GetTotalUnreadMsgCount: [];
+  /** User.GetUserStatus */
+  GetUserStatus: [userIDs: JSONString<string[]>];
+  /** Full.GetUsersInfo */
+  GetUsersInfo: [userIDs: JSONString<string[]>];
+  /** User.GetUsersInfo */
+  GetUsersInfoFromSrv: [userIDs: JSONString<string[]>];
+  /** Full.GetUsersInfoWithCache */
+  GetUsersInfoWithCache: [userIDs: JSONString<string[]>, groupID: string];
+  /** Conversation.HideAllConversations */
+  HideAllConversations: [];
+  /** Conversation.HideConversation */
+  HideConversation: [conversationID: string];
+  /** Conversation.InsertGroupMessageToLocalStorage */
+  InsertGroupMessageToLocalStorage: [s: JSONString<MsgStruct>, groupID: string, sendID: string];
+  /** Conversation.InsertSingleMessageToLocalStorage */
+  InsertSingleMessageToLocalStorage: [s: JSONString<MsgStruct>, recvID: string, sendID: string];
+  /** Group.InviteUserToGroup */
+  InviteUserToGroup: [groupID: string, reason: string, userIDList: JSONString<string[]>];
+  /** Group.IsJoinGroup */
+  IsJoinGroup: [groupID: string];
+  /** Group.JoinGroup */
+  JoinGroup: [groupID: string, reqMsg: string, joinSource: number, ex: string];
+  /** Group.KickGroupMember */
+  KickGroupMember: [groupID: string, reason: string, userIDList: JSONString<string[]>];
+  /** LoginMgr.Login */
+  Login: [userID: string, token: string];
+  /** LoginMgr.Logout */
+  Logout: [];
+  /** Conversation.MarkConversationMessageAsRead */
+  MarkConversationMessageAsRead: [conversationID: string];
+  /** Conversation.MarkMessagesAsReadByMsgID */
+  MarkMessagesAsReadByMsgID: [conversationID: string, clientMsgIDs: JSONString<string[]>];
+  /** LoginMgr.NetworkStatusChanged */
+  NetworkStatusChanged: [];
+  /** Conversation.PinConversation */
+  PinConversation: [conversationID: string, isPinned: boolean];
+  /** Friend.PinFriends */
+  PinFriends: [userIDPin: JSONString<SetFriendPinParams>];
+  /** Group.QuitGroup */
+  QuitGroup: [groupID: string];
+  /** Friend.RefuseFriendApplication */
+  RefuseFriendApplication: [userIDHandleMsg: JSONString<ProcessFriendApplicationParams>];
+  /** Group.RefuseGroupApplication */
+  RefuseGroupApplication: [groupID: string, fromUserID: string, handleMsg: string];
+  /** Friend.RemoveBlack */
+  RemoveBlack: [blackUserID: string];
+  /** Conversation.ResetConversationGroupAtType */
+  ResetConversationGroupAtType: [conversationID: string];
+  /** Conversation.RevokeMessage */
+  RevokeMessage: [conversationID: string, clientMsgID: string];
+  /** Conversation.SearchConversation */
+  SearchConversation: [searchParam: string];
+  /** Friend.SearchFriends */
+  SearchFriends: [param: JSONString<SearchFriendsParam>];
+  /** Group.SearchGroupMembers */
+  SearchGroupMembers: [searchParam: JSONString<SearchGroupMembersParam>];
+  /** Group.SearchGroups */
+  SearchGroups: [param: JSONString<SearchGroupsParam>];
+  /** Conversation.SearchLocalMessages */
+  SearchLocalMessages: [searchParam: JSONString<SearchLocalMessagesParams>];
+  /** Conversation.SendMessage */
+  SendMessage: [s: JSONString<MsgStruct>, recvID: string, groupID: string, p: JSONString<OfflinePushInfo>, isOnlineOnly: boolean];
+  /** Conversation.SendMessageNotOss */
+  SendMessageNotOss: [s: JSONString<MsgStruct>, recvID: string, groupID: string, p: JSONString<OfflinePushInfo>, isOnlineOnly: boolean];
+  /** LoginMgr.SetAppBackgroundStatus */
+  SetAppBackgroundStatus: [isBackground: boolean];
+  /** Third.SetAppBadge */
+  SetAppBadge: [appUnreadCount: number];
+  /** Conversation.SetOneConversationBurnDuration */
+  SetConversationBurnDuration: [conversationID: string, burnDuration: number];
+  /** Conversation.SetConversationDraft */
+  SetConversationDraft: [conversationID: string, draftText: string];
+  /** Conversation.SetConversationIsMsgDestruct */
+  SetConversationIsMsgDestruct: [conversationID: string, isMsgDestruct: boolean];
+  /** Conversation.SetConversationMsgDestructTime */
+  SetConversationMsgDestructTime: [conversationID: string, msgDestructTime: number | string];
+  /** Conversation.SetOneConversationPrivateChat */
+  SetConversationPrivateChat: [conversationID: string, isPrivate: boolean];
+  /** Conversation.SetOneConversationRecvMessageOpt */
+  SetConversationRecvMessageOpt: [conversationID: string, opt: number | string];
+  /** Friend.SetFriendRemark */
+  SetFriendRemark: [userIDRemark: JSONString<SetFriendRemarkParams>];
+  /** Friend.SetFriendsEx */
+  SetFriendsEx: [friendIDs: JSONString<string[]>, ex: string];
+  /** User.SetGlobalRecvMessageOpt */
+  SetGlobalRecvMessageOpt: [opt: number | string];
+  /** Group.SetGroupApplyMemberFriend */
+  SetGroupApplyMemberFriend: [groupID: string, rule: number];
+  /** Group.SetGroupInfo */
+  SetGroupInfo: [groupInfo: JSONString<GroupInfoForSet>];
+  /** Group.SetGroupLookMemberInfo */
+  SetGroupLookMemberInfo: [groupID: string, rule: number];
+  /** Group.SetGroupMemberInfo */
+  SetGroupMemberInfo: [groupMemberInfo: JSONString<SetGroupMemberInfo>];
+  /** Group.SetGroupMemberNickname */
+  SetGroupMemberNickname: [groupID: string, userID: string, groupMemberNickname: string];
+  /** Group.SetGroupMemberRoleLevel */
+  SetGroupMemberRoleLevel: [groupID: string, userID: string, roleLevel: number | string];
+  /** Group.SetGroupVerification */
+  SetGroupVerification: [groupID: string, verification: number];
+  /** Conversation.SetMessageLocalEx */
+  SetMessageLocalEx: [conversationID: string, clientMsgID: string, localEx: string];
+  /** Conversation.SetOneConversationEx */
+  SetOneConversationEx: [conversationID: string, ex: string];
+  /** User.SetSelfInfo */
+  SetSelfInfo: [userInfo: JSONString<UserInfoWithEx>];
+  /** User.SetSelfInfo */
+  SetSelfInfoEx: [userInfo: JSONString<UserInfoWithEx>];
+  /** gatewayFuncs.SubscribeEvents */
+  SubscribeEvents: [sub: JSONString<Subscription>];
+  /** User.SubscribeUsersStatus */
+  SubscribeUsersStatus: [userIDs: JSONString<string[]>];
+  /** Group.TransferGroupOwner */
+  TransferGroupOwner: [groupID: string, newOwnerUserID: string];
+  /** Conversation.TypingStatusUpdate */
+  TypingStatusUpdate: [recvID: string, msgTip: string];
+  /** User.UnsubscribeUsersStatus */
+  UnsubscribeUsersStatus: [userIDs: JSONString<string[]>];
+  /** Third.UpdateFcmToken */
+  UpdateFcmToken: [fcmToken: string, expireTime: number | string];
+  /** User.UpdateMsgSenderInfo */
+  UpdateMsgSenderInfo: [nickname: string, faceURL: string];
+  /** File.UploadFile */
+  UploadFile: [req: JSONString<UploadFileReq>, cb: unknown];
+  /** Third.UploadLogs */
+  UploadLogs: [ex: string, progress: unknown];
+}
+
+/** The decoded data of the successful response of every reqFuncName. */
+export interface RespData {
+  AcceptFriendApplication: "";
+  AcceptGroupApplication: "";
+  AckEvents: EventAck;
+  AddBlack: "";
+  AddFriend: "";
+  ChangeGroupMemberMute: "";
+  ChangeGroupMute: "";
+  CheckFriend: UserIDResult[];
+  ClearConversationAndDeleteAllMsg: "";
+  CreateAdvancedQuoteMessage: MsgStruct;
+  CreateAdvancedTextMessage: MsgStruct;
+  CreateCardMessage: MsgStruct;
+  CreateCustomMessage: MsgStruct;
+  CreateFaceMessage: MsgStruct;
+  CreateFileMessage: MsgStruct;
+  CreateFileMessageByURL: MsgStruct;
+  CreateFileMessageFromFullPath: MsgStruct;
+  CreateForwardMessage: MsgStruct;
+  CreateGroup: GroupInfo;
+  CreateImageMessage: MsgStruct;
+  CreateImageMessageByURL: MsgStruct;
+  CreateImageMessageFromFullPath: MsgStruct;
+  CreateLocationMessage: MsgStruct;
+  CreateMergerMessage: MsgStruct;
+  CreateQuoteMessage: MsgStruct;
+  CreateSoundMessage: MsgStruct;
+  CreateSoundMessageByURL: MsgStruct;
+  CreateSoundMessageFromFullPath: MsgStruct;
+  CreateTextAtMessage: MsgStruct;
+  CreateTextMessage: MsgStruct;
+  CreateVideoMessage: MsgStruct;
+  CreateVideoMessageByURL: MsgStruct;
+  CreateVideoMessageFromFullPath: MsgStruct;
+  DeleteAllMsgFromLocal: "";
+  DeleteAllMsgFromLocalAndSvr: "";
+  DeleteConversationAndDeleteAllMsg: "";
+  DeleteFriend: "";
+  DeleteMessage: "";
+  DeleteMessageFromLocalStorage: "";
+  DismissGroup: "";
+  FindMessageList: FindMessageListCallback;
+  GetAdvancedHistoryMessageList: GetAdvancedHistoryMessageListCallback;
+  GetAdvancedHistoryMessageListReverse: GetAdvancedHistoryMessageListCallback;
+  GetAllConversationList: LocalConversation[];
+  GetAtAllTag: string;
+  GetBlackList: LocalBlack[];
+  GetConversationIDBySessionType: string;
+  GetConversationListSplit: LocalConversation[];
+  GetConversationRecvMessageOpt: GetConversationRecvMessageOptResp[];
+  GetFlowControl: FlowControl;
+  GetFriendApplicationListAsApplicant: LocalFriendRequest[];
+  GetFriendApplicationListAsRecipient: LocalFriendRequest[];
+  GetFriendList: FullUserInfo[];
+  GetFriendListPage: FullUserInfo[];
+  GetGroupApplicationListAsApplicant: LocalGroupRequest[];
+  GetGroupApplicationListAsRecipient: LocalAdminGroupRequest[];
+  GetGroupMemberList: LocalGroupMember[];
+  GetGroupMemberListByJoinTimeFilter: LocalGroupMember[];
+  GetGroupMemberOwnerAndAdmin: LocalGroupMember[];
+  GetJoinedGroupList: LocalGroup[];
+  GetLoginStatus: number;
+  GetMethods: MethodsInfo;
+  GetMultipleConversation: LocalConversation[];
+  GetOneConversation: LocalConversation;
+  GetSelfUserInfo: LocalUser;
+  GetSpecifiedFriendsInfo: FullUserInfo[];
+  GetSpecifiedGroupMembersInfo: LocalGroupMember[];
+  GetSpecifiedGroupsInfo: LocalGroup[];
+  GetSubscribeUsersStatus: OnlineStatus[];
+  GetTotalUnreadMsgCount: number;
+  GetUserStatus: OnlineStatus[];
+  GetUsersInfo: FullUserInfo[];
+  GetUsersInfoFromSrv: LocalUser[];
+  GetUsersInfoWithCache: FullUserInfoWithCache[];
+  HideAllConversations: "";
+  HideConversation: "";
+  InsertGroupMessageToLocalStorage: MsgStruct;
+  InsertSingleMessageToLocalStorage: MsgStruct;
+  InviteUserToGroup: "";
+  IsJoinGroup: boolean;
+  JoinGroup: "";
+  KickGroupMember: "";
+  Login: "";
+  Logout: "";
+  MarkConversationMessageAsRead: "";
+  MarkMessagesAsReadByMsgID: "";
+  NetworkStatusChanged: "";
+  PinConversation: "";
+  PinFriends: "";
+  QuitGroup: "";
+  RefuseFriendApplication: "";
+  RefuseGroupApplication: "";
+  RemoveBlack: "";
+  ResetConversationGroupAtType: "";
+  RevokeMessage: "";
+  SearchConversation: Conversation[];
+  SearchFriends: SearchFriendItem[];
+  SearchGroupMembers: LocalGroupMember[];
+  SearchGroups: LocalGroup[];
+  SearchLocalMessages: SearchLocalMessagesCallback;
+  SendMessage: MsgStruct;
+  SendMessageNotOss: MsgStruct;
+  SetAppBackgroundStatus: "";
+  SetAppBadge: "";
+  SetConversationBurnDuration: "";
+  SetConversationDraft: "";
+  SetConversationIsMsgDestruct: "";
+  SetConversationMsgDestructTime: "";
+  SetConversationPrivateChat: "";
+  SetConversationRecvMessageOpt: "";
+  SetFriendRemark: "";
+  SetFriendsEx: "";
+  SetGlobalRecvMessageOpt: "";
+  SetGroupApplyMemberFriend: "";
+  SetGroupInfo: "";
+  SetGroupLookMemberInfo: "";
+  SetGroupMemberInfo: "";
+  SetGroupMemberNickname: "";
+  SetGroupMemberRoleLevel: "";
+  SetGroupVerification: "";
+  SetMessageLocalEx: "";
+  SetOneConversationEx: "";
+  SetSelfInfo: "";
+  SetSelfInfoEx: "";
+  SubscribeEvents: Subscription;
+  SubscribeUsersStatus: OnlineStatus[];
+  TransferGroupOwner: "";
+  TypingStatusUpdate: "";
+  UnsubscribeUsersStatus: "";
+  UpdateFcmToken: "";
+  UpdateMsgSenderInfo: "";
+  UploadFile: UploadFileResp;
+  UploadLogs: "";
+}
+
+/** Events of the sdk listeners, pushed with an empty operationID. */
+export type ListenerEventName =
+  | "OnConnecting"
+  | "OnConnectSuccess"
+  | "OnConnectFailed"
+  | "OnKickedOffline"
+  | "OnUserTokenExpired"
+  | "OnSyncServerStart"
+  | "OnSyncServerFinish"
+  | "OnSyncServerFailed"
+  | "OnNewConversation"
+  | "OnConversationChanged"
+  | "OnTotalUnreadMessageCountChanged"
+  | "OnConversationUserInputStatusChanged"
+  | "OnRecvNewMessage"
+  | "OnRecvC2CReadReceipt"
+  | "OnRecvGroupReadReceipt"
+  | "OnRecvMessageRevoked"
+  | "OnNewRecvMessageRevoked"
+  | "OnRecvMessageModified"
+  | "OnRecvOnlineOnlyMessage"
+  | "OnRecvMessageExtensionsChanged"
+  | "OnRecvMessageExtensionsDeleted"
+  | "OnRecvMessageExtensionsAdded"
+  | "OnRecvOfflineNewMessage"
+  | "OnMsgDeleted"
+  | "OnRecvNewMessages"
+  | "OnRecvOfflineNewMessages"
+  | "OnFriendApplicationAdded"
+  | "OnFriendApplicationDeleted"
+  | "OnFriendApplicationAccepted"
+  | "OnFriendApplicationRejected"
+  | "OnFriendAdded"
+  | "OnFriendDeleted"
+  | "OnFriendInfoChanged"
+  | "OnBlackAdded"
+  | "OnBlackDeleted"
+  | "OnJoinedGroupAdded"
+  | "OnJoinedGroupDeleted"
+  | "OnGroupMemberAdded"
+  | "OnGroupMemberDeleted"
+  | "OnGroupApplicationAdded"
+  | "OnGroupApplicationDeleted"
+  | "OnGroupInfoChanged"
+  | "OnGroupMemberInfoChanged"
+  | "OnGroupApplicationAccepted"
+  | "OnGroupApplicationRejected"
+  | "OnGroupDismissed"
+  | "OnUserStatusChanged"
+  | "OnSelfInfoUpdated"
+  | "OnRecvCustomBusinessMessage"
+  | "OnRoomParticipantConnected"
+  | "OnRoomParticipantDisconnected"
+  | "OnReceiveNewInvitation"
+  | "OnInviteeAccepted"
+  | "OnInviteeAcceptedByOtherDevice"
+  | "OnInviteeRejected"
+  | "OnInviteeRejectedByOtherDevice"
+  | "OnInvitationCancelled"
+  | "OnInvitationTimeout"
+  | "OnHangUp"
+  ;
+
+/** Events the gateway itself pushes. */
+export type GatewayEventName =
+  | "OnKickedByAdmin"
+  | "OnSystemNotice"
+  | "OnConnectRejected"
+  | "OnFlowControl"
+  | "OnEventsLost"
+  ;
+
+export type EventName = ListenerEventName | GatewayEventName;
+
+/** sdk_struct.AdvancedTextElem */
+export interface AdvancedTextElem {
+  text?: string;
+  messageEntityList?: MessageEntity[];
+}
+
+/** relation.ApplyToAddFriendReq */
+export interface ApplyToAddFriendReq {
+  fromUserID: string;
+  toUserID: string;
+  reqMsg: string;
+  ex: string;
+}
+
+/** sdk_struct.AtInfo */
+export interface AtInfo {
+  atUserID?: string;
+  groupNickname?: string;
+}
+
+/** sdk_struct.AtTextElem */
+export interface AtTextElem {
+  text?: string;
+  atUserList?: string[];
+  atUsersInfo?: AtInfo[];
+  quoteMessage?: MsgStruct;
+  isAtSelf: boolean;
+}
+
+/** sdk_struct.AttachedInfoElem */
+export interface AttachedInfoElem {
+  groupHasReadInfo?: GroupHasReadInfo;
+  isPrivateChat: boolean;
+  burnDuration: number;
+  hasReadTime: number;
+  messageEntityList?: MessageEntity[];
+  isEncryption: boolean;
+  inEncryptStatus: boolean;
+  uploadProgress?: UploadProgress;
+}
+
+/** wrapperspb.BoolValue */
+export interface BoolValue {
+  value: boolean;
+}
+
+/** sdk_struct.CardElem */
+export interface CardElem {
+  userID: string;
+  nickname: string;
+  faceURL: string;
+  ex: string;
+}
+
+/** server_api_params.Conversation */
+export interface Conversation {
+  ownerUserID: string;
+  conversationID: string;
+  conversationType: number;
+  userID: string;
+  groupID: string;
+  recvMsgOpt: number;
+  unreadCount: number;
+  draftTextTime: number;
+  isPinned: boolean;
+  isPrivateChat: boolean;
+  burnDuration: number;
+  groupAtType: number;
+  isNotInGroup: boolean;
+  updateUnreadCountTime: number;
+  attachedInfo: string;
+  ex: string;
+}
+
+/** sdk_params_callback.ConversationArgs */
+export interface ConversationArgs {
+  conversationID: string;
+  clientMsgIDList: string[];
+}
+
+/** group.CreateGroupReq */
+export interface CreateGroupReq {
+  memberUserIDs: string[];
+  groupInfo: GroupInfo;
+  adminUserIDs: string[];
+  ownerUserID: string;
+}
+
+/** sdk_struct.CustomElem */
+export interface CustomElem {
+  data?: string;
+  description?: string;
+  extension?: string;
+}
+
+/** errcode.Detail */
+export interface Detail {
+  category: string;
+  reason?: string;
+  arg?: number;
+  type?: string;
+}
+
+/** core_func.EventAck */
+export interface EventAck {
+  acked: number;
+  lastSeq: number;
+  pending: number;
+}
+
+/** core_func.EventData */
+export interface EventData {
+  event: string;
+  errCode: number;
+  errMsg: string;
+  errDetail?: Detail;
+  data: string;
+  operationID: string;
+  seq?: number;
+}
+
+/** sdk_struct.FaceElem */
+export interface FaceElem {
+  index: number;
+  data?: string;
+}
+
+/** sdk_struct.FileBaseInfo */
+export interface FileBaseInfo {
+  filePath?: string;
+  uuid?: string;
+  sourceUrl?: string;
+  fileName?: string;
+  fileSize: number;
+  fileType?: string;
+}
+
+/** sdk_struct.FileElem */
+export interface FileElem {
+  filePath?: string;
+  uuid?: string;
+  sourceUrl?: string;
+  fileName?: string;
+  fileSize: number;
+  fileType?: string;
+}
+
+/** sdk_params_callback.FindMessageListCallback */
+export interface FindMessageListCallback {
+  totalCount: number;
+  findResultItems: SearchByConversationResult[];
+}
+
+/** core_func.FlowControl */
+export interface FlowControl {
+  size: number;
+  credit: number;
+  slowDown: boolean;
+  overflows: number;
+}
+
+/** server_api_params.FullUserInfo */
+export interface FullUserInfo {
+  publicInfo: PublicUser;
+  friendInfo: LocalFriend;
+  blackInfo: LocalBlack;
+}
+
+/** server_api_params.FullUserInfoWithCache */
+export interface FullUserInfoWithCache {
+  publicInfo: PublicUser;
+  friendInfo: LocalFriend;
+  blackInfo: LocalBlack;
+  groupMemberInfo: LocalGroupMember;
+}
+
+/** sdk_params_callback.GetAdvancedHistoryMessageListCallback */
+export interface GetAdvancedHistoryMessageListCallback {
+  messageList: MsgStruct[];
+  lastMinSeq: number;
+  isEnd: boolean;
+  errCode: number;
+  errMsg: string;
+}
+
+/** sdk_params_callback.GetAdvancedHistoryMessageListParams */
+export interface GetAdvancedHistoryMessageListParams {
+  lastMinSeq: number;
+  conversationID: string;
+  startClientMsgID: string;
+  count: number;
+}
+
+/** server_api_params.GetConversationRecvMessageOptResp */
+export interface GetConversationRecvMessageOptResp {
+  conversationID: string;
+  result: number;
+}
+
+/** sdk_struct.GroupHasReadInfo */
+export interface GroupHasReadInfo {
+  hasReadUserIDList?: string[];
+  hasReadCount: number;
+  groupMemberCount: number;
+}
+
+/** sdkws.GroupInfo */
+export interface GroupInfo {
+  groupID: string;
+  groupName: string;
+  notification: string;
+  introduction: string;
+  faceURL: string;
+  ownerUserID: string;
+  createTime: number;
+  memberCount: number;
+  ex: string;
+  status: number;
+  creatorUserID: string;
+  groupType: number;
+  needVerification: number;
+  lookMemberInfo: number;
+  applyMemberFriend: number;
+  notificationUpdateTime: number;
+  notificationUserID: string;
+}
+
+/** sdkws.GroupInfoForSet */
+export interface GroupInfoForSet {
+  groupID: string;
+  groupName: string;
+  notification: string;
+  introduction: string;
+  faceURL: string;
+  ex: StringValue;
+  needVerification: Int32Value;
+  lookMemberInfo: Int32Value;
+  applyMemberFriend: Int32Value;
+}
+
+/** wrapperspb.Int32Value */
+export interface Int32Value {
+  value: number;
+}
+
+/** model_struct.LocalAdminGroupRequest */
+export interface LocalAdminGroupRequest {
+  groupID: string;
+  groupName: string;
+  notification: string;
+  introduction: string;
+  groupFaceURL: string;
+  createTime: number;
+  status: number;
+  creatorUserID: string;
+  groupType: number;
+  ownerUserID: string;
+  memberCount: number;
+  userID: string;
+  nickname: string;
+  userFaceURL: string;
+  handleResult: number;
+  reqMsg: string;
+  handledMsg: string;
+  reqTime: number;
+  handleUserID: string;
+  handledTime: number;
+  ex: string;
+  attachedInfo: string;
+  joinSource: number;
+  inviterUserID: string;
+}
+
+/** model_struct.LocalBlack */
+export interface LocalBlack {
+  ownerUserID: string;
+  userID: string;
+  nickname: string;
+  faceURL: string;
+  createTime: number;
+  addSource: number;
+  operatorUserID: string;
+  ex: string;
+  attachedInfo: string;
+}
+
+/** model_struct.LocalConversation */
+export interface LocalConversation {
+  conversationID: string;
+  conversationType: number;
+  userID: string;
+  groupID: string;
+  showName: string;
+  faceURL: string;
+  recvMsgOpt: number;
+  unreadCount: number;
+  groupAtType: number;
+  latestMsg: string;
+  latestMsgSendTime: number;
+  draftText: string;
+  draftTextTime: number;
+  isPinned: boolean;
+  isPrivateChat: boolean;
+  burnDuration: number;
+  isNotInGroup: boolean;
+  updateUnreadCountTime: number;
+  attachedInfo: string;
+  ex: string;
+  maxSeq: number;
+  minSeq: number;
+  hasReadSeq: number;
+  msgDestructTime: number;
+  isMsgDestruct: boolean;
+}
+
+/** model_struct.LocalFriend */
+export interface LocalFriend {
+  ownerUserID: string;
+  userID: string;
+  remark: string;
+  createTime: number;
+  addSource: number;
+  operatorUserID: string;
+  nickname: string;
+  faceURL: string;
+  ex: string;
+  attachedInfo: string;
+  isPinned: boolean;
+}
+
+/** model_struct.LocalFriendRequest */
+export interface LocalFriendRequest {
+  fromUserID: string;
+  fromNickname: string;
+  fromFaceURL: string;
+  toUserID: string;
+  toNickname: string;
+  toFaceURL: string;
+  handleResult: number;
+  reqMsg: string;
+  createTime: number;
+  handlerUserID: string;
+  handleMsg: string;
+  handleTime: number;
+  ex: string;
+  attachedInfo: string;
+}
+
+/** model_struct.LocalGroup */
+export interface LocalGroup {
+  groupID: string;
+  groupName: string;
+  notification: string;
+  introduction: string;
+  faceURL: string;
+  createTime: number;
+  status: number;
+  creatorUserID: string;
+  groupType: number;
+  ownerUserID: string;
+  memberCount: number;
+  ex: string;
+  attachedInfo: string;
+  needVerification: number;
+  lookMemberInfo: number;
+  applyMemberFriend: number;
+  notificationUpdateTime: number;
+  notificationUserID: string;
+}
+
+/** model_struct.LocalGroupMember */
+export interface LocalGroupMember {
+  groupID: string;
+  userID: string;
+  nickname: string;
+  faceURL: string;
+  roleLevel: number;
+  joinTime: number;
+  joinSource: number;
+  inviterUserID: string;
+  muteEndTime: number;
+  operatorUserID: string;
+  ex: string;
+  attachedInfo: string;
+}
+
+/** model_struct.LocalGroupRequest */
+export interface LocalGroupRequest {
+  groupID: string;
+  groupName: string;
+  notification: string;
+  introduction: string;
+  groupFaceURL: string;
+  createTime: number;
+  status: number;
+  creatorUserID: string;
+  groupType: number;
+  ownerUserID: string;
+  memberCount: number;
+  userID: string;
+  nickname: string;
+  userFaceURL: string;
+  handleResult: number;
+  reqMsg: string;
+  handledMsg: string;
+  reqTime: number;
+  handleUserID: string;
+  handledTime: number;
+  ex: string;
+  attachedInfo: string;
+  joinSource: number;
+  inviterUserID: string;
+}
+
+/** model_struct.LocalUser */
+export interface LocalUser {
+  userID: string;
+  nickname: string;
+  faceURL: string;
+  createTime: number;
+  ex: string;
+  attachedInfo: string;
+  globalRecvMsgOpt: number;
+}
+
+/** sdk_struct.LocationElem */
+export interface LocationElem {
+  description?: string;
+  longitude: number;
+  latitude: number;
+}
+
+/** sdk_struct.MergeElem */
+export interface MergeElem {
+  title?: string;
+  abstractList?: string[];
+  multiMessage?: MsgStruct[];
+  messageEntityList?: MessageEntity[];
+}
+
+/** sdk_struct.MessageEntity */
+export interface MessageEntity {
+  type?: string;
+  offset: number;
+  length: number;
+  url?: string;
+  ex?: string;
+}
+
+/** core_func.MethodInfo */
+export interface MethodInfo {
+  name: string;
+  params: ParamInfo[];
+  results: string[];
+  deprecated?: boolean;
+}
+
+/** core_func.MethodsInfo */
+export interface MethodsInfo {
+  version: string;
+  sdkVersion: string;
+  methods: MethodInfo[];
+}
+
+/** sdk_struct.MsgStruct */
+export interface MsgStruct {
+  clientMsgID?: string;
+  serverMsgID?: string;
+  createTime: number;
+  sendTime: number;
+  sessionType: number;
+  sendID?: string;
+  recvID?: string;
+  msgFrom: number;
+  contentType: number;
+  senderPlatformID: number;
+  senderNickname?: string;
+  senderFaceUrl?: string;
+  groupID?: string;
+  content?: string;
+  seq: number;
+  isRead: boolean;
+  status: number;
+  isReact?: boolean;
+  isExternalExtensions?: boolean;
+  offlinePush?: OfflinePushInfo;
+  attachedInfo?: string;
+  ex?: string;
+  localEx?: string;
+  textElem?: TextElem;
+  cardElem?: CardElem;
+  pictureElem?: PictureElem;
+  soundElem?: SoundElem;
+  videoElem?: VideoElem;
+  fileElem?: FileElem;
+  mergeElem?: MergeElem;
+  atTextElem?: AtTextElem;
+  faceElem?: FaceElem;
+  locationElem?: LocationElem;
+  customElem?: CustomElem;
+  quoteElem?: QuoteElem;
+  notificationElem?: NotificationElem;
+  advancedTextElem?: AdvancedTextElem;
+  typingElem?: TypingElem;
+  attachedInfoElem?: AttachedInfoElem;
+}
+
+/** sdk_struct.NotificationElem */
+export interface NotificationElem {
+  detail?: string;
+}
+
+/** sdkws.OfflinePushInfo */
+export interface OfflinePushInfo {
+  title: string;
+  desc: string;
+  ex: string;
+  iOSPushSound: string;
+  iOSBadgeCount: boolean;
+  signalInfo: string;
+}
+
+/** user.OnlineStatus */
+export interface OnlineStatus {
+  userID: string;
+  status: number;
+  platformIDs: number[];
+}
+
+/** core_func.ParamInfo */
+export interface ParamInfo {
+  type: string;
+  wire: string;
+}
+
+/** sdk_struct.PictureBaseInfo */
+export interface PictureBaseInfo {
+  uuid?: string;
+  type?: string;
+  size: number;
+  width: number;
+  height: number;
+  url?: string;
+}
+
+/** sdk_struct.PictureElem */
+export interface PictureElem {
+  sourcePath?: string;
+  sourcePicture?: PictureBaseInfo;
+  bigPicture?: PictureBaseInfo;
+  snapshotPicture?: PictureBaseInfo;
+}
+
+/** sdk_params_callback.ProcessFriendApplicationParams */
+export interface ProcessFriendApplicationParams {
+  toUserID: string;
+  handleMsg: string;
+}
+
+/** server_api_params.PublicUser */
+export interface PublicUser {
+  userID: string;
+  nickname: string;
+  faceURL: string;
+  ex: string;
+  createTime: number;
+}
+
+/** sdk_struct.QuoteElem */
+export interface QuoteElem {
+  text?: string;
+  quoteMessage?: MsgStruct;
+  messageEntityList?: MessageEntity[];
+}
+
+/** sdk_params_callback.SearchByConversationResult */
+export interface SearchByConversationResult {
+  conversationID: string;
+  conversationType: number;
+  showName: string;
+  faceURL: string;
+  latestMsgSendTime?: number;
+  messageCount: number;
+  messageList: MsgStruct[];
+}
+
+/** sdk_params_callback.SearchFriendItem */
+export interface SearchFriendItem {
+  relationship: number;
+  ownerUserID: string;
+  userID: string;
+  remark: string;
+  createTime: number;
+  addSource: number;
+  operatorUserID: string;
+  nickname: string;
+  faceURL: string;
+  ex: string;
+  attachedInfo: string;
+  isPinned: boolean;
+}
+
+/** sdk_params_callback.SearchFriendsParam */
+export interface SearchFriendsParam {
+  keywordList: string[];
+  isSearchUserID: boolean;
+  isSearchNickname: boolean;
+  isSearchRemark: boolean;
+}
+
+/** sdk_params_callback.SearchGroupMembersParam */
+export interface SearchGroupMembersParam {
+  groupID: string;
+  keywordList: string[];
+  isSearchUserID: boolean;
+  isSearchMemberNickname: boolean;
+  offset: number;
+  count: number;
+}
+
+/** sdk_params_callback.SearchGroupsParam */
+export interface SearchGroupsParam {
+  keywordList: string[];
+  isSearchGroupID: boolean;
+  isSearchGroupName: boolean;
+}
+
+/** sdk_params_callback.SearchLocalMessagesCallback */
+export interface SearchLocalMessagesCallback {
+  totalCount: number;
+  searchResultItems: SearchByConversationResult[];
+}
+
+/** sdk_params_callback.SearchLocalMessagesParams */
+export interface SearchLocalMessagesParams {
+  conversationID: string;
+  keywordList: string[];
+  keywordListMatchType: number;
+  senderUserIDList: string[];
+  messageTypeList: number[];
+  searchTimePosition: number;
+  searchTimePeriod: number;
+  pageIndex: number;
+  count: number;
+}
+
+/** sdk_params_callback.SetFriendPinParams */
+export interface SetFriendPinParams {
+  toUserID: string[];
+  isPinned: BoolValue;
+}
+
+/** sdk_params_callback.SetFriendRemarkParams */
+export interface SetFriendRemarkParams {
+  toUserID: string;
+  remark: string;
+}
+
+/** group.SetGroupMemberInfo */
+export interface SetGroupMemberInfo {
+  groupID: string;
+  userID: string;
+  nickname: StringValue;
+  faceURL: StringValue;
+  roleLevel: Int32Value;
+  ex: StringValue;
+}
+
+/** sdk_struct.SoundBaseInfo */
+export interface SoundBaseInfo {
+  uuid?: string;
+  soundPath?: string;
+  sourceUrl?: string;
+  dataSize: number;
+  duration: number;
+  soundType?: string;
+}
+
+/** sdk_struct.SoundElem */
+export interface SoundElem {
+  uuid?: string;
+  soundPath?: string;
+  sourceUrl?: string;
+  dataSize: number;
+  duration: number;
+  soundType?: string;
+}
+
+/** wrapperspb.StringValue */
+export interface StringValue {
+  value: string;
+}
+
+/** core_func.Subscription */
+export interface Subscription {
+  categories?: string[];
+  conversationIDs?: string[];
+}
+
+/** sdk_struct.TextElem */
+export interface TextElem {
+  content: string;
+}
+
+/** sdk_struct.TypingElem */
+export interface TypingElem {
+  msgTips?: string;
+}
+
+/** file.UploadFileReq */
+export interface UploadFileReq {
+  filepath: string;
+  name: string;
+  contentType: string;
+  cause: string;
+  uuid: string;
+}
+
+/** file.UploadFileResp */
+export interface UploadFileResp {
+  url: string;
+}
+
+/** sdk_struct.UploadProgress */
+export interface UploadProgress {
+  total: number;
+  save: number;
+  current: number;
+  uploadID: string;
+}
+
+/** server_api_params.UserIDResult */
+export interface UserIDResult {
+  userID: string;
+  result: number;
+}
+
+/** sdkws.UserInfoWithEx */
+export interface UserInfoWithEx {
+  userID: string;
+  nickname: StringValue;
+  faceURL: StringValue;
+  ex: StringValue;
+  globalRecvMsgOpt: Int32Value;
+}
+
+/** sdk_struct.VideoBaseInfo */
+export interface VideoBaseInfo {
+  videoPath?: string;
+  videoUUID?: string;
+  videoUrl?: string;
+  videoType?: string;
+  videoSize: number;
+  duration: number;
+  snapshotPath?: string;
+  snapshotUUID?: string;
+  snapshotSize: number;
+  snapshotUrl?: string;
+  snapshotWidth: number;
+  snapshotHeight: number;
+  snapshotType?: string;
+}
+
+/** sdk_struct.VideoElem */
+export interface VideoElem {
+  videoPath?: string;
+  videoUUID?: string;
+  videoUrl?: string;
+  videoType?: string;
+  videoSize: number;
+  duration: number;
+  snapshotPath?: string;
+  snapshotUUID?: string;
+  snapshotSize: number;
+  snapshotUrl?: string;
+  snapshotWidth: number;
+  snapshotHeight: number;
+  snapshotType?: string;
+}
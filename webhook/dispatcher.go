@@ -0,0 +1,444 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const (
+	queueDirName   = "queue"
+	DeadLetterName = "deadletter.jsonl"
+	fileSuffix     = ".json"
+)
+
+// delivery is a queued request, kept in Dir/queue/<ID>.json until it is done.
+type delivery struct {
+	ID       string          `json:"id"`
+	Hook     string          `json:"hook"`
+	Event    string          `json:"event"`
+	Body     json.RawMessage `json:"body"` // the Payload
+	Attempts int             `json:"attempts"`
+	NextAt   int64           `json:"nextAt"` // unix milliseconds of the next attempt
+	LastErr  string          `json:"lastErr,omitempty"`
+}
+
+// DeadLetter is a line of the dead-letter log, a delivery given up.
+type DeadLetter struct {
+	Time     int64           `json:"time"` // unix milliseconds it was given up
+	ID       string          `json:"id"`
+	Hook     string          `json:"hook"`
+	Event    string          `json:"event"`
+	Attempts int             `json:"attempts"`
+	Err      string          `json:"err"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// observed is a listener event waiting to be queued.
+type observed struct {
+	userID    string
+	sessionId string
+	ev        core_func.EventData
+	time      time.Time
+}
+
+// seenEvent is the session an event of a user was first seen by, and when.
+type seenEvent struct {
+	sessionId string
+	time      time.Time
+}
+
+// Dispatcher queues the listener events for the hooks they match and delivers them.
+type Dispatcher struct {
+	conf      Config
+	hooks     map[string]*Hook
+	client    *http.Client
+	queueDir  string
+	events    chan *observed
+	ready     chan *delivery
+	mu        sync.Mutex
+	timers    map[string]*time.Timer // of the deliveries waiting for their attempt
+	pending   int                    // deliveries queued and not done
+	closed    bool
+	publishMu sync.RWMutex // held by Publish, so that Close queues every event it accepted
+	stopped   bool         // set by Close under publishMu, Publish drops the events after it
+	deadMu    sync.Mutex
+	seq       atomic.Int64
+	seen      map[string]*seenEvent // by dedupKey, used by intake only
+	sweptAt   time.Time
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewDispatcher checks the hooks, resumes the deliveries left in the queue directory and starts delivering.
+func NewDispatcher(conf Config) (*Dispatcher, error) {
+	if conf.Dir == "" {
+		return nil, errors.New("webhook dir is empty")
+	}
+	if conf.Workers <= 0 {
+		conf.Workers = DefaultWorkers
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = DefaultMaxAttempts
+	}
+	if conf.Backoff <= 0 {
+		conf.Backoff = DefaultBackoff
+	}
+	if conf.MaxBackoff < conf.Backoff {
+		conf.MaxBackoff = max(DefaultMaxBackoff, conf.Backoff)
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultTimeout
+	}
+	if conf.DedupWindow == 0 {
+		conf.DedupWindow = DefaultDedupWindow
+	}
+	d := &Dispatcher{conf: conf, hooks: make(map[string]*Hook), client: &http.Client{Timeout: conf.Timeout},
+		queueDir: filepath.Join(conf.Dir, queueDirName), events: make(chan *observed, eventChanLen),
+		ready: make(chan *delivery, conf.Workers), timers: make(map[string]*time.Timer), seen: make(map[string]*seenEvent),
+		done: make(chan struct{})}
+	for _, h := range conf.Hooks {
+		if err := h.check(); err != nil {
+			return nil, err
+		}
+		if _, ok := d.hooks[h.Name]; ok {
+			return nil, fmt.Errorf("webhook %q defined twice", h.Name)
+		}
+		d.hooks[h.Name] = h
+	}
+	if err := os.MkdirAll(d.queueDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := d.resume(); err != nil {
+		return nil, err
+	}
+	d.wg.Add(1 + conf.Workers)
+	go d.intake()
+	for i := 0; i < conf.Workers; i++ {
+		go d.work()
+	}
+	return d, nil
+}
+
+// Publish queues a listener event for the hooks it matches, it never blocks. It is a core_func.EventObserver.
+func (d *Dispatcher) Publish(userID, sessionId string, ev core_func.EventData) {
+	matched := false
+	for _, h := range d.hooks {
+		matched = matched || h.matches(userID, ev.Event)
+	}
+	if !matched {
+		return
+	}
+	d.publishMu.RLock()
+	defer d.publishMu.RUnlock()
+	if d.stopped {
+		return
+	}
+	select {
+	case d.events <- &observed{userID: userID, sessionId: sessionId, ev: ev, time: time.Now()}:
+	default:
+		logger.Error(context.Background(), "webhook event dropped, the queue is full", "event", ev.Event,
+			"userID", userID)
+	}
+}
+
+// Pending returns the number of deliveries not done yet.
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pending
+}
+
+// Close stops delivering, the events published before it are queued and the deliveries not done stay in the queue
+// directory for the next dispatcher.
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		d.publishMu.Lock()
+		d.stopped = true
+		d.publishMu.Unlock()
+		d.mu.Lock()
+		d.closed = true
+		for id, t := range d.timers {
+			t.Stop()
+			delete(d.timers, id)
+		}
+		d.mu.Unlock()
+		close(d.done)
+		d.wg.Wait()
+	})
+}
+
+// resume schedules the deliveries of the queue directory.
+func (d *Dispatcher) resume() error {
+	entries, err := os.ReadDir(d.queueDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileSuffix) {
+			continue
+		}
+		path := filepath.Join(d.queueDir, e.Name())
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dl := &delivery{}
+		if err := json.Unmarshal(b, dl); err != nil || dl.ID == "" {
+			logger.Error(context.Background(), "webhook queue file dropped", "file", path, "err", err)
+			_ = os.Remove(path)
+			continue
+		}
+		d.mu.Lock()
+		d.pending++
+		d.mu.Unlock()
+		if _, ok := d.hooks[dl.Hook]; !ok {
+			d.giveUp(dl, fmt.Errorf("%w %q", ErrUnknownHook, dl.Hook))
+			continue
+		}
+		d.schedule(dl, time.Until(time.UnixMilli(dl.NextAt)))
+	}
+	return nil
+}
+
+// intake queues the observed events, those still waiting once the dispatcher is closed too.
+func (d *Dispatcher) intake() {
+	defer d.wg.Done()
+	for {
+		select {
+		case o := <-d.events:
+			d.enqueue(o)
+		case <-d.done:
+			for {
+				select {
+				case o := <-d.events:
+					d.enqueue(o)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// enqueue writes a delivery of the event for each hook it matches, then schedules it.
+func (d *Dispatcher) enqueue(o *observed) {
+	if d.duplicate(o) {
+		return
+	}
+	for _, h := range d.hooks {
+		if !h.matches(o.userID, o.ev.Event) {
+			continue
+		}
+		id := strconv.FormatInt(o.time.UnixMilli(), 36) + "-" + strconv.FormatInt(d.seq.Add(1), 36)
+		body, err := json.Marshal(&Payload{ID: id, Hook: h.Name, Event: o.ev.Event, UserID: o.userID,
+			SessionId: o.sessionId, Time: o.time.UnixMilli(), ErrCode: o.ev.ErrCode, ErrMsg: o.ev.ErrMsg,
+			Data: o.ev.Data})
+		if err != nil {
+			logger.Error(context.Background(), "webhook payload error", "event", o.ev.Event, "err", err)
+			continue
+		}
+		dl := &delivery{ID: id, Hook: h.Name, Event: o.ev.Event, Body: body, NextAt: o.time.UnixMilli()}
+		if err := d.save(dl); err != nil {
+			logger.Error(context.Background(), "webhook queue write error", "id", id, "err", err)
+			continue
+		}
+		d.mu.Lock()
+		d.pending++
+		d.mu.Unlock()
+		d.schedule(dl, 0)
+	}
+}
+
+// duplicate reports whether another session of the user saw the same event within DedupWindow: every session of a
+// user gets the events of the user, e.g. OnFriendApplicationAdded, which are posted once. The same event seen twice by
+// one session happened twice.
+func (d *Dispatcher) duplicate(o *observed) bool {
+	if d.conf.DedupWindow < 0 {
+		return false
+	}
+	if o.time.Sub(d.sweptAt) > d.conf.DedupWindow {
+		for key, e := range d.seen {
+			if o.time.Sub(e.time) > d.conf.DedupWindow {
+				delete(d.seen, key)
+			}
+		}
+		d.sweptAt = o.time
+	}
+	h := sha256.New()
+	for _, s := range []string{o.userID, o.ev.Event, strconv.Itoa(int(o.ev.ErrCode)), o.ev.ErrMsg, o.ev.Data} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	key := string(h.Sum(nil))
+	if e, ok := d.seen[key]; ok && e.sessionId != o.sessionId && o.time.Sub(e.time) <= d.conf.DedupWindow {
+		return true
+	}
+	d.seen[key] = &seenEvent{sessionId: o.sessionId, time: o.time}
+	return false
+}
+
+// schedule hands the delivery to the workers after delay.
+func (d *Dispatcher) schedule(dl *delivery, delay time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	d.timers[dl.ID] = time.AfterFunc(max(delay, 0), func() {
+		d.mu.Lock()
+		delete(d.timers, dl.ID)
+		d.mu.Unlock()
+		select {
+		case d.ready <- dl:
+		case <-d.done:
+		}
+	})
+}
+
+// work attempts the deliveries that are due.
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case dl := <-d.ready:
+			d.attempt(dl)
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// attempt posts a delivery once; a failed one is retried after its backoff, or given up after MaxAttempts or
+// when the endpoint refuses it with a client error.
+func (d *Dispatcher) attempt(dl *delivery) {
+	status, err := d.post(dl)
+	if err == nil {
+		d.finish(dl)
+		return
+	}
+	dl.Attempts++
+	dl.LastErr = err.Error()
+	permanent := status >= 400 && status < 500 && status != http.StatusRequestTimeout &&
+		status != http.StatusTooManyRequests
+	if permanent || dl.Attempts >= d.conf.MaxAttempts {
+		d.giveUp(dl, err)
+		return
+	}
+	delay := d.backoff(dl.Attempts)
+	dl.NextAt = time.Now().Add(delay).UnixMilli()
+	if err := d.save(dl); err != nil {
+		logger.Error(context.Background(), "webhook queue write error", "id", dl.ID, "err", err)
+	}
+	logger.Warn(context.Background(), "webhook delivery failed, retrying", "hook", dl.Hook, "id", dl.ID,
+		"attempts", dl.Attempts, "delay", delay, "err", err)
+	d.schedule(dl, delay)
+}
+
+// post sends the request of a delivery, the status is 0 when there is no response.
+func (d *Dispatcher) post(dl *delivery) (int, error) {
+	h, ok := d.hooks[dl.Hook]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownHook, dl.Hook)
+	}
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(dl.Body))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, dl.ID)
+	req.Header.Set(HeaderEvent, dl.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	if h.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(h.Secret, ts, dl.Body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook %s answered %s", h.Name, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the retry following the attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.conf.Backoff
+	for i := 1; i < attempts && delay < d.conf.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.conf.MaxBackoff)
+}
+
+// giveUp appends the delivery to the dead-letter log and drops it from the queue.
+func (d *Dispatcher) giveUp(dl *delivery, err error) {
+	logger.Error(context.Background(), "webhook delivery given up", "hook", dl.Hook, "id", dl.ID,
+		"attempts", dl.Attempts, "err", err)
+	line, _ := json.Marshal(&DeadLetter{Time: time.Now().UnixMilli(), ID: dl.ID, Hook: dl.Hook, Event: dl.Event,
+		Attempts: dl.Attempts, Err: err.Error(), Body: dl.Body})
+	d.deadMu.Lock()
+	f, ferr := os.OpenFile(filepath.Join(d.conf.Dir, DeadLetterName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if ferr == nil {
+		_, ferr = f.Write(append(line, '\n'))
+		if cerr := f.Close(); ferr == nil {
+			ferr = cerr
+		}
+	}
+	d.deadMu.Unlock()
+	if ferr != nil {
+		// kept in the queue, it is given up again at the next start
+		logger.Error(context.Background(), "webhook dead-letter write error", "id", dl.ID, "err", ferr)
+		return
+	}
+	d.finish(dl)
+}
+
+// finish drops a delivery that is done from the queue.
+func (d *Dispatcher) finish(dl *delivery) {
+	if err := os.Remove(d.path(dl)); err != nil && !os.IsNotExist(err) {
+		logger.Error(context.Background(), "webhook queue remove error", "id", dl.ID, "err", err)
+	}
+	d.mu.Lock()
+	d.pending--
+	d.mu.Unlock()
+}
+
+// save writes the queue file of a delivery, replacing the previous one at once.
+func (d *Dispatcher) save(dl *delivery) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	tmp := d.path(dl) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path(dl))
+}
+
+func (d *Dispatcher) path(dl *delivery) string {
+	return filepath.Join(d.queueDir, dl.ID+fileSuffix)
+}
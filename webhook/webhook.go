@@ -0,0 +1,175 @@
+// Package webhook posts the sdk listener events the gateway sees, e.g. OnFriendApplicationAdded, OnGroupDismissed
+// or OnKickedOffline, to HTTP endpoints. Every delivery is kept in a queue directory until its endpoint accepts it,
+// and retried with backoff, across restarts too; a delivery failing for good is appended to the dead-letter log.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yrzs/openimwssdk/core_func"
+	"github.com/yrzs/openimwssdk/logger"
+)
+
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp" // unix seconds of the attempt
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex hmac of timestamp.body>, when the hook has a secret
+
+	DefaultWorkers     = 4
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = time.Second
+	DefaultMaxBackoff  = 5 * time.Minute
+	DefaultTimeout     = 10 * time.Second
+	DefaultDedupWindow = 2 * time.Second
+	eventChanLen       = 4096
+)
+
+var ErrUnknownHook = errors.New("unknown hook")
+
+// Hook is an endpoint and the events posted to it. The filters are combined, an empty one passes every event.
+type Hook struct {
+	Name       string                    `json:"name"` // unique, deliveries refer to their hook by name
+	URL        string                    `json:"url"`
+	Secret     string                    `json:"secret,omitempty"`     // key of the signature, none without it
+	Events     []string                  `json:"events,omitempty"`     // names of the listener events
+	Categories []core_func.EventCategory `json:"categories,omitempty"` // see core_func.EventCategories
+	UserIDs    []string                  `json:"userIDs,omitempty"`    // users whose sessions see the event
+	Headers    map[string]string         `json:"headers,omitempty"`    // added to the requests
+	events     map[string]bool
+	categories map[core_func.EventCategory]bool
+	userIDs    map[string]bool
+}
+
+// Payload is the body of a webhook request.
+type Payload struct {
+	ID        string `json:"id"` // of the delivery, the same for its retries
+	Hook      string `json:"hook"`
+	Event     string `json:"event"`
+	UserID    string `json:"userID"`
+	SessionId string `json:"sessionId"` // the first session of the user that saw the event
+	Time      int64  `json:"time"`      // unix milliseconds the gateway saw the event
+	ErrCode   int32  `json:"errCode,omitempty"`
+	ErrMsg    string `json:"errMsg,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// Config of the webhooks.
+type Config struct {
+	Dir         string // of the queue and the dead-letter log
+	Hooks       []*Hook
+	Workers     int           // deliveries attempted at once
+	MaxAttempts int           // a delivery failing so many times goes to the dead-letter log
+	Backoff     time.Duration // before the first retry, doubled for each one after it
+	MaxBackoff  time.Duration
+	Timeout     time.Duration // of one attempt
+	DedupWindow time.Duration // an event seen by another session of the user within it is posted once, <0 posts all
+}
+
+// LoadHooks reads the hooks of a JSON file holding an array of Hook.
+func LoadHooks(path string) ([]*Hook, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []*Hook
+	if err := json.Unmarshal(b, &hooks); err != nil {
+		return nil, fmt.Errorf("webhooks %s: %w", path, err)
+	}
+	return hooks, nil
+}
+
+// check validates the hook and builds its filters.
+func (h *Hook) check() error {
+	if h.Name == "" || h.URL == "" {
+		return fmt.Errorf("webhook %q needs a name and a url", h.Name)
+	}
+	h.events, h.categories, h.userIDs = nil, nil, nil
+	if len(h.Events) > 0 {
+		h.events = make(map[string]bool)
+		for _, event := range h.Events {
+			h.events[event] = true
+		}
+	}
+	if len(h.Categories) > 0 {
+		h.categories = make(map[core_func.EventCategory]bool)
+		for _, c := range h.Categories {
+			known := false
+			for _, k := range core_func.EventCategories {
+				known = known || c == k
+			}
+			if !known {
+				return fmt.Errorf("webhook %q: unknown event category %q", h.Name, c)
+			}
+			h.categories[c] = true
+		}
+	}
+	if len(h.UserIDs) > 0 {
+		h.userIDs = make(map[string]bool)
+		for _, userID := range h.UserIDs {
+			h.userIDs[userID] = true
+		}
+	}
+	return nil
+}
+
+// matches reports whether the event of a user passes the filters of the hook.
+func (h *Hook) matches(userID string, event string) bool {
+	if h.events != nil && !h.events[event] {
+		return false
+	}
+	if h.categories != nil {
+		if c, ok := core_func.EventCategoryOf(event); !ok || !h.categories[c] {
+			return false
+		}
+	}
+	return h.userIDs == nil || h.userIDs[userID]
+}
+
+// Sign returns the signature header value of a body sent at timestamp, unix seconds.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is that of the body sent at timestamp, for the receivers.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+var defaultDispatcher *Dispatcher
+
+// Init starts the dispatcher of the hooks and observes the listener events of the sessions, no hooks leave
+// webhooks disabled.
+func Init(conf Config) error {
+	if len(conf.Hooks) == 0 {
+		return nil
+	}
+	d, err := NewDispatcher(conf)
+	if err != nil {
+		return err
+	}
+	defaultDispatcher = d
+	core_func.Observe(d.Publish)
+	logger.Info(context.Background(), "webhooks enabled", "hooks", len(conf.Hooks), "pending", d.Pending())
+	return nil
+}
+
+// Close stops the dispatcher started by Init, the deliveries not done yet stay queued for the next start.
+func Close() {
+	if defaultDispatcher != nil {
+		defaultDispatcher.Close()
+	}
+}
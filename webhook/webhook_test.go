@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yrzs/openimwssdk/core_func"
+)
+
+func TestHookMatches(t *testing.T) {
+	h := &Hook{Name: "h", URL: "http://x", Categories: []core_func.EventCategory{core_func.CategoryGroup},
+		UserIDs: []string{"u1"}}
+	assert.NoError(t, h.check())
+	assert.True(t, h.matches("u1", "OnGroupDismissed"))
+	assert.False(t, h.matches("u2", "OnGroupDismissed"))
+	assert.False(t, h.matches("u1", "OnFriendApplicationAdded"))
+	h = &Hook{Name: "h", URL: "http://x", Events: []string{"OnKickedOffline"}}
+	assert.NoError(t, h.check())
+	assert.True(t, h.matches("u2", "OnKickedOffline"))
+	assert.False(t, h.matches("u2", "OnGroupDismissed"))
+	assert.Error(t, (&Hook{Name: "h", URL: "http://x", Categories: []core_func.EventCategory{"nope"}}).check())
+}
+
+func TestDispatcher(t *testing.T) {
+	var calls atomic.Int32
+	payloads := make(chan *Payload, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if !Verify("s3cret", ts, body, r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		p := &Payload{}
+		_ = json.Unmarshal(body, p)
+		payloads <- p
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	d, err := NewDispatcher(Config{Dir: dir, Backoff: 10 * time.Millisecond, Hooks: []*Hook{
+		{Name: "kicked", URL: srv.URL, Secret: "s3cret", Events: []string{"OnKickedOffline"}},
+		{Name: "gone", URL: srv.URL + "/gone", Secret: "s3cret", Events: []string{"OnGroupDismissed"}},
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer d.Close()
+
+	d.Publish("u1", "s1", core_func.EventData{Event: "OnKickedOffline"})
+	d.Publish("u1", "s1", core_func.EventData{Event: "OnConnectSuccess"})
+	select {
+	case p := <-payloads:
+		assert.Equal(t, "kicked", p.Hook)
+		assert.Equal(t, "OnKickedOffline", p.Event)
+		assert.Equal(t, "u1", p.UserID)
+		assert.Equal(t, "s1", p.SessionId)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	// a client error is given up at once
+	d.Publish("u1", "s1", core_func.EventData{Event: "OnGroupDismissed", Data: `{"groupID":"g1"}`})
+	assert.Eventually(t, func() bool { return d.Pending() == 0 }, 5*time.Second, 10*time.Millisecond)
+	b, err := os.ReadFile(filepath.Join(dir, DeadLetterName))
+	assert.NoError(t, err)
+	dead := &DeadLetter{}
+	assert.NoError(t, json.Unmarshal(b, dead))
+	assert.Equal(t, "gone", dead.Hook)
+	assert.Equal(t, 1, dead.Attempts)
+	entries, _ := os.ReadDir(filepath.Join(dir, queueDirName))
+	assert.Empty(t, entries)
+}
+
+func TestDispatcherCloseQueuesPublished(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDispatcher(Config{Dir: dir, Backoff: time.Hour, Hooks: []*Hook{{Name: "all", URL: "http://127.0.0.1:1"}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 500; i++ {
+		d.Publish("u1", "s1", core_func.EventData{Event: "OnFriendApplicationAdded"})
+	}
+	d.Close()
+	d.Publish("u1", "s1", core_func.EventData{Event: "OnFriendApplicationAdded"}) // dropped
+	files, _ := filepath.Glob(filepath.Join(dir, queueDirName, "*"+fileSuffix))
+	assert.Len(t, files, 500)
+	assert.Equal(t, 500, d.Pending())
+}
+
+func TestDispatcherDedup(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDispatcher(Config{Dir: dir, Backoff: time.Hour, Hooks: []*Hook{{Name: "all", URL: "http://127.0.0.1:1"}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ev := core_func.EventData{Event: "OnFriendApplicationAdded", Data: `{"fromUserID":"u2"}`}
+	d.Publish("u1", "s1", ev)
+	d.Publish("u1", "s2", ev) // the other device of u1
+	d.Publish("u1", "s1", ev) // seen again by the same session, a new event
+	d.Publish("u3", "s3", ev)
+	d.Publish("u1", "s2", core_func.EventData{Event: "OnFriendApplicationAdded", Data: `{"fromUserID":"u4"}`})
+	d.Close()
+	assert.Equal(t, 4, d.Pending())
+}
+
+func TestDispatcherResume(t *testing.T) {
+	dir := t.TempDir()
+	hooks := []*Hook{{Name: "all", URL: "http://127.0.0.1:1"}}
+	d, err := NewDispatcher(Config{Dir: dir, Backoff: 200 * time.Millisecond, Hooks: hooks})
+	if !assert.NoError(t, err) {
+		return
+	}
+	d.Publish("u1", "s1", core_func.EventData{Event: "OnFriendApplicationAdded"})
+	// closed once the first attempt failed, the retry is left to the next dispatcher
+	assert.Eventually(t, func() bool {
+		files, _ := filepath.Glob(filepath.Join(dir, queueDirName, "*"+fileSuffix))
+		if len(files) != 1 {
+			return false
+		}
+		b, _ := os.ReadFile(files[0])
+		return strings.Contains(string(b), `"attempts":1`)
+	}, 5*time.Second, 10*time.Millisecond)
+	d.Close()
+	assert.Equal(t, 1, d.Pending())
+
+	delivered := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- r.Header.Get(HeaderEvent)
+	}))
+	defer srv.Close()
+	hooks[0].URL = srv.URL
+	d, err = NewDispatcher(Config{Dir: dir, Hooks: hooks})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer d.Close()
+	select {
+	case event := <-delivered:
+		assert.Equal(t, "OnFriendApplicationAdded", event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued webhook not resumed")
+	}
+}